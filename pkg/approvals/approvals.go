@@ -0,0 +1,289 @@
+package approvals
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gptscript-ai/gptscript/pkg/config"
+	"github.com/gptscript-ai/gptscript/pkg/filelock"
+	"github.com/gptscript-ai/gptscript/pkg/hash"
+	"github.com/gptscript-ai/gptscript/pkg/policy"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+)
+
+// FileName is the name of the file, in the same directory as the CLI config, that approvals are stored in.
+const FileName = "approvals.json"
+
+// Scope is how long the answer to a confirmation is remembered for.
+type Scope string
+
+const (
+	Once   Scope = "once"
+	Run    Scope = "run"
+	Always Scope = "always"
+)
+
+// Approval is an answer to a confirmation that is remembered always. It only applies while the source of the tool is
+// the same as when it was given.
+type Approval struct {
+	ID          string    `json:"id"`
+	Tool        string    `json:"tool"`
+	Source      string    `json:"source"`
+	Interpreter string    `json:"interpreter"`
+	Hash        string    `json:"hash"`
+	Accept      bool      `json:"accept"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type file struct {
+	Approvals []Approval `json:"approvals"`
+}
+
+// lock serializes the changes to approvals files made by this process.
+var lock sync.Mutex
+
+// Store keeps the approvals in a file.
+type Store struct {
+	file string
+}
+
+func NewStore(file string) *Store {
+	return &Store{
+		file: file,
+	}
+}
+
+// lockFile locks the file for this process and for other processes, until the returned function is called.
+func (s *Store) lockFile() (func(), error) {
+	lock.Lock()
+	unlock, err := filelock.Lock(s.file)
+	if err != nil {
+		lock.Unlock()
+		return nil, fmt.Errorf("failed to lock approvals: %w", err)
+	}
+	return func() {
+		unlock()
+		lock.Unlock()
+	}, nil
+}
+
+// DefaultFile returns the approvals file that is next to the CLI config.
+func DefaultFile(configFile string) (string, error) {
+	cfg, err := config.ReadCLIConfig(configFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read CLI config: %w", err)
+	}
+	return filepath.Join(filepath.Dir(cfg.GetFilename()), FileName), nil
+}
+
+// id identifies a tool by its source, name, and interpreter, so that an approval is kept when the tool is changed.
+func id(tool types.Tool) string {
+	return hash.ID(policy.Source(tool), tool.Name, tool.GetInterpreter())[:12]
+}
+
+// sourceHash is the hash of the source of the tool. Approvals don't apply to the tool anymore when it changes.
+func sourceHash(tool types.Tool) string {
+	return hash.Digest(tool.String())
+}
+
+func (s *Store) read() (file, error) {
+	var f file
+	data, err := os.ReadFile(s.file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	} else if err != nil {
+		return f, fmt.Errorf("failed to read approvals: %w", err)
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return f, fmt.Errorf("failed to parse approvals %s: %w", s.file, err)
+	}
+	return f, nil
+}
+
+func (s *Store) write(f file) error {
+	sort.Slice(f.Approvals, func(i, j int) bool {
+		return f.Approvals[i].CreatedAt.Before(f.Approvals[j].CreatedAt)
+	})
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.file), 0700); err != nil {
+		return fmt.Errorf("failed to create approvals directory: %w", err)
+	}
+	if err := os.WriteFile(s.file, data, 0600); err != nil {
+		return fmt.Errorf("failed to write approvals: %w", err)
+	}
+	return nil
+}
+
+// Get returns the approval for the tool. Approvals that were given for a different source of the tool are removed.
+func (s *Store) Get(tool types.Tool) (Approval, bool, error) {
+	unlock, err := s.lockFile()
+	if err != nil {
+		return Approval{}, false, err
+	}
+	defer unlock()
+
+	f, err := s.read()
+	if err != nil {
+		return Approval{}, false, err
+	}
+
+	toolID := id(tool)
+	for i, approval := range f.Approvals {
+		if approval.ID != toolID {
+			continue
+		}
+		if approval.Hash == sourceHash(tool) {
+			return approval, true, nil
+		}
+		f.Approvals = append(f.Approvals[:i], f.Approvals[i+1:]...)
+		return Approval{}, false, s.write(f)
+	}
+
+	return Approval{}, false, nil
+}
+
+// Add remembers the answer for the tool, replacing the previous one.
+func (s *Store) Add(tool types.Tool, accept bool) error {
+	unlock, err := s.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	approval := Approval{
+		ID:          id(tool),
+		Tool:        tool.Name,
+		Source:      policy.Source(tool),
+		Interpreter: tool.GetInterpreter(),
+		Hash:        sourceHash(tool),
+		Accept:      accept,
+		CreatedAt:   time.Now(),
+	}
+	f.Approvals = slices.DeleteFunc(f.Approvals, func(a Approval) bool {
+		return a.ID == approval.ID
+	})
+	f.Approvals = append(f.Approvals, approval)
+	return s.write(f)
+}
+
+// List returns all the approvals.
+func (s *Store) List() ([]Approval, error) {
+	unlock, err := s.lockFile()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	f, err := s.read()
+	return f.Approvals, err
+}
+
+// Revoke removes the approvals with the given IDs, or that are for the tools with the given names. An error is
+// returned if nothing matches one of them.
+func (s *Store) Revoke(idsOrNames ...string) error {
+	unlock, err := s.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	f, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	for _, idOrName := range idsOrNames {
+		var (
+			kept  = f.Approvals[:0]
+			found bool
+		)
+		for _, approval := range f.Approvals {
+			if approval.ID == idOrName || approval.Tool == idOrName {
+				found = true
+				continue
+			}
+			kept = append(kept, approval)
+		}
+		if !found {
+			return fmt.Errorf("no approval found for %q", idOrName)
+		}
+		f.Approvals = kept
+	}
+
+	return s.write(f)
+}
+
+// RevokeAll removes all the approvals.
+func (s *Store) RevokeAll() error {
+	unlock, err := s.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return s.write(file{})
+}
+
+// Session remembers the answers that are given during one run, and the ones that are remembered always in the store.
+type Session struct {
+	store *Store
+	lock  sync.Mutex
+	run   map[string]bool
+}
+
+// NewSession returns a session for a run. If store is nil, answers are only remembered for the run.
+func NewSession(store *Store) *Session {
+	return &Session{
+		store: store,
+		run:   map[string]bool{},
+	}
+}
+
+// Lookup returns the remembered answer for the tool, if there is one.
+func (s *Session) Lookup(tool types.Tool) (accept, found bool, err error) {
+	s.lock.Lock()
+	accept, found = s.run[id(tool)+sourceHash(tool)]
+	s.lock.Unlock()
+	if found || s.store == nil {
+		return accept, found, nil
+	}
+
+	approval, found, err := s.store.Get(tool)
+	return approval.Accept, found, err
+}
+
+// Remember remembers the answer for the tool for the given scope.
+func (s *Session) Remember(tool types.Tool, accept bool, scope Scope) error {
+	switch scope {
+	case "", Once:
+		return nil
+	case Run:
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.run[id(tool)+sourceHash(tool)] = accept
+		return nil
+	case Always:
+		if s.store == nil {
+			return fmt.Errorf("answers can't be remembered always without an approvals file")
+		}
+		return s.store.Add(tool, accept)
+	default:
+		return fmt.Errorf("invalid scope %q, use %s, %s, or %s", scope, Once, Run, Always)
+	}
+}
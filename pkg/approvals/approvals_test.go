@@ -0,0 +1,116 @@
+package approvals
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func tool(name, body string) types.Tool {
+	return types.Tool{
+		ToolDef: types.ToolDef{
+			Parameters: types.Parameters{
+				Name: name,
+			},
+			Instructions: "#!/bin/bash\n" + body,
+		},
+		Source: types.ToolSource{
+			Location: "/tools/tool.gpt",
+		},
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), FileName))
+
+	hello := tool("hello", "echo hello")
+	_, found, err := store.Get(hello)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	require.NoError(t, store.Add(hello, true))
+	require.NoError(t, store.Add(tool("rm", "rm -rf /"), false))
+
+	approval, found, err := store.Get(hello)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.True(t, approval.Accept)
+	require.Equal(t, "bash", approval.Interpreter)
+	require.Equal(t, "/tools/tool.gpt", approval.Source)
+
+	// The approval doesn't apply anymore when the source of the tool changes, and is removed.
+	_, found, err = store.Get(tool("hello", "echo goodbye"))
+	require.NoError(t, err)
+	require.False(t, found)
+	_, found, err = store.Get(hello)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	list, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.False(t, list[0].Accept)
+
+	require.ErrorContains(t, store.Revoke("missing"), `no approval found for "missing"`)
+	require.NoError(t, store.Revoke("rm"))
+	list, err = store.List()
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
+
+func TestStoresOfOneFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), FileName)
+
+	// Each store is like the store of another process, so only the lock of the file keeps their approvals.
+	var wg sync.WaitGroup
+	for i := range 4 {
+		store := NewStore(file)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 20 {
+				if err := store.Add(tool(fmt.Sprintf("tool-%d-%d", i, j), "echo hi"), true); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	list, err := NewStore(file).List()
+	require.NoError(t, err)
+	require.Len(t, list, 80)
+}
+
+func TestSession(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), FileName))
+	hello, rm := tool("hello", "echo hello"), tool("rm", "rm -rf /")
+
+	session := NewSession(store)
+	require.NoError(t, session.Remember(hello, true, Once))
+	require.NoError(t, session.Remember(hello, true, Run))
+	require.NoError(t, session.Remember(rm, false, Always))
+	require.Error(t, session.Remember(rm, false, "forever"))
+
+	accept, found, err := session.Lookup(hello)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.True(t, accept)
+
+	// Only the answers that are remembered always are used by other runs.
+	other := NewSession(store)
+	_, found, err = other.Lookup(hello)
+	require.NoError(t, err)
+	require.False(t, found)
+	accept, found, err = other.Lookup(rm)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.False(t, accept)
+
+	require.Error(t, NewSession(nil).Remember(hello, true, Always))
+}
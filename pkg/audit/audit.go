@@ -0,0 +1,277 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gptscript-ai/gptscript/pkg/filelock"
+)
+
+const (
+	ActionCommand    = "command"
+	ActionBuiltin    = "builtin"
+	ActionHTTP       = "http"
+	ActionOpenAPI    = "openapi"
+	ActionCredential = "credential"
+
+	CredentialSourceStore    = "store"
+	CredentialSourceTool     = "tool"
+	CredentialSourceOverride = "override"
+
+	StatusOK     = "ok"
+	StatusError  = "error"
+	StatusDenied = "denied"
+)
+
+// Entry is one line of the audit log. Hash is the hash of the entry without Hash, and of the hash of the previous
+// entry, so that changing, removing, or reordering entries is detected.
+type Entry struct {
+	Seq         int64     `json:"seq"`
+	Time        time.Time `json:"time"`
+	RunID       string    `json:"runID,omitempty"`
+	CallID      string    `json:"callID,omitempty"`
+	Action      string    `json:"action"`
+	ToolID      string    `json:"toolID,omitempty"`
+	ToolName    string    `json:"toolName,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	Interpreter string    `json:"interpreter,omitempty"`
+	Input       string    `json:"input,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	ExitCode    *int      `json:"exitCode,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	OutputHash  string    `json:"outputHash,omitempty"`
+	// Credential is the name of the credential that was used, and CredentialSource is where it came from: the
+	// credential store, the credential tool, or an override.
+	Credential        string   `json:"credential,omitempty"`
+	CredentialContext string   `json:"credentialContext,omitempty"`
+	CredentialSource  string   `json:"credentialSource,omitempty"`
+	EnvVars           []string `json:"envVars,omitempty"`
+	PrevHash          string   `json:"prevHash"`
+	Hash              string   `json:"hash"`
+}
+
+func (e Entry) computeHash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// HashOutput returns the hash that is logged instead of the output of a call.
+func HashOutput(output string) string {
+	sum := sha256.Sum256([]byte(output))
+	return hex.EncodeToString(sum[:])
+}
+
+// Log appends entries to an audit log file.
+type Log struct {
+	file string
+	lock sync.Mutex
+}
+
+var (
+	logs     = map[string]*Log{}
+	logsLock sync.Mutex
+)
+
+// Open returns the log for the file. The same log is returned for the same file, so that entries that are written by
+// different runs in this process are chained correctly.
+func Open(file string) (*Log, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+
+	logsLock.Lock()
+	defer logsLock.Unlock()
+
+	if l, ok := logs[abs]; ok {
+		return l, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(abs), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	l := &Log{
+		file: abs,
+	}
+	logs[abs] = l
+	return l, nil
+}
+
+// Append adds the entry to the end of the log, after setting its sequence number and hashes. The file is locked while
+// the last entry is read and the new one is written, so that the entries of other processes are chained correctly too.
+func (l *Log) Append(entry Entry) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	unlock, err := filelock.Lock(l.file)
+	if err != nil {
+		return fmt.Errorf("failed to lock audit log: %w", err)
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(l.file, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	last, err := lastEntry(f)
+	if err != nil {
+		return err
+	}
+
+	if last != nil {
+		entry.Seq = last.Seq + 1
+		entry.PrevHash = last.Hash
+	} else {
+		entry.Seq = 1
+		entry.PrevHash = ""
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Time = entry.Time.UTC()
+	if entry.Hash, err = entry.computeHash(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// lastEntry reads the last line of the file, reading more of the end of the file until the whole line is read.
+func lastEntry(f *os.File) (*Entry, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	size := info.Size()
+	for chunk := int64(4096); ; chunk *= 2 {
+		offset := max(size-chunk, 0)
+		data := make([]byte, size-offset)
+		if _, err := f.ReadAt(data, offset); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+
+		data = bytes.TrimRight(data, "\n")
+		if len(data) == 0 {
+			return nil, nil
+		}
+		i := bytes.LastIndexByte(data, '\n')
+		if i < 0 && offset > 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(data[i+1:], &entry); err != nil {
+			return nil, fmt.Errorf("the last entry of the audit log is invalid: %w", err)
+		}
+		return &entry, nil
+	}
+}
+
+// VerifyError is the first problem that was found in a log.
+type VerifyError struct {
+	Line   int
+	Reason string
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Reason)
+}
+
+// Verify checks that the entries of the log haven't been changed, removed, or reordered, and returns the number of
+// entries and the hash of the last one. Entries can still be removed from the end of the log without being detected,
+// so keep a copy of the hash of the last entry to detect that.
+func Verify(r io.Reader) (count int, lastHash string, _ error) {
+	var (
+		scanner = bufio.NewScanner(r)
+		prev    *Entry
+		line    int
+	)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024*1024)
+
+	for scanner.Scan() {
+		line++
+		data := scanner.Bytes()
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return count, lastHash, &VerifyError{Line: line, Reason: fmt.Sprintf("invalid entry: %v", err)}
+		}
+
+		// The entry must be exactly what was written, so that fields that are added or changed are detected too.
+		if written, err := json.Marshal(entry); err != nil || !bytes.Equal(written, data) {
+			return count, lastHash, &VerifyError{Line: line, Reason: "the entry has been modified"}
+		}
+
+		hash, err := entry.computeHash()
+		if err != nil {
+			return count, lastHash, err
+		}
+		if hash != entry.Hash {
+			return count, lastHash, &VerifyError{Line: line, Reason: "the hash of the entry doesn't match its content"}
+		}
+
+		if prev == nil {
+			if entry.PrevHash != "" || entry.Seq != 1 {
+				return count, lastHash, &VerifyError{Line: line, Reason: "the log doesn't start with the first entry"}
+			}
+		} else if entry.PrevHash != prev.Hash || entry.Seq != prev.Seq+1 {
+			return count, lastHash, &VerifyError{Line: line, Reason: fmt.Sprintf("the entry doesn't follow entry %d", prev.Seq)}
+		}
+
+		prev = &entry
+		count++
+		lastHash = entry.Hash
+	}
+
+	return count, lastHash, scanner.Err()
+}
+
+type runIDKey struct{}
+
+// WithRunID returns a context with the run ID that is logged for calls.
+func WithRunID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, runID)
+}
+
+// EnsureRunID returns a context with a new run ID, unless it already has one.
+func EnsureRunID(ctx context.Context) context.Context {
+	if RunID(ctx) != "" {
+		return ctx
+	}
+	return WithRunID(ctx, uuid.NewString())
+}
+
+// RunID returns the run ID of the context.
+func RunID(ctx context.Context) string {
+	runID, _ := ctx.Value(runIDKey{}).(string)
+	return runID
+}
@@ -0,0 +1,109 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendAndVerify(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(file)
+	require.NoError(t, err)
+
+	require.NoError(t, l.Append(Entry{Action: ActionCommand, ToolID: "a", Status: StatusOK}))
+	// Long entries are read back correctly when the next entry is appended.
+	require.NoError(t, l.Append(Entry{Action: ActionBuiltin, ToolID: "b", Input: strings.Repeat("x", 10000), Status: StatusOK}))
+
+	// Another log for the same file continues the chain.
+	delete(logs, l.file)
+	l, err = Open(file)
+	require.NoError(t, err)
+	require.NoError(t, l.Append(Entry{Action: ActionCredential, Credential: "c", Status: StatusOK}))
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+
+	count, lastHash, err := Verify(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Contains(t, lines[2], `"seq":3`)
+	require.Contains(t, lines[2], lastHash)
+
+	for name, tc := range map[string]struct {
+		lines  []string
+		line   int
+		reason string
+	}{
+		"changed": {
+			lines:  []string{lines[0], strings.Replace(lines[1], `"toolID":"b"`, `"toolID":"x"`, 1), lines[2]},
+			line:   2,
+			reason: "the hash of the entry doesn't match its content",
+		},
+		"field added": {
+			lines:  []string{lines[0], strings.Replace(lines[1], `{`, `{"extra":1,`, 1), lines[2]},
+			line:   2,
+			reason: "the entry has been modified",
+		},
+		"removed": {
+			lines:  []string{lines[0], lines[2]},
+			line:   2,
+			reason: "the entry doesn't follow entry 1",
+		},
+		"reordered": {
+			lines:  []string{lines[0], lines[2], lines[1]},
+			line:   2,
+			reason: "the entry doesn't follow entry 1",
+		},
+		"first removed": {
+			lines:  lines[1:],
+			line:   1,
+			reason: "the log doesn't start with the first entry",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := Verify(strings.NewReader(strings.Join(tc.lines, "\n")))
+			var verifyErr *VerifyError
+			require.ErrorAs(t, err, &verifyErr)
+			require.Equal(t, tc.line, verifyErr.Line)
+			require.Equal(t, tc.reason, verifyErr.Reason)
+		})
+	}
+}
+
+func TestAppendFromLogs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	// Each log is like the log of another process, so only the lock of the file keeps the chain valid.
+	var wg sync.WaitGroup
+	for range 4 {
+		l, err := Open(file)
+		require.NoError(t, err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				if err := l.Append(Entry{Action: ActionCommand, ToolID: fmt.Sprint(i)}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+
+	count, _, err := Verify(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, 200, count)
+}
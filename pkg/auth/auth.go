@@ -6,12 +6,15 @@ import (
 	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/gptscript-ai/gptscript/pkg/approvals"
 	"github.com/gptscript-ai/gptscript/pkg/builtin"
 	"github.com/gptscript-ai/gptscript/pkg/context"
 	"github.com/gptscript-ai/gptscript/pkg/engine"
 	"github.com/gptscript-ai/gptscript/pkg/runner"
 )
 
+const deniedMessage = "Request denied, blocking execution."
+
 func Authorize(ctx engine.Context, input string) (runner.AuthorizerResponse, error) {
 	defer context.GetPauseFuncFromCtx(ctx.Ctx)()()
 
@@ -33,10 +36,82 @@ func Authorize(ctx engine.Context, input string) (runner.AuthorizerResponse, err
 
 	return runner.AuthorizerResponse{
 		Accept:  result,
-		Message: "Request denied, blocking execution.",
+		Message: deniedMessage,
 	}, nil
 }
 
+const (
+	answerAllowOnce   = "Allow once"
+	answerAllowRun    = "Allow for this run"
+	answerAllowAlways = "Always allow"
+	answerDeny        = "Deny"
+	answerDenyAlways  = "Always deny"
+)
+
+var answers = map[string]runner.AuthorizerResponse{
+	answerAllowOnce:   {Accept: true, Remember: approvals.Once},
+	answerAllowRun:    {Accept: true, Remember: approvals.Run},
+	answerAllowAlways: {Accept: true, Remember: approvals.Always},
+	answerDeny:        {Remember: approvals.Once},
+	answerDenyAlways:  {Remember: approvals.Always},
+}
+
+// NewAuthorizer returns an authorizer that asks the user to confirm calls, and remembers the answers in the session
+// for as long as the user chooses.
+func NewAuthorizer(session *approvals.Session) runner.AuthorizerFunc {
+	return func(ctx engine.Context, input string) (runner.AuthorizerResponse, error) {
+		if IsSafe(ctx) {
+			return runner.AuthorizerResponse{
+				Accept: true,
+			}, nil
+		}
+
+		return Remembered(session, ctx, func() (runner.AuthorizerResponse, error) {
+			defer context.GetPauseFuncFromCtx(ctx.Ctx)()()
+
+			var answer string
+			err := survey.AskOne(&survey.Select{
+				Help:    fmt.Sprintf("The full source of the tools is as follows:\n\n%s", ctx.Tool.String()),
+				Default: answerAllowOnce,
+				Message: ConfirmMessage(ctx, input),
+				Options: []string{answerAllowOnce, answerAllowRun, answerAllowAlways, answerDeny, answerDenyAlways},
+			}, &answer)
+			if err != nil {
+				return runner.AuthorizerResponse{}, err
+			}
+
+			return answers[answer], nil
+		})
+	}
+}
+
+// Remembered returns the remembered answer for the call if there is one. Otherwise, it asks for the answer and
+// remembers it for as long as the answer says.
+func Remembered(session *approvals.Session, ctx engine.Context, ask func() (runner.AuthorizerResponse, error)) (runner.AuthorizerResponse, error) {
+	accept, found, err := session.Lookup(ctx.Tool)
+	if err != nil {
+		return runner.AuthorizerResponse{}, err
+	}
+	if found {
+		return runner.AuthorizerResponse{
+			Accept:  accept,
+			Message: deniedMessage,
+		}, nil
+	}
+
+	resp, err := ask()
+	if err != nil {
+		return runner.AuthorizerResponse{}, err
+	}
+	if err := session.Remember(ctx.Tool, resp.Accept, resp.Remember); err != nil {
+		return runner.AuthorizerResponse{}, err
+	}
+	if !resp.Accept && resp.Message == "" {
+		resp.Message = deniedMessage
+	}
+	return resp, nil
+}
+
 func IsSafe(ctx engine.Context) bool {
 	if !ctx.Tool.IsCommand() {
 		return true
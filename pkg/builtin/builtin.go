@@ -259,6 +259,24 @@ var tools = map[string]types.Tool{
 			BuiltinFunc: SysModelProviderCredential,
 		},
 	},
+	"sys.oauth2": {
+		ToolDef: types.ToolDef{
+			Parameters: types.Parameters{
+				Description: "A credential tool that gets an OAuth2 access token and refreshes it when it expires",
+				Arguments: types.ObjectSchema(
+					"flow", "The OAuth2 flow to use: authorization_code (default), device_code, or client_credentials",
+					"auth_url", "The authorization endpoint, for the authorization_code flow",
+					"token_url", "The token endpoint",
+					"device_auth_url", "The device authorization endpoint, for the device_code flow",
+					"client_id", "The client ID",
+					"client_secret", "The client secret, if the client has one. Use ${VAR} to read it from an environment variable",
+					"scope", "The scopes to request, separated by spaces or commas",
+					"env", "The environment variable to set to the access token (default OAUTH2_TOKEN)",
+					"redirect_port", "The port of the loopback redirect URL of the authorization_code flow (default is random)"),
+			},
+			BuiltinFunc: SysOAuth2,
+		},
+	},
 }
 
 func ListTools() (result []types.Tool) {
@@ -0,0 +1,164 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	context2 "github.com/gptscript-ai/gptscript/pkg/context"
+	"github.com/gptscript-ai/gptscript/pkg/credentials"
+	"github.com/gptscript-ai/gptscript/pkg/oauth2"
+	"github.com/gptscript-ai/gptscript/pkg/prompt"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+)
+
+type oauth2Params struct {
+	Flow          string `json:"flow,omitempty"`
+	AuthURL       string `json:"auth_url,omitempty"`
+	TokenURL      string `json:"token_url,omitempty"`
+	DeviceAuthURL string `json:"device_auth_url,omitempty"`
+	ClientID      string `json:"client_id,omitempty"`
+	ClientSecret  string `json:"client_secret,omitempty"`
+	Scope         string `json:"scope,omitempty"`
+	Env           string `json:"env,omitempty"`
+	RedirectPort  string `json:"redirect_port,omitempty"`
+}
+
+// SysOAuth2 is a credential tool that gets an OAuth2 access token. If the existing credential has a refresh token, it
+// is used to get a new access token without asking the user again.
+func SysOAuth2(ctx context.Context, env []string, input string, _ chan<- string) (string, error) {
+	var params oauth2Params
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return "", fmt.Errorf("invalid arguments for sys.oauth2: %w", err)
+	}
+
+	// Values can reference environment variables, so that secrets don't have to be written in the tool file.
+	lookup := func(key string) string {
+		for _, e := range env {
+			if k, v, ok := strings.Cut(e, "="); ok && k == key {
+				return v
+			}
+		}
+		return ""
+	}
+	for _, v := range []*string{&params.AuthURL, &params.TokenURL, &params.DeviceAuthURL, &params.ClientID, &params.ClientSecret, &params.Scope} {
+		*v = os.Expand(*v, lookup)
+	}
+
+	if params.Env == "" {
+		params.Env = "OAUTH2_TOKEN"
+	}
+	if params.Flow == "" {
+		params.Flow = oauth2.FlowAuthorizationCode
+	}
+	if params.TokenURL == "" {
+		return "", fmt.Errorf("sys.oauth2 requires token_url")
+	}
+
+	cfg := oauth2.Config{
+		ClientID:      params.ClientID,
+		ClientSecret:  params.ClientSecret,
+		AuthURL:       params.AuthURL,
+		TokenURL:      params.TokenURL,
+		DeviceAuthURL: params.DeviceAuthURL,
+		Scopes:        strings.Fields(strings.ReplaceAll(params.Scope, ",", " ")),
+	}
+	if params.RedirectPort != "" {
+		port, err := strconv.Atoi(params.RedirectPort)
+		if err != nil {
+			return "", fmt.Errorf("invalid redirect_port %q for sys.oauth2: %w", params.RedirectPort, err)
+		}
+		cfg.RedirectPort = port
+	}
+
+	token, err := refreshExistingCredential(ctx, cfg, lookup(credentials.ExistingCredential))
+	if err != nil {
+		log.Infof("Failed to refresh the OAuth2 token, authorizing again: %v", err)
+	}
+
+	if token == nil {
+		switch params.Flow {
+		case oauth2.FlowClientCredentials:
+			token, err = cfg.ClientCredentials(ctx)
+		case oauth2.FlowDeviceCode:
+			if cfg.DeviceAuthURL == "" {
+				return "", fmt.Errorf("sys.oauth2 requires device_auth_url for the %s flow", params.Flow)
+			}
+			token, err = cfg.DeviceCode(ctx, func(verificationURI, userCode string) error {
+				return notifyUser(ctx, env, fmt.Sprintf("To authorize, open %s and enter the code %s", verificationURI, userCode), map[string]string{
+					"authURL":  verificationURI,
+					"userCode": userCode,
+				})
+			})
+		case oauth2.FlowAuthorizationCode:
+			if cfg.AuthURL == "" {
+				return "", fmt.Errorf("sys.oauth2 requires auth_url for the %s flow", params.Flow)
+			}
+			token, err = cfg.AuthorizationCode(ctx, func(authURL string) error {
+				return notifyUser(ctx, env, fmt.Sprintf("To authorize, open %s", authURL), map[string]string{
+					"authURL": authURL,
+				})
+			})
+		default:
+			return "", fmt.Errorf("invalid flow %q for sys.oauth2, use %s, %s, or %s", params.Flow,
+				oauth2.FlowAuthorizationCode, oauth2.FlowDeviceCode, oauth2.FlowClientCredentials)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	cred := credentials.Credential{
+		Env: map[string]string{
+			params.Env: token.AccessToken,
+		},
+		RefreshToken: token.RefreshToken,
+	}
+	if !token.Expiry.IsZero() {
+		cred.ExpiresAt = &token.Expiry
+	}
+
+	data, err := json.Marshal(cred)
+	return string(data), err
+}
+
+func refreshExistingCredential(ctx context.Context, cfg oauth2.Config, existing string) (*oauth2.Token, error) {
+	if existing == "" {
+		return nil, nil
+	}
+
+	var cred credentials.Credential
+	if err := json.Unmarshal([]byte(existing), &cred); err != nil {
+		return nil, err
+	}
+	if cred.RefreshToken == "" {
+		return nil, nil
+	}
+
+	return cfg.Refresh(ctx, cred.RefreshToken)
+}
+
+// notifyUser tells the user how to authorize. It goes through the prompt server when there is one, so that the user
+// sees it in the SDK or MCP client that runs the tool, and waits until the user acknowledges it.
+func notifyUser(ctx context.Context, env []string, msg string, metadata map[string]string) error {
+	for _, e := range env {
+		if v, ok := strings.CutPrefix(e, types.PromptURLEnvVar+"="); ok && v != "" {
+			input, err := json.Marshal(map[string]any{
+				"message":  msg,
+				"metadata": metadata,
+			})
+			if err != nil {
+				return err
+			}
+			_, err = prompt.SysPrompt(ctx, env, string(input), nil)
+			return err
+		}
+	}
+
+	defer context2.GetPauseFuncFromCtx(ctx)()()
+	_, err := fmt.Fprintln(os.Stderr, msg)
+	return err
+}
@@ -0,0 +1,69 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gptscript-ai/gptscript/pkg/credentials"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSysOAuth2PromptServer(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "the-code", r.PostForm.Get("code"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"the-token"}`))
+	})
+
+	var prompts []types.Prompt
+	mux.HandleFunc("/prompt", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer prompt-token", r.Header.Get("Authorization"))
+
+		var p types.Prompt
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&p))
+		prompts = append(prompts, p)
+
+		// Play the user that authorizes in the browser.
+		authURL, err := url.Parse(p.Metadata["authURL"])
+		require.NoError(t, err)
+		redirect, err := url.Parse(authURL.Query().Get("redirect_uri"))
+		require.NoError(t, err)
+		redirect.RawQuery = url.Values{"code": {"the-code"}, "state": {authURL.Query().Get("state")}}.Encode()
+		resp, err := http.Get(redirect.String())
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		_, _ = w.Write([]byte("{}"))
+	})
+
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	input, err := json.Marshal(oauth2Params{
+		AuthURL:  s.URL + "/authorize",
+		TokenURL: s.URL + "/token",
+		ClientID: "client",
+	})
+	require.NoError(t, err)
+
+	out, err := SysOAuth2(context.Background(), []string{
+		types.PromptURLEnvVar + "=" + s.URL + "/prompt",
+		types.PromptTokenEnvVar + "=prompt-token",
+	}, string(input), nil)
+	require.NoError(t, err)
+
+	var cred credentials.Credential
+	require.NoError(t, json.Unmarshal([]byte(out), &cred))
+	require.Equal(t, "the-token", cred.Env["OAUTH2_TOKEN"])
+
+	require.Len(t, prompts, 1)
+	require.Contains(t, prompts[0].Message, "To authorize, open "+s.URL+"/authorize?")
+	require.Empty(t, prompts[0].Fields)
+}
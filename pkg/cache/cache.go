@@ -26,7 +26,7 @@ type Client struct {
 }
 
 type Options struct {
-	DisableCache bool   `usage:"Disable caching of LLM API responses"`
+	DisableCache bool   `usage:"Disable caching of LLM API responses and tool results"`
 	CacheDir     string `usage:"Directory to store cache (default: $XDG_CACHE_HOME/gptscript)"`
 }
 
@@ -120,7 +120,8 @@ func (c *Client) Store(ctx context.Context, key, value any) error {
 		return err
 	}
 
-	f, err := os.Create(filepath.Join(c.dir, keyValue))
+	// Cached values, such as responses of LLMs and results of tools, can be private, so only the user can read them.
+	f, err := os.OpenFile(filepath.Join(c.dir, keyValue), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	cmd2 "github.com/gptscript-ai/cmd"
+	"github.com/spf13/cobra"
+)
+
+type Approvals struct {
+	root *GPTScript
+}
+
+func (a *Approvals) Customize(cmd *cobra.Command) {
+	cmd.Use = "approvals"
+	cmd.Aliases = []string{"approval"}
+	cmd.Short = "Manage the answers to confirmations that are always remembered"
+	cmd.Args = cobra.NoArgs
+	cmd.AddCommand(cmd2.Command(&ApprovalsList{root: a.root}))
+	cmd.AddCommand(cmd2.Command(&ApprovalsRevoke{root: a.root}))
+}
+
+func (a *Approvals) Run(cmd *cobra.Command, _ []string) error {
+	return cmd.Help()
+}
+
+type ApprovalsList struct {
+	JSON bool `usage:"Output the approvals as JSON" local:"true"`
+
+	root *GPTScript
+}
+
+func (a *ApprovalsList) Customize(cmd *cobra.Command) {
+	cmd.Use = "ls"
+	cmd.Aliases = []string{"list"}
+	cmd.Short = "List remembered approvals"
+	cmd.Args = cobra.NoArgs
+}
+
+func (a *ApprovalsList) Run(*cobra.Command, []string) error {
+	store, err := a.root.approvalsStore()
+	if err != nil {
+		return err
+	}
+
+	list, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	if a.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 3, ' ', 0)
+	defer w.Flush()
+
+	_, _ = w.Write([]byte("ID\tTOOL\tINTERPRETER\tSOURCE\tDECISION\tCREATED\n"))
+	for _, approval := range list {
+		decision := "allow"
+		if !approval.Accept {
+			decision = "deny"
+		}
+		printFields(w, []any{approval.ID, approval.Tool, approval.Interpreter, approval.Source, decision,
+			approval.CreatedAt.Local().Format(time.DateTime)})
+	}
+
+	return nil
+}
+
+type ApprovalsRevoke struct {
+	All bool `usage:"Revoke all approvals" local:"true"`
+
+	root *GPTScript
+}
+
+func (a *ApprovalsRevoke) Customize(cmd *cobra.Command) {
+	cmd.Use = "revoke [<id or tool name>...]"
+	cmd.Aliases = []string{"rm"}
+	cmd.SilenceUsage = true
+	cmd.Short = "Revoke remembered approvals, so that the user is asked again"
+}
+
+func (a *ApprovalsRevoke) Run(_ *cobra.Command, args []string) error {
+	if a.All == (len(args) > 0) {
+		return fmt.Errorf("either give the approvals to revoke or use --all")
+	}
+
+	store, err := a.root.approvalsStore()
+	if err != nil {
+		return err
+	}
+
+	if a.All {
+		return store.RevokeAll()
+	}
+	return store.Revoke(args...)
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	cmd2 "github.com/gptscript-ai/cmd"
+	"github.com/gptscript-ai/gptscript/pkg/audit"
+	"github.com/spf13/cobra"
+)
+
+type Audit struct{}
+
+func (a *Audit) Customize(cmd *cobra.Command) {
+	cmd.Use = "audit"
+	cmd.Short = "Work with audit logs written with --audit-log"
+	cmd.Args = cobra.NoArgs
+	cmd.AddCommand(cmd2.Command(&AuditVerify{}))
+}
+
+func (a *Audit) Run(cmd *cobra.Command, _ []string) error {
+	return cmd.Help()
+}
+
+type AuditVerify struct{}
+
+func (a *AuditVerify) Customize(cmd *cobra.Command) {
+	cmd.Use = "verify <file>"
+	cmd.SilenceUsage = true
+	cmd.Short = "Verify that the entries of an audit log haven't been changed, removed, or reordered"
+	cmd.Args = cobra.ExactArgs(1)
+}
+
+func (a *AuditVerify) Run(cmd *cobra.Command, args []string) error {
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	count, lastHash, err := audit.Verify(f)
+	if err != nil {
+		return fmt.Errorf("audit log %s is not valid after %d valid entries: %w", args[0], count, err)
+	}
+
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "audit log %s is valid: %d entries, the hash of the last entry is %s\n", args[0], count, lastHash)
+	return err
+}
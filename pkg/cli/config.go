@@ -0,0 +1,352 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	cmd2 "github.com/gptscript-ai/cmd"
+	"github.com/gptscript-ai/gptscript/pkg/config"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	sourceFlag    = "flag"
+	sourceDefault = "default"
+
+	// noProjectConfig is the value of --project-config that turns off looking for a project config.
+	noProjectConfig = "none"
+)
+
+var (
+	// projectPathOptions are the options that are paths. In the project config, they are relative to its directory.
+	projectPathOptions = map[string]bool{
+		"approvals-file": true,
+		"audit-log":      true,
+		"cache-dir":      true,
+		"config":         true,
+		"dump-state":     true,
+		"policy":         true,
+		"workspace":      true,
+	}
+
+	// projectUnsupportedOptions can't be set in the project config, because they are needed to find it.
+	projectUnsupportedOptions = map[string]bool{
+		"chdir":          true,
+		"help":           true,
+		"project-config": true,
+		"version":        true,
+	}
+
+	// flagEnvVars matches the environment variables that the cmd package adds to the end of the usage of each flag.
+	flagEnvVars = regexp.MustCompile(`\(\$([A-Za-z0-9_,]+)\)$`)
+
+	// redactedOptions have values that aren't shown. The credential overrides are shown separately without their values.
+	redactedOptions = map[string]bool{
+		"credential-override": true,
+		"openai-api-key":      true,
+	}
+)
+
+// loadProjectConfig reads the project config and sets the flags that aren't set on the command line or in the
+// environment to its options. It runs before the flags are read from the environment, and records where the value of
+// each flag comes from.
+func (r *GPTScript) loadProjectConfig(cmd *cobra.Command) error {
+	r.optionSources = map[string]string{}
+	defer r.recordOptionSources(cmd)
+
+	file := types.FirstSet(r.ProjectConfig, os.Getenv("GPTSCRIPT_PROJECT_CONFIG"))
+	if file == noProjectConfig {
+		return nil
+	} else if file == "" {
+		var err error
+		file, err = config.FindProjectConfig(types.FirstSet(r.Chdir, os.Getenv("GPTSCRIPT_CHDIR"), "."))
+		if err != nil || file == "" {
+			return err
+		}
+	}
+
+	project, err := config.ReadProjectConfig(file)
+	if err != nil {
+		return err
+	}
+	r.project = project
+
+	// The environment is set first, because it can also set flags.
+	r.envSources = map[string]string{}
+	for key, value := range project.Env {
+		if _, ok := os.LookupEnv(key); ok {
+			r.envSources[key] = "environment"
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+		r.envSources[key] = project.Location()
+	}
+
+	for _, name := range project.OptionNames() {
+		f := lookupFlag(cmd, name)
+		if f == nil || projectUnsupportedOptions[name] {
+			return fmt.Errorf("invalid project config %s: unknown option %s", project.Location(), name)
+		}
+		if f.Changed || setFromEnv(f) != "" {
+			continue
+		}
+
+		value, _ := project.Option(name)
+		if projectPathOptions[name] {
+			value = project.Path(value)
+		}
+		if err := f.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid project config %s: option %s: %w", project.Location(), name, err)
+		}
+		f.Changed = true
+		r.optionSources[name] = project.Location()
+	}
+
+	return nil
+}
+
+func (r *GPTScript) recordOptionSources(cmd *cobra.Command) {
+	for _, f := range rootFlags(cmd) {
+		if _, ok := r.optionSources[f.Name]; ok {
+			continue
+		}
+		f = lookupFlag(cmd, f.Name)
+		if f.Changed {
+			r.optionSources[f.Name] = sourceFlag
+		} else if env := setFromEnv(f); env != "" {
+			r.optionSources[f.Name] = "$" + env
+		} else {
+			r.optionSources[f.Name] = sourceDefault
+		}
+	}
+}
+
+// lookupFlag returns the flag of the command, or of the root command if it is not a flag of the command.
+func lookupFlag(cmd *cobra.Command, name string) *pflag.Flag {
+	if f := cmd.Flags().Lookup(name); f != nil {
+		return f
+	}
+	if f := cmd.Root().PersistentFlags().Lookup(name); f != nil {
+		return f
+	}
+	return cmd.Root().LocalNonPersistentFlags().Lookup(name)
+}
+
+// rootFlags returns the flags of the root command, which are the options of gptscript, sorted by name.
+func rootFlags(cmd *cobra.Command) []*pflag.Flag {
+	var result []*pflag.Flag
+	add := func(f *pflag.Flag) {
+		if f.Name != "help" && f.Name != "version" {
+			result = append(result, f)
+		}
+	}
+	cmd.Root().PersistentFlags().VisitAll(add)
+	cmd.Root().LocalNonPersistentFlags().VisitAll(add)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// setFromEnv returns the environment variable that the flag is set from, if any.
+func setFromEnv(f *pflag.Flag) string {
+	m := flagEnvVars.FindStringSubmatch(f.Usage)
+	if m == nil {
+		return ""
+	}
+	for _, env := range strings.Split(m[1], ",") {
+		if os.Getenv(env) != "" {
+			return env
+		}
+	}
+	return ""
+}
+
+// projectCredentialOverrides returns the credential overrides of the project and the command line. Those of the
+// command line come last, so that they take precedence.
+func (r *GPTScript) projectCredentialOverrides() []string {
+	if r.project == nil {
+		return r.CredentialOverride
+	}
+	return append(append([]string{}, r.project.CredentialOverrides...), r.CredentialOverride...)
+}
+
+type Config struct {
+	root *GPTScript
+}
+
+func (c *Config) Customize(cmd *cobra.Command) {
+	cmd.Use = "config"
+	cmd.Short = "Work with the project config in " + config.ProjectFileName
+	cmd.Args = cobra.NoArgs
+	cmd.AddCommand(cmd2.Command(&ConfigShow{root: c.root}))
+}
+
+func (c *Config) Run(cmd *cobra.Command, _ []string) error {
+	return cmd.Help()
+}
+
+type ConfigShow struct {
+	JSON    bool `usage:"Output the configuration as JSON" local:"true"`
+	ShowEnv bool `usage:"Show the values of the environment variables of the project config, which are redacted by default" local:"true"`
+
+	root *GPTScript
+}
+
+type configValue struct {
+	Name   string `json:"name,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Source string `json:"source"`
+}
+
+type effectiveConfig struct {
+	ProjectConfig       string        `json:"projectConfig,omitempty"`
+	Options             []configValue `json:"options"`
+	Env                 []configValue `json:"env,omitempty"`
+	CredentialOverrides []configValue `json:"credentialOverrides,omitempty"`
+	ModelAliases        []configValue `json:"modelAliases,omitempty"`
+	Policies            []configValue `json:"policies,omitempty"`
+}
+
+func (c *ConfigShow) Customize(cmd *cobra.Command) {
+	cmd.Use = "show"
+	cmd.Short = "Show the effective configuration, and where each value comes from"
+	cmd.Args = cobra.NoArgs
+}
+
+func (c *ConfigShow) Run(cmd *cobra.Command, _ []string) error {
+	cfg := c.root.effectiveConfig(cmd, c.ShowEnv)
+
+	if c.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(cfg)
+	}
+
+	if cfg.ProjectConfig == "" {
+		fmt.Printf("Project config: none\n\n")
+	} else {
+		fmt.Printf("Project config: %s\n\n", cfg.ProjectConfig)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 3, ' ', 0)
+	defer w.Flush()
+
+	var printed bool
+	for _, section := range []struct {
+		header string
+		values []configValue
+	}{
+		{"OPTION\tVALUE\tSOURCE\n", cfg.Options},
+		{"ENV\tVALUE\tSOURCE\n", cfg.Env},
+		{"CREDENTIAL OVERRIDE\tENV\tSOURCE\n", cfg.CredentialOverrides},
+		{"MODEL ALIAS\tMODEL\tSOURCE\n", cfg.ModelAliases},
+		{"POLICY\t\tSOURCE\n", cfg.Policies},
+	} {
+		if len(section.values) == 0 {
+			continue
+		}
+		if printed {
+			_, _ = w.Write([]byte("\n"))
+		}
+		printed = true
+		_, _ = w.Write([]byte(section.header))
+		for _, v := range section.values {
+			printFields(w, []any{v.Name, v.Value, v.Source})
+		}
+	}
+
+	return nil
+}
+
+// effectiveConfig returns the configuration without secrets. The values of the environment variables are only included
+// if showEnv is set, because the project config is where API keys and tokens for the tools of the project end up.
+func (r *GPTScript) effectiveConfig(cmd *cobra.Command, showEnv bool) effectiveConfig {
+	var result effectiveConfig
+
+	for _, f := range rootFlags(cmd) {
+		if f.Hidden {
+			continue
+		}
+		f = lookupFlag(cmd, f.Name)
+		value := f.Value.String()
+		if f.Value.Type() == "stringSlice" {
+			value = strings.Trim(value, "[]")
+		}
+		if value != "" && redactedOptions[f.Name] {
+			value = "<redacted>"
+		}
+		result.Options = append(result.Options, configValue{
+			Name:   f.Name,
+			Value:  value,
+			Source: r.optionSources[f.Name],
+		})
+	}
+
+	if r.project != nil {
+		result.ProjectConfig = r.project.Location()
+
+		for _, key := range sortedKeys(r.project.Env) {
+			value := os.Getenv(key)
+			if value != "" && !showEnv {
+				value = "<redacted>"
+			}
+			result.Env = append(result.Env, configValue{Name: key, Value: value, Source: r.envSources[key]})
+		}
+		// The credential overrides of the project come first, because those of the command line take precedence.
+		result.CredentialOverrides = credentialOverrideValues(r.project.CredentialOverrides, r.project.Location())
+		for _, alias := range sortedKeys(r.project.ModelAliases) {
+			result.ModelAliases = append(result.ModelAliases, configValue{Name: alias, Value: r.project.ModelAliases[alias], Source: r.project.Location()})
+		}
+		for _, p := range r.project.Policies {
+			result.Policies = append(result.Policies, configValue{Name: p, Source: r.project.Location()})
+		}
+	}
+
+	result.CredentialOverrides = append(result.CredentialOverrides, credentialOverrideValues(r.CredentialOverride, r.optionSources["credential-override"])...)
+
+	return result
+}
+
+// credentialOverrideValues returns the credential overrides with the names of the credentials and environment
+// variables, but not their values.
+func credentialOverrideValues(overrides []string, source string) []configValue {
+	var result []configValue
+	for _, o := range overrides {
+		if o == "" {
+			continue
+		}
+		// Overrides given on the command line can be split at the commas between the environment variables.
+		credName, envs, ok := strings.Cut(o, ":")
+		if !ok {
+			credName, envs = "", o
+		}
+		var redacted []string
+		for _, env := range strings.Split(envs, ",") {
+			if key, _, ok := strings.Cut(env, "="); ok {
+				env = key + "=<redacted>"
+			}
+			redacted = append(redacted, env)
+		}
+		result = append(result, configValue{Name: credName, Value: strings.Join(redacted, ","), Source: source})
+	}
+	return result
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gptscript-ai/gptscript/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+// configShow runs config show with the args and returns the configuration that it prints.
+func configShow(t *testing.T, args ...string) (effectiveConfig, error) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	cmd := New()
+	cmd.SetArgs(append([]string{"config", "show", "--json"}, args...))
+	runErr := cmd.Execute()
+	require.NoError(t, w.Close())
+	os.Stdout = stdout
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	var cfg effectiveConfig
+	if runErr == nil {
+		require.NoError(t, json.Unmarshal(data, &cfg))
+	}
+	return cfg, runErr
+}
+
+func option(cfg effectiveConfig, name string) configValue {
+	for _, o := range cfg.Options {
+		if o.Name == name {
+			return o
+		}
+	}
+	return configValue{}
+}
+
+func TestLoadProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, config.ProjectFileName)
+	require.NoError(t, os.WriteFile(file, []byte(`
+options:
+  audit-log: audit.jsonl
+  default-model-provider: project-provider
+  workspace: ws
+env:
+  GPTSCRIPT_TEST_PROJECT_TOKEN: s3cr3t
+`), 0644))
+	t.Setenv("GPTSCRIPT_DEFAULT_MODEL_PROVIDER", "env-provider")
+	t.Cleanup(func() {
+		_ = os.Unsetenv("GPTSCRIPT_TEST_PROJECT_TOKEN")
+	})
+
+	cfg, err := configShow(t, "--project-config", file, "--workspace", "flag-ws")
+	require.NoError(t, err)
+	require.Equal(t, file, cfg.ProjectConfig)
+
+	// A flag takes precedence over the environment, which takes precedence over the project config.
+	require.Equal(t, configValue{Name: "workspace", Value: "flag-ws", Source: sourceFlag}, option(cfg, "workspace"))
+	require.Equal(t, configValue{Name: "default-model-provider", Value: "env-provider", Source: "$GPTSCRIPT_DEFAULT_MODEL_PROVIDER"}, option(cfg, "default-model-provider"))
+	require.Equal(t, configValue{Name: "audit-log", Value: filepath.Join(dir, "audit.jsonl"), Source: file}, option(cfg, "audit-log"))
+	require.Equal(t, sourceDefault, option(cfg, "policy").Source)
+
+	// The values of the environment are redacted unless they are asked for.
+	require.Equal(t, []configValue{{Name: "GPTSCRIPT_TEST_PROJECT_TOKEN", Value: "<redacted>", Source: file}}, cfg.Env)
+	require.NoError(t, os.Unsetenv("GPTSCRIPT_TEST_PROJECT_TOKEN"))
+	cfg, err = configShow(t, "--project-config", file, "--show-env")
+	require.NoError(t, err)
+	require.Equal(t, []configValue{{Name: "GPTSCRIPT_TEST_PROJECT_TOKEN", Value: "s3cr3t", Source: file}}, cfg.Env)
+}
+
+func TestLoadProjectConfigUnknownOption(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, config.ProjectFileName)
+
+	for option, value := range map[string]string{
+		"no-such-option": "yes",
+		// chdir is used to find the project config, so it can't be set by it.
+		"chdir": "other",
+	} {
+		require.NoError(t, os.WriteFile(file, []byte("options:\n  "+option+": "+value+"\n"), 0644))
+		_, err := configShow(t, "--project-config", file)
+		require.ErrorContains(t, err, "unknown option "+option)
+	}
+}
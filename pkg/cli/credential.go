@@ -35,6 +35,11 @@ func (c *Credential) Customize(cmd *cobra.Command) {
 	cmd.Args = cobra.NoArgs
 	cmd.AddCommand(cmd2.Command(&Delete{root: c.root}))
 	cmd.AddCommand(cmd2.Command(&Show{root: c.root}))
+	cmd.AddCommand(cmd2.Command(&Migrate{root: c.root}))
+	cmd.AddCommand(cmd2.Command(&Set{root: c.root}))
+	cmd.AddCommand(cmd2.Command(&Import{root: c.root}))
+	cmd.AddCommand(cmd2.Command(&Export{root: c.root}))
+	cmd.AddCommand(cmd2.Command(&RenameContext{root: c.root}))
 }
 
 func (c *Credential) Run(cmd *cobra.Command, _ []string) error {
@@ -43,10 +48,12 @@ func (c *Credential) Run(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("failed to read CLI config: %w", err)
 	}
 
-	ctx := c.root.CredentialContext
+	credCtxs := c.root.credentialContexts()
 	if c.AllContexts {
-		ctx = "*"
+		credCtxs = []string{"*"}
 	}
+	// Show which context each credential comes from if there is more than one.
+	showContext := len(credCtxs) > 1 || credCtxs[0] == "*"
 
 	opts, err := c.root.NewGPTScriptOpts()
 	if err != nil {
@@ -63,7 +70,7 @@ func (c *Credential) Run(cmd *cobra.Command, _ []string) error {
 	}
 
 	// Initialize the credential store and get all the credentials.
-	store, err := credentials.NewStore(cfg, opts.Runner.RuntimeManager, ctx, opts.Cache.CacheDir)
+	store, err := credentials.NewStore(cfg, opts.Runner.RuntimeManager, credCtxs, opts.Cache.CacheDir)
 	if err != nil {
 		return fmt.Errorf("failed to get credentials store: %w", err)
 	}
@@ -77,7 +84,7 @@ func (c *Credential) Run(cmd *cobra.Command, _ []string) error {
 	defer w.Flush()
 
 	// Sort credentials and print column names, depending on the options.
-	if c.AllContexts {
+	if showContext {
 		// Sort credentials by context
 		sort.Slice(creds, func(i, j int) bool {
 			if creds[i].Context == creds[j].Context {
@@ -114,7 +121,7 @@ func (c *Credential) Run(cmd *cobra.Command, _ []string) error {
 		}
 
 		var fields []any
-		if c.AllContexts {
+		if showContext {
 			fields = []any{cred.Context, cred.ToolName, expires}
 		} else {
 			fields = []any{cred.ToolName, expires}
@@ -135,6 +142,31 @@ func (c *Credential) Run(cmd *cobra.Command, _ []string) error {
 	return nil
 }
 
+// newCredentialStore returns the configured credential store for the given contexts. Stores that are used together must
+// share cfg, because the file store saves the whole config, which would drop the changes made through another copy.
+func newCredentialStore(cmd *cobra.Command, root *GPTScript, cfg *config.CLIConfig, credCtxs ...string) (credentials.CredentialStore, error) {
+	opts, err := root.NewGPTScriptOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Cache = cache.Complete(opts.Cache)
+	opts.Runner = runner.Complete(opts.Runner)
+	if opts.Runner.RuntimeManager == nil {
+		opts.Runner.RuntimeManager = runtimes.Default(opts.Cache.CacheDir)
+	}
+
+	if err = opts.Runner.RuntimeManager.SetUpCredentialHelpers(cmd.Context(), cfg); err != nil {
+		return nil, err
+	}
+
+	store, err := credentials.NewStore(cfg, opts.Runner.RuntimeManager, credCtxs, opts.Cache.CacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials store: %w", err)
+	}
+	return store, nil
+}
+
 func printFields(w *tabwriter.Writer, fields []any) {
 	if len(fields) == 0 {
 		return
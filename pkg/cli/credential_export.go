@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/gptscript-ai/gptscript/pkg/config"
+	"github.com/gptscript-ai/gptscript/pkg/credentials"
+	"github.com/spf13/cobra"
+)
+
+const (
+	credentialFormatJSON   = "json"
+	credentialFormatDotenv = "dotenv"
+)
+
+type Export struct {
+	root        *GPTScript
+	AllContexts bool   `usage:"Export credentials of all contexts" local:"true"`
+	Format      string `usage:"Format of the exported credentials: json or dotenv" local:"true" default:"json"`
+	Encrypt     bool   `usage:"Encrypt the exported credentials with GPTSCRIPT_CREDENTIAL_PASSPHRASE or GPTSCRIPT_CREDENTIAL_KEY_FILE" local:"true"`
+}
+
+func (c *Export) Customize(cmd *cobra.Command) {
+	cmd.Use = "export [credential name...]"
+	cmd.SilenceUsage = true
+	cmd.Short = "Export stored credentials so that they can be imported on another machine"
+	cmd.Long = `Export stored credentials so that they can be imported on another machine, all of them or only the ones named.
+
+The json format includes the expiration and refresh token of each credential, the dotenv format only has the
+environment variables. Use --output to write the credentials to a file instead of stdout.`
+}
+
+func (c *Export) Run(cmd *cobra.Command, args []string) error {
+	if c.Format != credentialFormatJSON && c.Format != credentialFormatDotenv {
+		return fmt.Errorf("invalid format %q, use %s or %s", c.Format, credentialFormatJSON, credentialFormatDotenv)
+	}
+
+	credCtxs := c.root.credentialContexts()
+	if c.AllContexts {
+		credCtxs = []string{"*"}
+	}
+
+	cfg, err := config.ReadCLIConfig(c.root.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CLI config: %w", err)
+	}
+
+	store, err := newCredentialStore(cmd, c.root, cfg, credCtxs...)
+	if err != nil {
+		return err
+	}
+
+	creds, err := store.List(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to list credentials: %w", err)
+	}
+
+	if len(args) > 0 {
+		names := map[string]bool{}
+		for _, arg := range args {
+			names[arg] = false
+		}
+
+		var selected []credentials.Credential
+		for _, cred := range creds {
+			if _, ok := names[cred.ToolName]; ok {
+				selected = append(selected, cred)
+				names[cred.ToolName] = true
+			}
+		}
+		for _, arg := range args {
+			if !names[arg] {
+				return fmt.Errorf("credential %q not found", arg)
+			}
+		}
+		creds = selected
+	}
+
+	sort.Slice(creds, func(i, j int) bool {
+		if creds[i].Context == creds[j].Context {
+			return creds[i].ToolName < creds[j].ToolName
+		}
+		return creds[i].Context < creds[j].Context
+	})
+
+	var data []byte
+	if c.Format == credentialFormatDotenv {
+		data = credentials.MarshalDotenv(creds)
+	} else {
+		if creds == nil {
+			creds = []credentials.Credential{}
+		}
+		data, err = json.MarshalIndent(creds, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	}
+
+	if c.Encrypt {
+		if data, err = credentials.Encrypt(data); err != nil {
+			return fmt.Errorf("failed to encrypt credentials: %w", err)
+		}
+	}
+
+	if c.root.Output == "" || c.root.Output == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(c.root.Output, data, 0600)
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gptscript-ai/gptscript/pkg/config"
+	"github.com/gptscript-ai/gptscript/pkg/credentials"
+	"github.com/spf13/cobra"
+)
+
+type Import struct {
+	root      *GPTScript
+	Format    string `usage:"Format of the credentials: json or dotenv (default is detected from the contents)" local:"true"`
+	Name      string `usage:"Name of the credential for dotenv variables that are not preceded by a credential comment" local:"true"`
+	Overwrite bool   `usage:"Replace credentials that already exist" local:"true"`
+}
+
+func (c *Import) Customize(cmd *cobra.Command) {
+	cmd.Use = "import <file>"
+	cmd.SilenceUsage = true
+	cmd.Short = "Import credentials from a file created by export, or from a dotenv file"
+	cmd.Long = `Import credentials from a file created by export, or from a dotenv file. Use - to read from stdin.
+
+Credentials are imported into the context they were exported from, or into --credential-context if the file doesn't
+say. Encrypted files are decrypted with GPTSCRIPT_CREDENTIAL_PASSPHRASE or GPTSCRIPT_CREDENTIAL_KEY_FILE.`
+	cmd.Example = `  gptscript credential import credentials.json
+  gptscript credential import --name my-api .env`
+	cmd.Args = cobra.ExactArgs(1)
+}
+
+func (c *Import) Run(cmd *cobra.Command, args []string) error {
+	var (
+		data []byte
+		err  error
+	)
+	if args[0] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	if credentials.IsEncrypted(data) {
+		if data, err = credentials.Decrypt(data); err != nil {
+			return err
+		}
+	}
+
+	format := c.Format
+	if format == "" {
+		format = credentialFormatDotenv
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+			format = credentialFormatJSON
+		}
+	}
+
+	var creds []credentials.Credential
+	switch format {
+	case credentialFormatJSON:
+		if err := json.Unmarshal(data, &creds); err != nil {
+			return fmt.Errorf("failed to parse credentials: %w", err)
+		}
+	case credentialFormatDotenv:
+		if creds, err = credentials.UnmarshalDotenv(data, c.Name); err != nil {
+			return fmt.Errorf("failed to parse credentials: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid format %q, use %s or %s", format, credentialFormatJSON, credentialFormatDotenv)
+	}
+
+	cfg, err := config.ReadCLIConfig(c.root.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CLI config: %w", err)
+	}
+
+	stores := map[string]credentials.CredentialStore{}
+	for _, cred := range creds {
+		if cred.ToolName == "" {
+			return fmt.Errorf("credential without a name in %s", args[0])
+		}
+		if cred.Context == "" {
+			cred.Context = c.root.credentialWriteContext()
+		}
+		if cred.Type == "" {
+			cred.Type = credentials.CredentialTypeTool
+		}
+
+		store, ok := stores[cred.Context]
+		if !ok {
+			if store, err = newCredentialStore(cmd, c.root, cfg, cred.Context); err != nil {
+				return err
+			}
+			stores[cred.Context] = store
+		}
+
+		if !c.Overwrite {
+			if _, exists, err := store.Get(cmd.Context(), cred.ToolName); err != nil {
+				return fmt.Errorf("failed to get credential %s: %w", cred.ToolName, err)
+			} else if exists {
+				return fmt.Errorf("credential %s already exists in context %s, use --overwrite to replace it", cred.ToolName, cred.Context)
+			}
+		}
+
+		if err := store.Add(cmd.Context(), cred); err != nil {
+			return fmt.Errorf("failed to store credential %s: %w", cred.ToolName, err)
+		}
+	}
+
+	fmt.Printf("Imported %d credentials\n", len(creds))
+	return nil
+}
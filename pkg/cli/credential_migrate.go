@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/gptscript-ai/gptscript/pkg/cache"
+	"github.com/gptscript-ai/gptscript/pkg/config"
+	"github.com/gptscript-ai/gptscript/pkg/credentials"
+	"github.com/gptscript-ai/gptscript/pkg/repos/runtimes"
+	"github.com/spf13/cobra"
+)
+
+type Migrate struct {
+	root *GPTScript
+	From string `usage:"Credential store to move the credentials from (default is the configured store)" local:"true"`
+	Keep bool   `usage:"Keep the credentials in the old credential store" local:"true"`
+}
+
+func (c *Migrate) Customize(cmd *cobra.Command) {
+	cmd.Use = "migrate <credential store>"
+	cmd.SilenceUsage = true
+	cmd.Short = "Move the stored credentials of all contexts to another credential store"
+	cmd.Long = `Move the stored credentials of all contexts to another credential store, such as "encrypted" or "file".
+
+The configured credential store is changed to the new store unless --from is used.`
+	cmd.Args = cobra.ExactArgs(1)
+}
+
+func (c *Migrate) Run(cmd *cobra.Command, args []string) error {
+	cfg, err := config.ReadCLIConfig(c.root.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CLI config: %w", err)
+	}
+
+	from, to := cfg.CredentialsStore, args[0]
+	if c.From != "" {
+		from = c.From
+	}
+	for _, helper := range []string{from, to} {
+		if err := config.ValidateCredentialHelper(helper); err != nil {
+			return err
+		}
+	}
+	if from == to {
+		return fmt.Errorf("credentials are already stored in %s", to)
+	}
+
+	cacheDir := cache.Complete(cache.Options(c.root.CacheOptions)).CacheDir
+
+	fromStore, err := c.newStore(cmd, cfg, cacheDir, from, "*")
+	if err != nil {
+		return err
+	}
+
+	creds, err := fromStore.List(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to list credentials in %s: %w", from, err)
+	}
+
+	for _, cred := range creds {
+		toStore, err := c.newStore(cmd, cfg, cacheDir, to, cred.Context)
+		if err != nil {
+			return err
+		}
+		if err := toStore.Add(cmd.Context(), cred); err != nil {
+			return fmt.Errorf("failed to add credential %s to %s: %w", cred.ToolName, to, err)
+		}
+	}
+
+	if !c.Keep {
+		for _, cred := range creds {
+			ctxStore, err := c.newStore(cmd, cfg, cacheDir, from, cred.Context)
+			if err != nil {
+				return err
+			}
+			if err := ctxStore.Remove(cmd.Context(), cred.ToolName); err != nil {
+				return fmt.Errorf("failed to remove credential %s from %s: %w", cred.ToolName, from, err)
+			}
+		}
+	}
+
+	if c.From == "" {
+		cfg.CredentialsStore = to
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("failed to save CLI config: %w", err)
+		}
+	}
+
+	fmt.Printf("Moved %d credentials from %s to %s\n", len(creds), from, to)
+	return nil
+}
+
+// newStore returns a store for the given credential store and context. Each store gets its own runtime manager because
+// the credential helper that a runtime manager sets up is based on the credential store in the config that it is given.
+func (c *Migrate) newStore(cmd *cobra.Command, cfg *config.CLIConfig, cacheDir, helper, credCtx string) (credentials.CredentialStore, error) {
+	helperCfg := *cfg
+	helperCfg.CredentialsStore = helper
+
+	rm := runtimes.Default(cacheDir)
+	if err := rm.SetUpCredentialHelpers(cmd.Context(), &helperCfg); err != nil {
+		return nil, err
+	}
+
+	store, err := credentials.NewStoreWithHelper(cfg, rm, helper, []string{credCtx}, "", cacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials store: %w", err)
+	}
+	return store, nil
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/gptscript-ai/gptscript/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+type RenameContext struct {
+	root      *GPTScript
+	Overwrite bool `usage:"Replace credentials that already exist in the new context" local:"true"`
+}
+
+func (c *RenameContext) Customize(cmd *cobra.Command) {
+	cmd.Use = "rename-context <old context> <new context>"
+	cmd.SilenceUsage = true
+	cmd.Short = "Move all credentials of a context to another context"
+	cmd.Args = cobra.ExactArgs(2)
+}
+
+func (c *RenameContext) Run(cmd *cobra.Command, args []string) error {
+	from, to := args[0], args[1]
+	if from == to {
+		return fmt.Errorf("the old and new context are both %s", from)
+	}
+
+	cfg, err := config.ReadCLIConfig(c.root.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CLI config: %w", err)
+	}
+
+	fromStore, err := newCredentialStore(cmd, c.root, cfg, from)
+	if err != nil {
+		return err
+	}
+	toStore, err := newCredentialStore(cmd, c.root, cfg, to)
+	if err != nil {
+		return err
+	}
+
+	creds, err := fromStore.List(cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to list credentials: %w", err)
+	}
+	if len(creds) == 0 {
+		return fmt.Errorf("there are no credentials in context %s", from)
+	}
+
+	// Check for conflicts before changing anything, so that a conflict doesn't leave the credentials split between the
+	// two contexts.
+	if !c.Overwrite {
+		for _, cred := range creds {
+			if _, exists, err := toStore.Get(cmd.Context(), cred.ToolName); err != nil {
+				return fmt.Errorf("failed to get credential %s: %w", cred.ToolName, err)
+			} else if exists {
+				return fmt.Errorf("credential %s already exists in context %s, use --overwrite to replace it", cred.ToolName, to)
+			}
+		}
+	}
+
+	for _, cred := range creds {
+		if err := toStore.Add(cmd.Context(), cred); err != nil {
+			return fmt.Errorf("failed to add credential %s to context %s: %w", cred.ToolName, to, err)
+		}
+		if err := fromStore.Remove(cmd.Context(), cred.ToolName); err != nil {
+			return fmt.Errorf("failed to remove credential %s from context %s: %w", cred.ToolName, from, err)
+		}
+	}
+
+	fmt.Printf("Moved %d credentials from context %s to %s\n", len(creds), from, to)
+	return nil
+}
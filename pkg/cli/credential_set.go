@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/gptscript-ai/gptscript/pkg/config"
+	"github.com/gptscript-ai/gptscript/pkg/credentials"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+type Set struct {
+	root *GPTScript
+}
+
+func (c *Set) Customize(cmd *cobra.Command) {
+	cmd.Use = "set <credential name> <env var>[=<value>]..."
+	cmd.SilenceUsage = true
+	cmd.Short = "Store a credential with the given environment variables"
+	cmd.Long = `Store a credential with the given environment variables, replacing the credential if it already exists.
+
+The value of each environment variable without one is asked for, without echoing it. If stdin is not a terminal, the
+values are read from stdin, one per line.`
+	cmd.Example = `  gptscript credential set my-api MY_API_KEY
+  gptscript credential set my-db DB_USER=admin DB_PASSWORD`
+	cmd.Args = cobra.MinimumNArgs(2)
+}
+
+func (c *Set) Run(cmd *cobra.Command, args []string) error {
+	cred := credentials.Credential{
+		ToolName: args[0],
+		Type:     credentials.CredentialTypeTool,
+		Env:      map[string]string{},
+	}
+
+	var (
+		stdin       *bufio.Reader
+		interactive = term.IsTerminal(int(os.Stdin.Fd()))
+	)
+	for _, arg := range args[1:] {
+		key, value, ok := strings.Cut(arg, "=")
+		if key == "" {
+			return fmt.Errorf("invalid environment variable %q", arg)
+		}
+
+		if !ok {
+			if interactive {
+				if err := survey.AskOne(&survey.Password{Message: key}, &value, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr)); err != nil {
+					return err
+				}
+			} else {
+				if stdin == nil {
+					stdin = bufio.NewReader(os.Stdin)
+				}
+				line, err := stdin.ReadString('\n')
+				if err != nil && line == "" {
+					return fmt.Errorf("failed to read the value of %s from stdin: %w", key, err)
+				}
+				value = strings.TrimRight(line, "\r\n")
+			}
+		}
+
+		cred.Env[key] = value
+	}
+
+	cfg, err := config.ReadCLIConfig(c.root.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CLI config: %w", err)
+	}
+
+	store, err := newCredentialStore(cmd, c.root, cfg, c.root.credentialWriteContext())
+	if err != nil {
+		return err
+	}
+
+	if err := store.Add(cmd.Context(), cred); err != nil {
+		return fmt.Errorf("failed to store credential: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gptscript-ai/gptscript/pkg/cache"
+	"github.com/gptscript-ai/gptscript/pkg/daemon"
+	"github.com/spf13/cobra"
+)
+
+type Daemons struct {
+	JSON bool `usage:"Output the daemons as JSON" local:"true"`
+
+	root *GPTScript
+}
+
+func (d *Daemons) Customize(cmd *cobra.Command) {
+	cmd.Use = "daemons"
+	cmd.Aliases = []string{"daemon"}
+	cmd.Short = "List daemon tools started by running gptscript processes"
+	cmd.Args = cobra.NoArgs
+}
+
+func (d *Daemons) Run(*cobra.Command, []string) error {
+	cacheOpts := cache.Complete(cache.Options(d.root.CacheOptions))
+	daemons, err := daemon.List(daemon.Dir(cacheOpts.CacheDir))
+	if err != nil {
+		return fmt.Errorf("failed to list daemons: %w", err)
+	}
+
+	if d.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(daemons)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 1, 3, ' ', 0)
+	defer w.Flush()
+
+	_, _ = w.Write([]byte("TOOL\tADDRESS\tPID\tSTATUS\tUPTIME\tRESTARTS\tTOOL ID\tLOG\n"))
+	for _, info := range daemons {
+		address := fmt.Sprint(info.Port)
+		if info.Socket != "" {
+			address = info.Socket
+		}
+		uptime := "-"
+		if info.Status == daemon.StatusRunning {
+			uptime = info.Uptime().Truncate(time.Second).String()
+		}
+		printFields(w, []any{info.ToolName, address, fmt.Sprint(info.PID), string(info.Status), uptime,
+			fmt.Sprint(info.Restarts), info.ToolID, info.LogFile})
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gptscript-ai/gptscript/pkg/dataset"
+	"github.com/gptscript-ai/gptscript/pkg/loader"
+	"github.com/spf13/cobra"
+)
+
+type EvalDataset struct {
+	Scorer      []string `usage:"The scorers of the outputs: exact, contains, json or judge (default exact)" local:"true"`
+	JSONField   []string `usage:"The fields that the json scorer compares, as dotted paths (default all the fields of the expected output)" local:"true" name:"json-field"`
+	Criteria    string   `usage:"The criteria of the judge scorer, for the examples that don't have their own" local:"true"`
+	Concurrency int      `usage:"The number of examples that run at the same time" local:"true" default:"4"`
+	Report      string   `usage:"Write the report as JSON to this file" local:"true"`
+	Diff        bool     `usage:"Compare two reports instead of running a dataset" local:"true"`
+
+	gptscript *GPTScript
+}
+
+func (e *EvalDataset) Customize(cmd *cobra.Command) {
+	cmd.Use = "eval-dataset <tool.gpt> <dataset.jsonl>"
+	cmd.SilenceUsage = true
+	cmd.Short = "Run a tool over the examples of a dataset and score the outputs, or compare two reports with --diff"
+	cmd.Args = cobra.ExactArgs(2)
+}
+
+func (e *EvalDataset) Run(cmd *cobra.Command, args []string) error {
+	if e.Diff {
+		base, err := dataset.ReadReport(args[0])
+		if err != nil {
+			return err
+		}
+		head, err := dataset.ReadReport(args[1])
+		if err != nil {
+			return err
+		}
+		return dataset.WriteDiff(os.Stdout, base, head)
+	}
+
+	examples, err := dataset.Load(args[1])
+	if err != nil {
+		return err
+	}
+
+	opts, err := e.gptscript.NewGPTScriptOpts()
+	if err != nil {
+		return err
+	}
+
+	scorerOpts := dataset.ScorerOptions{
+		JSONFields: e.JSONField,
+		Criteria:   e.Criteria,
+	}
+	if j, err := newJudge(opts); err != nil {
+		return err
+	} else if j != nil {
+		scorerOpts.Judge = j
+	}
+
+	names := e.Scorer
+	if len(names) == 0 {
+		names = []string{"exact"}
+	}
+	scorers, err := dataset.NewScorers(names, scorerOpts)
+	if err != nil {
+		return err
+	}
+
+	runner, err := dataset.NewRunner(cmd.Context(), opts)
+	if err != nil {
+		return err
+	}
+	defer runner.Close()
+
+	prg, err := loader.Program(cmd.Context(), args[0], "", loader.Options{
+		Cache: runner.Cache(),
+	})
+	if err != nil {
+		return err
+	}
+
+	report := runner.Run(cmd.Context(), prg, examples, dataset.Options{
+		Scorers:     scorers,
+		Concurrency: e.Concurrency,
+		Progress: func(result dataset.Result) {
+			var scores []string
+			for _, scorer := range scorers {
+				scores = append(scores, fmt.Sprintf("%s=%.2f", scorer.Name(), result.Scores[scorer.Name()]))
+			}
+			if result.Error != "" {
+				scores = append(scores, "error: "+result.Error)
+			}
+			fmt.Printf("%s  %s (%.2fs)\n", result.ID, strings.Join(scores, " "), result.Seconds)
+		},
+	})
+	report.Tool = args[0]
+	report.Dataset = args[1]
+
+	fmt.Println()
+	if err := dataset.WriteSummary(os.Stdout, report); err != nil {
+		return err
+	}
+
+	if e.Report != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(e.Report, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
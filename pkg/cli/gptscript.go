@@ -14,11 +14,14 @@ import (
 	"github.com/fatih/color"
 	"github.com/gptscript-ai/cmd"
 	gptscript2 "github.com/gptscript-ai/go-gptscript"
+	"github.com/gptscript-ai/gptscript/pkg/approvals"
 	"github.com/gptscript-ai/gptscript/pkg/assemble"
 	"github.com/gptscript-ai/gptscript/pkg/auth"
 	"github.com/gptscript-ai/gptscript/pkg/builtin"
 	"github.com/gptscript-ai/gptscript/pkg/cache"
 	"github.com/gptscript-ai/gptscript/pkg/chat"
+	"github.com/gptscript-ai/gptscript/pkg/config"
+	"github.com/gptscript-ai/gptscript/pkg/debugger"
 	"github.com/gptscript-ai/gptscript/pkg/env"
 	"github.com/gptscript-ai/gptscript/pkg/gptscript"
 	"github.com/gptscript-ai/gptscript/pkg/input"
@@ -27,6 +30,8 @@ import (
 	"github.com/gptscript-ai/gptscript/pkg/monitor"
 	"github.com/gptscript-ai/gptscript/pkg/mvl"
 	"github.com/gptscript-ai/gptscript/pkg/openai"
+	"github.com/gptscript-ai/gptscript/pkg/policy"
+	"github.com/gptscript-ai/gptscript/pkg/redact"
 	"github.com/gptscript-ai/gptscript/pkg/runner"
 	"github.com/gptscript-ai/gptscript/pkg/system"
 	"github.com/gptscript-ai/gptscript/pkg/types"
@@ -49,6 +54,7 @@ type GPTScript struct {
 	DisplayOptions
 	Color          *bool  `usage:"Use color in output (default true)" default:"true"`
 	Confirm        bool   `usage:"Prompt before running potentially dangerous commands"`
+	DebugStep      bool   `usage:"Pause before each tool call and request to the LLM to inspect, change, or skip them"`
 	Debug          bool   `usage:"Enable debug logging"`
 	NoTrunc        bool   `usage:"Do not truncate long log messages"`
 	Quiet          *bool  `usage:"No output logging (set --quiet=false to force on even when there is no TTY)" short:"q"`
@@ -64,7 +70,8 @@ type GPTScript struct {
 	Chdir                    string   `usage:"Change current working directory" short:"C"`
 	Daemon                   bool     `usage:"Run tool as a daemon" local:"true" hidden:"true"`
 	Ports                    string   `usage:"The port range to use for ephemeral daemon ports (ex: 11000-12000)" hidden:"true"`
-	CredentialContext        string   `usage:"Context name in which to store credentials" default:"default"`
+	CredentialContext        []string `usage:"Context names in which to look for credentials, in order (default \"default\", ex: --credential-context personal,team)"`
+	CredentialWriteContext   string   `usage:"Context name in which to store new credentials (default is the first credential context)"`
 	CredentialOverride       []string `usage:"Credentials to override (ex: --credential-override github.com/example/cred-tool:API_TOKEN=1234)"`
 	ChatState                string   `usage:"The chat state to continue, or null to start a new chat and return the state" local:"true"`
 	ForceChat                bool     `usage:"Force an interactive chat session if even the top level tool is not a chat tool" local:"true"`
@@ -75,8 +82,18 @@ type GPTScript struct {
 	SaveChatStateFile        string   `usage:"A file to save the chat state to so that a conversation can be resumed with --chat-state" local:"true"`
 	DefaultModelProvider     string   `usage:"Default LLM model provider to use, this will override OpenAI settings"`
 	GithubEnterpriseHostname string   `usage:"The host name for a Github Enterprise instance to enable for remote loading" local:"true"`
-
-	readData []byte
+	Policy                   string   `usage:"A YAML or JSON policy file with rules that allow, deny, or ask before tools are called"`
+	AuditLog                 string   `usage:"Append a tamper-evident log of the commands run, files written, URLs fetched and credentials used to this file"`
+	ApprovalsFile            string   `usage:"The file to store the answers to confirmations that are always remembered in (default is approvals.json next to the config file)"`
+	DisableRedaction         bool     `usage:"Don't replace credential values and sensitive input with a placeholder in events, logs and saved chat state (for local debugging only)"`
+	Breakpoint               []string `usage:"Debug the run, but only pause for the calls of the tools with these names instead of at every step (ex: --breakpoint search,summarize)"`
+	ProjectConfig            string   `usage:"The project config file to use, or none to not use one (default is the nearest gptscript.yaml in the current directory or its parents)"`
+
+	readData      []byte
+	redactor      *redact.Redactor
+	project       *config.ProjectConfig
+	optionSources map[string]string
+	envSources    map[string]string
 }
 
 func New() *cobra.Command {
@@ -85,7 +102,16 @@ func New() *cobra.Command {
 		root,
 		&Eval{gptscript: root},
 		&Credential{root: root},
+		&Approvals{root: root},
+		&Audit{},
+		&Config{root: root},
+		&Daemons{root: root},
+		&MCPServe{root: root},
 		&Parse{gptscript: root},
+		&Report{root: root},
+		&ReplayEvents{root: root},
+		&Test{gptscript: root},
+		&EvalDataset{gptscript: root},
 		&Fmt{},
 		&Getenv{},
 		&SDKServer{
@@ -131,25 +157,84 @@ func New() *cobra.Command {
 	return command
 }
 
+// credentialContexts returns the credential contexts to look for credentials in, in order.
+func (r *GPTScript) credentialContexts() []string {
+	if len(r.CredentialContext) == 0 {
+		return []string{"default"}
+	}
+	return r.CredentialContext
+}
+
+// credentialWriteContext returns the credential context that new credentials are stored in.
+func (r *GPTScript) credentialWriteContext() string {
+	if r.CredentialWriteContext != "" {
+		return r.CredentialWriteContext
+	}
+	return r.credentialContexts()[0]
+}
+
+func (r *GPTScript) approvalsStore() (*approvals.Store, error) {
+	if r.ApprovalsFile != "" {
+		return approvals.NewStore(r.ApprovalsFile), nil
+	}
+	file, err := approvals.DefaultFile(r.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	return approvals.NewStore(file), nil
+}
+
 func (r *GPTScript) NewGPTScriptOpts() (gptscript.Options, error) {
 	opts := gptscript.Options{
 		Cache:   cache.Options(r.CacheOptions),
 		OpenAI:  openai.Options(r.OpenAIOptions),
 		Monitor: monitor.Options(r.DisplayOptions),
 		Runner: runner.Options{
-			CredentialOverrides: r.CredentialOverride,
+			CredentialOverrides: r.projectCredentialOverrides(),
 			Sequential:          r.ForceSequential,
 		},
-		Quiet:                r.Quiet,
-		Env:                  os.Environ(),
-		CredentialContext:    r.CredentialContext,
-		Workspace:            r.Workspace,
-		DisablePromptServer:  r.UI,
-		DefaultModelProvider: r.DefaultModelProvider,
+		Quiet:                  r.Quiet,
+		Env:                    os.Environ(),
+		CredentialContexts:     r.credentialContexts(),
+		CredentialWriteContext: r.CredentialWriteContext,
+		Workspace:              r.Workspace,
+		DisablePromptServer:    r.UI,
+		DisableRedaction:       r.DisableRedaction,
+		DefaultModelProvider:   r.DefaultModelProvider,
+		AuditLog:               r.AuditLog,
+	}
+	opts.Runner.Redactor = r.redactor
+
+	// With a policy, the user is only asked about the calls that the policy says to ask about.
+	if r.Confirm || r.Policy != "" || (r.project != nil && len(r.project.Policies) > 0) {
+		store, err := r.approvalsStore()
+		if err != nil {
+			return gptscript.Options{}, err
+		}
+		opts.Runner.Authorizer = auth.NewAuthorizer(approvals.NewSession(store))
 	}
 
-	if r.Confirm {
-		opts.Runner.Authorizer = auth.Authorize
+	if r.DebugStep || len(r.Breakpoint) > 0 {
+		opts.Runner.Debugger = debugger.New(r.Breakpoint...).Step
+	}
+
+	if r.Policy != "" {
+		p, err := policy.Load(r.Policy)
+		if err != nil {
+			return gptscript.Options{}, err
+		}
+		opts.Runner.Policies = append(opts.Runner.Policies, p)
+	}
+
+	if r.project != nil {
+		for _, file := range r.project.Policies {
+			p, err := policy.Load(file)
+			if err != nil {
+				return gptscript.Options{}, err
+			}
+			opts.Runner.Policies = append(opts.Runner.Policies, p)
+		}
+		opts.ModelAliases = r.project.ModelAliases
 	}
 
 	if r.Ports != "" {
@@ -199,6 +284,15 @@ func (r *GPTScript) Customize(cmd *cobra.Command) {
 	cmd.ValidArgsFunction = func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
 		return nil, cobra.ShellCompDirectiveDefault
 	}
+
+	// The project config sets the flags that aren't set, so it is read before the flags are read from the environment.
+	pre := cmd.PersistentPreRunE
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := r.loadProjectConfig(cmd); err != nil {
+			return err
+		}
+		return pre(cmd, args)
+	}
 }
 
 func (r *GPTScript) listTools(ctx context.Context, gptScript *gptscript.GPTScript, prg types.Program) error {
@@ -258,6 +352,11 @@ func (r *GPTScript) PersistentPre(*cobra.Command, []string) error {
 		}
 	}
 
+	if !r.DisableRedaction {
+		r.redactor = redact.New()
+		mvl.WrapOutput(r.redactor.Writer)
+	}
+
 	if r.Color != nil {
 		color.NoColor = !*r.Color
 	}
@@ -278,7 +377,7 @@ func (r *GPTScript) listModels(ctx context.Context, gptScript *gptscript.GPTScri
 	return nil
 }
 
-func (r *GPTScript) readProgram(ctx context.Context, runner *gptscript.GPTScript, args []string) (prg types.Program, err error) {
+func (r *GPTScript) readProgram(ctx context.Context, runner *gptscript.GPTScript, args, env []string) (prg types.Program, err error) {
 	if len(args) == 0 {
 		return
 	}
@@ -299,11 +398,13 @@ func (r *GPTScript) readProgram(ctx context.Context, runner *gptscript.GPTScript
 		}
 		return loader.ProgramFromSource(ctx, string(data), r.SubTool, loader.Options{
 			Cache: runner.Cache,
+			Env:   env,
 		})
 	}
 
 	return loader.Program(ctx, args[0], r.SubTool, loader.Options{
 		Cache: runner.Cache,
+		Env:   env,
 	})
 }
 
@@ -412,7 +513,7 @@ func (r *GPTScript) Run(cmd *cobra.Command, args []string) (retErr error) {
 		return r.listModels(ctx, gptScript, args)
 	}
 
-	prg, err := r.readProgram(ctx, gptScript, args)
+	prg, err := r.readProgram(ctx, gptScript, args, gptOpt.Env)
 	if err != nil {
 		return err
 	}
@@ -468,6 +569,8 @@ func (r *GPTScript) Run(cmd *cobra.Command, args []string) (retErr error) {
 		if err != nil {
 			return err
 		}
+		// The state is written out, so it must not contain the secrets that it needs to continue in this process
+		resp.State = redact.JSON(r.redactor, resp.State)
 		data, err := json.Marshal(resp)
 		if err != nil {
 			return err
@@ -476,7 +579,7 @@ func (r *GPTScript) Run(cmd *cobra.Command, args []string) (retErr error) {
 	}
 
 	if prg.IsChat() || r.ForceChat {
-		if !r.DisableTUI && !r.Debug && !r.DebugMessages && !r.NoTrunc {
+		if !r.DisableTUI && !r.Debug && !r.DebugMessages && !r.NoTrunc && !r.DebugStep && len(r.Breakpoint) == 0 {
 			// Don't use cmd.Context() because then sigint will cancel everything
 			return tui.Run(context.Background(), args[0], tui.RunOptions{
 				ClientOpts: &gptscript2.GlobalOptions{
@@ -487,7 +590,7 @@ func (r *GPTScript) Run(cmd *cobra.Command, args []string) (retErr error) {
 				},
 				TrustedRepoPrefixes: []string{"github.com/gptscript-ai"},
 				DisableCache:        r.DisableCache,
-				CredentialOverrides: r.CredentialOverride,
+				CredentialOverrides: r.projectCredentialOverrides(),
 				Input:               toolInput,
 				CacheDir:            r.CacheDir,
 				SubTool:             r.SubTool,
@@ -497,7 +600,7 @@ func (r *GPTScript) Run(cmd *cobra.Command, args []string) (retErr error) {
 			})
 		}
 		return chat.Start(cmd.Context(), chatState, gptScript, func() (types.Program, error) {
-			return r.readProgram(ctx, gptScript, args)
+			return r.readProgram(ctx, gptScript, args, gptOpt.Env)
 		}, gptOpt.Env, toolInput, r.SaveChatStateFile)
 	}
 
@@ -0,0 +1,340 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/gptscript-ai/gptscript/pkg/auth"
+	"github.com/gptscript-ai/gptscript/pkg/engine"
+	"github.com/gptscript-ai/gptscript/pkg/gptscript"
+	"github.com/gptscript-ai/gptscript/pkg/loader"
+	"github.com/gptscript-ai/gptscript/pkg/mcp"
+	"github.com/gptscript-ai/gptscript/pkg/runner"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/gptscript-ai/gptscript/pkg/version"
+	"github.com/spf13/cobra"
+)
+
+type MCPServe struct {
+	root *GPTScript
+}
+
+func (m *MCPServe) Customize(cmd *cobra.Command) {
+	cmd.Use = "mcp-serve PROGRAM_FILE"
+	cmd.Short = "Serve the tools of a program to MCP clients over stdio"
+	cmd.Long = `Serve the tools of a program to MCP clients over stdio.
+
+The tools that the first tool in the file can call are advertised as MCP tools. If it can not call any tools, the
+first tool itself is advertised. Prompts for input and confirmations (with --confirm or policies, including the
+policies of the project config) are sent to the client as elicitation requests, and fail if the client does not
+support them. Runs can not be debugged with --debug-step or --breakpoint.`
+	cmd.Args = cobra.ExactArgs(1)
+}
+
+func (m *MCPServe) Run(cmd *cobra.Command, args []string) error {
+	// Stdin and stdout are used to talk to the client, so nothing can prompt on or print to the terminal.
+	if m.root.DebugStep || len(m.root.Breakpoint) > 0 {
+		return fmt.Errorf("mcp-serve can not debug runs, the debugger uses the terminal that talks to the MCP client")
+	}
+
+	opts, err := m.root.NewGPTScriptOpts()
+	if err != nil {
+		return err
+	}
+
+	opts.DisablePromptServer = true
+	opts.Quiet = &[]bool{true}[0]
+	// Confirmations and policies, from the flags or the project config, ask the client instead of the terminal.
+	if opts.Runner.Authorizer != nil {
+		opts.Runner.Authorizer = mcpAuthorize
+	}
+
+	ctx := cmd.Context()
+
+	gptScript, err := gptscript.New(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer gptScript.Close(true)
+
+	prg, err := loader.Program(ctx, args[0], "", loader.Options{
+		Cache: gptScript.Cache,
+		Env:   opts.Env,
+	})
+	if err != nil {
+		return err
+	}
+
+	prompts, err := newMCPPromptServer(ctx)
+	if err != nil {
+		return err
+	}
+
+	handler, err := newMCPToolHandler(gptScript, prg, opts.Env, prompts)
+	if err != nil {
+		return err
+	}
+
+	server := &mcp.Server{
+		Info: mcp.Implementation{
+			Name:    types.FirstSet(prg.ToolSet[prg.EntryToolID].Name, version.ProgramName),
+			Version: version.Get().String(),
+		},
+		Instructions: prg.ToolSet[prg.EntryToolID].Description,
+		Handler:      handler,
+	}
+
+	return server.ServeStdio(ctx, os.Stdin, os.Stdout)
+}
+
+type mcpToolHandler struct {
+	gptScript *gptscript.GPTScript
+	prg       types.Program
+	env       []string
+	prompts   *mcpPromptServer
+	tools     []mcp.Tool
+	toolIDs   map[string]string
+}
+
+func newMCPToolHandler(gptScript *gptscript.GPTScript, prg types.Program, env []string, prompts *mcpPromptServer) (*mcpToolHandler, error) {
+	entry := prg.ToolSet[prg.EntryToolID]
+	completionTools, err := entry.GetChatCompletionTools(prg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(completionTools) == 0 {
+		completionTools = []types.ChatCompletionTool{
+			{
+				Function: types.CompletionFunctionDefinition{
+					ToolID:      entry.ID,
+					Name:        types.FirstSet(entry.Name, version.ProgramName),
+					Description: entry.Description,
+					Parameters:  entry.Arguments,
+				},
+			},
+		}
+	}
+
+	h := &mcpToolHandler{
+		gptScript: gptScript,
+		prg:       prg,
+		env:       env,
+		prompts:   prompts,
+		toolIDs:   map[string]string{},
+	}
+
+	for _, tool := range completionTools {
+		schema := json.RawMessage(`{"type":"object","properties":{}}`)
+		if tool.Function.Parameters != nil {
+			schema, err = json.Marshal(tool.Function.Parameters)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert parameters of tool %s: %w", tool.Function.Name, err)
+			}
+		}
+		h.tools = append(h.tools, mcp.Tool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: schema,
+		})
+		h.toolIDs[tool.Function.Name] = tool.Function.ToolID
+	}
+
+	sort.Slice(h.tools, func(i, j int) bool {
+		return h.tools[i].Name < h.tools[j].Name
+	})
+
+	return h, nil
+}
+
+func (h *mcpToolHandler) ListTools(context.Context) ([]mcp.Tool, error) {
+	return h.tools, nil
+}
+
+func (h *mcpToolHandler) CallTool(ctx context.Context, name string, arguments json.RawMessage) (mcp.CallToolResult, error) {
+	toolID, ok := h.toolIDs[name]
+	if !ok {
+		return mcp.CallToolResult{}, fmt.Errorf("unknown tool %s", name)
+	}
+
+	prg := h.prg
+	prg.EntryToolID = toolID
+
+	input := string(arguments)
+	if input == "" {
+		input = "{}"
+	}
+
+	env, done := h.prompts.register(ctx)
+	defer done()
+
+	out, err := h.gptScript.Run(ctx, prg, append(h.env, env...), input)
+	if err != nil {
+		return mcp.ErrorResult(err.Error()), nil
+	}
+	return mcp.TextResult(out), nil
+}
+
+// mcpAuthorize asks the client to confirm the call. Calls are denied if the client can't be asked.
+func mcpAuthorize(ctx engine.Context, input string) (runner.AuthorizerResponse, error) {
+	if auth.IsSafe(ctx) {
+		return runner.AuthorizerResponse{
+			Accept: true,
+		}, nil
+	}
+
+	result, err := mcp.Elicit(ctx.Ctx, mcp.ElicitRequest{
+		Message: auth.ConfirmMessage(ctx, input),
+	})
+	if err != nil {
+		return runner.AuthorizerResponse{
+			Message: fmt.Sprintf("Request denied, unable to confirm the call with the MCP client: %v", err),
+		}, nil
+	}
+
+	return runner.AuthorizerResponse{
+		Accept:  result.Action == mcp.ElicitAccept,
+		Message: "Request denied, blocking execution.",
+	}, nil
+}
+
+// mcpPromptServer receives the requests from sys.prompt and sends them to the client that made the tool call.
+type mcpPromptServer struct {
+	url   string
+	token string
+	lock  sync.Mutex
+	calls map[string]context.Context
+}
+
+func newMCPPromptServer(ctx context.Context) (*mcpPromptServer, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	p := &mcpPromptServer{
+		url:   "http://" + l.Addr().String(),
+		token: uuid.NewString(),
+		calls: map[string]context.Context{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /prompt/{id}", p.prompt)
+	s := http.Server{
+		Handler: mux,
+	}
+
+	context.AfterFunc(ctx, func() {
+		_ = s.Shutdown(context.Background())
+	})
+
+	go func() {
+		if err := s.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("failed to run prompt server: %v", err)
+		}
+	}()
+
+	return p, nil
+}
+
+// register returns the environment variables that send the prompts of a call to the client of ctx.
+func (p *mcpPromptServer) register(ctx context.Context) ([]string, func()) {
+	id := uuid.NewString()
+
+	p.lock.Lock()
+	p.calls[id] = ctx
+	p.lock.Unlock()
+
+	return []string{
+		fmt.Sprintf("%s=%s/prompt/%s", types.PromptURLEnvVar, p.url, id),
+		fmt.Sprintf("%s=%s", types.PromptTokenEnvVar, p.token),
+	}, func() {
+		p.lock.Lock()
+		delete(p.calls, id)
+		p.lock.Unlock()
+	}
+}
+
+func (p *mcpPromptServer) prompt(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+p.token {
+		http.Error(w, "Unauthorized (invalid token)", http.StatusUnauthorized)
+		return
+	}
+
+	p.lock.Lock()
+	ctx, ok := p.calls[r.PathValue("id")]
+	p.lock.Unlock()
+	if !ok {
+		http.Error(w, "no tool call found for prompt", http.StatusNotFound)
+		return
+	}
+
+	var req types.Prompt
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := elicitPrompt(ctx, req)
+	if err != nil {
+		log.Errorf("failed to prompt MCP client: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(resp))
+}
+
+func elicitPrompt(ctx context.Context, req types.Prompt) (string, error) {
+	// MCP clients must not be asked for sensitive information with elicitation.
+	if req.Sensitive {
+		return "", fmt.Errorf("prompts for sensitive information can not be sent to MCP clients")
+	}
+
+	request := mcp.ElicitRequest{
+		Message: req.Message,
+		RequestedSchema: mcp.ElicitSchema{
+			Properties: map[string]mcp.ElicitProperty{},
+			Required:   req.Fields,
+		},
+	}
+	if request.Message == "" {
+		request.Message = "Please provide the following information"
+	}
+	for _, field := range req.Fields {
+		request.RequestedSchema.Properties[field] = mcp.ElicitProperty{
+			Type:  "string",
+			Title: field,
+		}
+	}
+
+	result, err := mcp.Elicit(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	if result.Action != mcp.ElicitAccept {
+		return "", fmt.Errorf("the prompt was not accepted by the user (%s)", result.Action)
+	}
+
+	if len(req.Fields) == 0 {
+		return "", nil
+	}
+
+	values := make(map[string]string, len(req.Fields))
+	for _, field := range req.Fields {
+		if v, ok := result.Content[field]; ok {
+			values[field] = fmt.Sprint(v)
+		}
+	}
+	data, err := json.Marshal(values)
+	return string(data), err
+}
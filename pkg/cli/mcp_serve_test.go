@@ -0,0 +1,16 @@
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPServeWithDebugger(t *testing.T) {
+	for _, flag := range []string{"--debug-step", "--breakpoint=search"} {
+		cmd := New()
+		cmd.SetArgs([]string{"mcp-serve", flag, filepath.Join(t.TempDir(), "tool.gpt")})
+		require.ErrorContains(t, cmd.Execute(), "mcp-serve can not debug runs")
+	}
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/gptscript-ai/gptscript/pkg/monitor"
+	"github.com/spf13/cobra"
+)
+
+type ReplayEvents struct {
+	Speed  int    `usage:"Replay the events this many times faster than they were recorded, 1 for the recorded speed (default is to not wait between events)" local:"true"`
+	CallID string `usage:"Only replay the events of the call with this ID and the calls that it made" local:"true" name:"call-id"`
+
+	root *GPTScript
+}
+
+func (r *ReplayEvents) Customize(cmd *cobra.Command) {
+	cmd.Use = "replay-events <events-file>"
+	cmd.SilenceUsage = true
+	cmd.Short = "Show the events written with --events-stream-to the way they were shown when the script was run"
+	cmd.Args = cobra.ExactArgs(1)
+}
+
+func (r *ReplayEvents) Run(cmd *cobra.Command, args []string) error {
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	console := monitor.NewConsole(monitor.Options(r.root.DisplayOptions), monitor.Options{DebugMessages: *r.root.Quiet})
+	return monitor.Replay(cmd.Context(), f, console, monitor.ReplayOptions{
+		Speed:  float64(r.Speed),
+		CallID: r.CallID,
+	})
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gptscript-ai/gptscript/pkg/report"
+	"github.com/spf13/cobra"
+)
+
+type Report struct {
+	Format string `usage:"The format of the report, html or markdown (default is markdown if --output ends in .md, otherwise html)" local:"true"`
+	Title  string `usage:"The title of the report (default is the name of the events file)" local:"true"`
+
+	root *GPTScript
+}
+
+func (r *Report) Customize(cmd *cobra.Command) {
+	cmd.Use = "report <events-file>"
+	cmd.SilenceUsage = true
+	cmd.Short = "Render the events written with --events-stream-to as an HTML or Markdown report of the runs"
+	cmd.Args = cobra.ExactArgs(1)
+}
+
+func (r *Report) Run(_ *cobra.Command, args []string) error {
+	format := report.FormatHTML
+	if r.Format != "" {
+		var err error
+		if format, err = report.ParseFormat(r.Format); err != nil {
+			return err
+		}
+	} else if ext := strings.ToLower(filepath.Ext(r.root.Output)); ext == ".md" || ext == ".markdown" {
+		format = report.FormatMarkdown
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rep, err := report.Read(f)
+	if err != nil {
+		return err
+	}
+
+	title := r.Title
+	if title == "" {
+		title = "Report of " + filepath.Base(args[0])
+	}
+
+	var buf bytes.Buffer
+	if err := rep.Render(&buf, format, title); err != nil {
+		return err
+	}
+
+	if r.root.Output == "" || r.root.Output == "-" {
+		_, err = os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return os.WriteFile(r.root.Output, buf.Bytes(), 0644)
+}
@@ -26,6 +26,17 @@ func (c *SDKServer) Run(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
+	// Confirmations are sent to the SDK client, which asks for them with the confirm option of a run, instead of
+	// being asked on the terminal because of a policy.
+	if !c.Confirm {
+		opts.Runner.Authorizer = nil
+	}
+
+	store, err := c.approvalsStore()
+	if err != nil {
+		return err
+	}
+
 	// Don't use cmd.Context() as we don't want to die on ctrl+c
 	ctx := context.Background()
 	if term.IsTerminal(int(os.Stdin.Fd())) {
@@ -37,5 +48,6 @@ func (c *SDKServer) Run(cmd *cobra.Command, _ []string) error {
 		Options:       opts,
 		ListenAddress: c.ListenAddress,
 		Debug:         c.Debug,
+		Approvals:     store,
 	})
 }
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+
+	openai "github.com/gptscript-ai/chat-completion-client"
+	"github.com/gptscript-ai/gptscript/pkg/gptscript"
+	"github.com/gptscript-ai/gptscript/pkg/tests/judge"
+	"github.com/gptscript-ai/gptscript/pkg/testspec"
+	"github.com/spf13/cobra"
+)
+
+type Test struct {
+	JUnit  string `usage:"Write the results as JUnit XML to this file" local:"true" name:"junit"`
+	Filter string `usage:"Only run the tests with names that match this regular expression" local:"true"`
+
+	gptscript *GPTScript
+}
+
+func (t *Test) Customize(cmd *cobra.Command) {
+	cmd.Use = "test [spec-file-or-dir...]"
+	cmd.SilenceUsage = true
+	cmd.Short = "Run the tests in the test spec files (*.test.yaml) next to scripts"
+}
+
+func (t *Test) Run(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+
+	var filter *regexp.Regexp
+	if t.Filter != "" {
+		var err error
+		if filter, err = regexp.Compile(t.Filter); err != nil {
+			return fmt.Errorf("invalid --filter: %w", err)
+		}
+	}
+
+	files, err := testspec.Find(args...)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no test spec files (*.test.yaml) found")
+	}
+
+	var specs []*testspec.Spec
+	for _, file := range files {
+		spec, err := testspec.Load(file)
+		if err != nil {
+			return err
+		}
+		if filter != nil {
+			tests := spec.Tests[:0]
+			for _, test := range spec.Tests {
+				if filter.MatchString(test.Name) {
+					tests = append(tests, test)
+				}
+			}
+			spec.Tests = tests
+		}
+		specs = append(specs, spec)
+	}
+
+	opts, err := t.gptscript.NewGPTScriptOpts()
+	if err != nil {
+		return err
+	}
+
+	var testJudge testspec.Judge
+	if j, err := newJudge(opts); err != nil {
+		return err
+	} else if j != nil {
+		testJudge = j
+	}
+
+	runner, err := testspec.NewRunner(cmd.Context(), testJudge, opts)
+	if err != nil {
+		return err
+	}
+	defer runner.Close()
+
+	var (
+		results []testspec.Result
+		failed  int
+	)
+	for _, spec := range specs {
+		for _, result := range runner.Run(cmd.Context(), spec) {
+			status := "PASS"
+			if !result.Passed() {
+				status = "FAIL"
+				failed++
+			}
+			fmt.Printf("%s  %s: %s (%.2fs)\n", status, result.Spec, result.Name, result.Duration.Seconds())
+			for _, failure := range result.Failures {
+				fmt.Printf("      %s\n", failure)
+			}
+			results = append(results, result)
+		}
+	}
+	fmt.Printf("\n%d passed, %d failed\n", len(results)-failed, failed)
+
+	if t.JUnit != "" {
+		var buf bytes.Buffer
+		if err := testspec.WriteJUnit(&buf, results); err != nil {
+			return err
+		}
+		if err := os.WriteFile(t.JUnit, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tests failed", failed, len(results))
+	}
+	return nil
+}
+
+// newJudge returns the judge of outputs. The judge uses OpenAI directly, so it is nil without an OpenAI API key.
+func newJudge(opts gptscript.Options) (*judge.Judge[string], error) {
+	if opts.OpenAI.APIKey == "" {
+		return nil, nil
+	}
+	cfg := openai.DefaultConfig(opts.OpenAI.APIKey)
+	if opts.OpenAI.BaseURL != "" {
+		cfg.BaseURL = opts.OpenAI.BaseURL
+	}
+	return judge.New[string](openai.NewClientWithConfig(cfg))
+}
@@ -3,7 +3,6 @@ package config
 import (
 	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"os"
 	"runtime"
@@ -16,9 +15,9 @@ import (
 )
 
 var (
-	darwinHelpers  = []string{"osxkeychain", "file"}
-	windowsHelpers = []string{"wincred", "file"}
-	linuxHelpers   = []string{"secretservice", "pass", "file"}
+	darwinHelpers  = []string{"osxkeychain", "file", "encrypted"}
+	windowsHelpers = []string{"wincred", "file", "encrypted"}
+	linuxHelpers   = []string{"secretservice", "pass", "file", "encrypted"}
 )
 
 const GPTScriptHelperPrefix = "gptscript-credential-"
@@ -56,6 +55,8 @@ type CLIConfig struct {
 	CredentialsStore string                `json:"credsStore,omitempty"`
 	GatewayURL       string                `json:"gatewayURL,omitempty"`
 	Integrations     map[string]string     `json:"integrations,omitempty"`
+	// AuditLog is the file that the audit log is written to, if the audit log isn't set when running a tool.
+	AuditLog string `json:"auditLog,omitempty"`
 
 	auths     map[string]types.AuthConfig
 	authsLock *sync.Mutex
@@ -146,21 +147,8 @@ func ReadCLIConfig(gptscriptConfigFile string) (*CLIConfig, error) {
 		}
 	}
 
-	if !isValidCredentialHelper(result.CredentialsStore) {
-		errMsg := fmt.Sprintf("invalid credential store '%s'", result.CredentialsStore)
-		switch runtime.GOOS {
-		case "darwin":
-			errMsg += " (use 'osxkeychain' or 'file')"
-		case "windows":
-			errMsg += " (use 'wincred' or 'file')"
-		case "linux":
-			errMsg += " (use 'secretservice', 'pass', or 'file')"
-		default:
-			errMsg += " (use 'file')"
-		}
-		errMsg += fmt.Sprintf("\nPlease edit your config file at %s to fix this.", result.location)
-
-		return nil, errors.New(errMsg)
+	if err := ValidateCredentialHelper(result.CredentialsStore); err != nil {
+		return nil, fmt.Errorf("%w\nPlease edit your config file at %s to fix this.", err, result.location)
 	}
 
 	return result, nil
@@ -178,17 +166,24 @@ func (c *CLIConfig) setDefaultCredentialsStore() error {
 	return c.Save()
 }
 
-func isValidCredentialHelper(helper string) bool {
+// ValidateCredentialHelper returns an error if helper is not a credential store that can be used on this operating system.
+func ValidateCredentialHelper(helper string) error {
+	var helpers []string
 	switch runtime.GOOS {
 	case "darwin":
-		return slices.Contains(darwinHelpers, helper)
+		helpers = darwinHelpers
 	case "windows":
-		return slices.Contains(windowsHelpers, helper)
+		helpers = windowsHelpers
 	case "linux":
-		return slices.Contains(linuxHelpers, helper)
+		helpers = linuxHelpers
 	default:
-		return helper == "file"
+		helpers = []string{"file", "encrypted"}
+	}
+
+	if slices.Contains(helpers, helper) {
+		return nil
 	}
+	return fmt.Errorf("invalid credential store '%s' (use '%s')", helper, strings.Join(helpers, "', '"))
 }
 
 func readFile(path string) ([]byte, error) {
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ProjectFileName is the name of the project configuration file. It is looked for in the current directory and then
+// in each of its parents, like git looks for .git.
+const ProjectFileName = "gptscript.yaml"
+
+// ProjectConfig is the configuration of the project in a directory, which sets defaults for running gptscript in the
+// directory or any of its subdirectories.
+type ProjectConfig struct {
+	// Options are the defaults for the flags of gptscript, by the name of the flag, such as default-model.
+	Options map[string]any `json:"options,omitempty"`
+	// Env is added to the environment, unless a variable is already set.
+	Env map[string]string `json:"env,omitempty"`
+	// CredentialOverrides are used in addition to the ones given with --credential-override, which take precedence.
+	CredentialOverrides []string `json:"credentialOverrides,omitempty"`
+	// ModelAliases map names that are used as models by tools to the models to use instead.
+	ModelAliases map[string]string `json:"modelAliases,omitempty"`
+	// Policies are policy files that are used in addition to the one given with --policy. Relative paths are relative
+	// to the directory of the project configuration file.
+	Policies []string `json:"policies,omitempty"`
+
+	location string
+}
+
+// FindProjectConfig returns the project configuration file that applies to the directory, or "" if there is none.
+func FindProjectConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		file := filepath.Join(dir, ProjectFileName)
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			return file, nil
+		} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// ReadProjectConfig reads a project configuration file.
+func ReadProjectConfig(file string) (*ProjectConfig, error) {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project config %s: %w", file, err)
+	}
+
+	result := &ProjectConfig{
+		location: file,
+	}
+	if err := yaml.UnmarshalStrict(data, result); err != nil {
+		return nil, fmt.Errorf("invalid project config %s: %w", file, err)
+	}
+
+	for name, value := range result.Options {
+		if _, err := optionString(value); err != nil {
+			return nil, fmt.Errorf("invalid project config %s: option %s: %w", file, name, err)
+		}
+	}
+
+	for i, policy := range result.Policies {
+		result.Policies[i] = result.Path(policy)
+	}
+
+	return result, nil
+}
+
+// Location is the path of the file that the configuration was read from.
+func (p *ProjectConfig) Location() string {
+	return p.location
+}
+
+// Path returns the path relative to the directory of the project configuration file, if it is not absolute.
+func (p *ProjectConfig) Path(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(p.location), path)
+}
+
+// OptionNames returns the names of the options that are set, sorted.
+func (p *ProjectConfig) OptionNames() []string {
+	names := make([]string, 0, len(p.Options))
+	for name := range p.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Option returns the value of the option as it would be given on the command line. Lists are joined with commas.
+func (p *ProjectConfig) Option(name string) (string, bool) {
+	value, ok := p.Options[name]
+	if !ok {
+		return "", false
+	}
+	s, _ := optionString(value)
+	return s, true
+}
+
+func optionString(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := optionString(item)
+			if err != nil {
+				return "", err
+			}
+			if _, isList := item.([]any); isList {
+				return "", fmt.Errorf("lists of lists are not supported")
+			}
+			values = append(values, s)
+		}
+		return strings.Join(values, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", value)
+	}
+}
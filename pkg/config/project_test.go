@@ -0,0 +1,85 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "a", "b")
+	require.NoError(t, os.MkdirAll(sub, 0755))
+
+	file, err := FindProjectConfig(sub)
+	require.NoError(t, err)
+	if file != "" {
+		// A project config above the temp directory is found, so the rest of the test doesn't apply.
+		t.Skipf("found %s outside of the test directory", file)
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ProjectFileName), []byte("{}"), 0644))
+	file, err = FindProjectConfig(sub)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, ProjectFileName), file)
+
+	// The nearest file is used.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a", ProjectFileName), []byte("{}"), 0644))
+	file, err = FindProjectConfig(sub)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "a", ProjectFileName), file)
+}
+
+func TestReadProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, ProjectFileName)
+	require.NoError(t, os.WriteFile(file, []byte(`
+options:
+  default-model: fast
+  credential-context: [project, default]
+  confirm: true
+  ports: 11000
+env:
+  FOO: bar
+modelAliases:
+  fast: gpt-4o-mini
+policies:
+  - policy.yaml
+  - /etc/policy.yaml
+`), 0644))
+
+	p, err := ReadProjectConfig(file)
+	require.NoError(t, err)
+	require.Equal(t, file, p.Location())
+	require.Equal(t, []string{"confirm", "credential-context", "default-model", "ports"}, p.OptionNames())
+
+	for name, expected := range map[string]string{
+		"default-model":      "fast",
+		"credential-context": "project,default",
+		"confirm":            "true",
+		"ports":              "11000",
+	} {
+		value, ok := p.Option(name)
+		require.True(t, ok, name)
+		require.Equal(t, expected, value, name)
+	}
+	_, ok := p.Option("missing")
+	require.False(t, ok)
+
+	require.Equal(t, map[string]string{"FOO": "bar"}, p.Env)
+	require.Equal(t, map[string]string{"fast": "gpt-4o-mini"}, p.ModelAliases)
+	require.Equal(t, []string{filepath.Join(dir, "policy.yaml"), "/etc/policy.yaml"}, p.Policies)
+	require.Equal(t, filepath.Join(dir, "cache"), p.Path("cache"))
+
+	for name, data := range map[string]string{
+		"unknown field": "modelAlias:\n  fast: gpt-4o-mini\n",
+		"map option":    "options:\n  default-model:\n    name: fast\n",
+		"nested list":   "options:\n  credential-context: [[a]]\n",
+	} {
+		require.NoError(t, os.WriteFile(file, []byte(data), 0644))
+		_, err := ReadProjectConfig(file)
+		require.Error(t, err, name)
+	}
+}
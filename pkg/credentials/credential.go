@@ -36,6 +36,21 @@ func (c Credential) IsExpired() bool {
 	return time.Now().After(*c.ExpiresAt)
 }
 
+// refreshWindow is how long before it expires a credential with a refresh token is refreshed, so that it doesn't expire
+// while the tool that uses it is running.
+const refreshWindow = time.Minute
+
+// ShouldRefresh returns true if the credential has expired, or if it has a refresh token and is about to expire.
+func (c Credential) ShouldRefresh() bool {
+	if c.ExpiresAt == nil {
+		return false
+	}
+	if c.RefreshToken != "" {
+		return time.Now().Add(refreshWindow).After(*c.ExpiresAt)
+	}
+	return c.IsExpired()
+}
+
 func (c Credential) toDockerAuthConfig() (types.AuthConfig, error) {
 	for k, v := range c.Env {
 		c.Env[k] = strings.TrimSpace(v)
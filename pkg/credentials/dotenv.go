@@ -0,0 +1,116 @@
+package credentials
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	dotenvCredentialHeader = "# credential:"
+	dotenvContextHeader    = "# context:"
+)
+
+var envVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// MarshalDotenv writes the environment variables of the credentials in dotenv format. Each credential starts with
+// comments that have its name and context, so that UnmarshalDotenv can read them back. The expiration and refresh
+// token of the credentials are not included.
+func MarshalDotenv(creds []Credential) []byte {
+	buf := &bytes.Buffer{}
+	for i, cred := range creds {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(buf, "%s %s\n", dotenvCredentialHeader, cred.ToolName)
+		if cred.Context != "" {
+			fmt.Fprintf(buf, "%s %s\n", dotenvContextHeader, cred.Context)
+		}
+
+		keys := make([]string, 0, len(cred.Env))
+		for k := range cred.Env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(buf, "%s=%s\n", k, strconv.Quote(cred.Env[k]))
+		}
+	}
+	return buf.Bytes()
+}
+
+// UnmarshalDotenv reads credentials written by MarshalDotenv. Variables that come before the first credential comment
+// belong to a credential named defaultName, which is an error if defaultName is empty.
+func UnmarshalDotenv(data []byte, defaultName string) ([]Credential, error) {
+	var (
+		creds   []Credential
+		current *Credential
+		scanner = bufio.NewScanner(bytes.NewReader(data))
+		lineNum int
+	)
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		if name, ok := strings.CutPrefix(line, dotenvCredentialHeader); ok {
+			creds = append(creds, Credential{
+				ToolName: strings.TrimSpace(name),
+				Type:     CredentialTypeTool,
+				Env:      map[string]string{},
+			})
+			current = &creds[len(creds)-1]
+			continue
+		}
+		if credCtx, ok := strings.CutPrefix(line, dotenvContextHeader); ok && current != nil {
+			current.Context = strings.TrimSpace(credCtx)
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || !envVarName.MatchString(key) {
+			return nil, fmt.Errorf("invalid line %d, expected KEY=VALUE", lineNum)
+		}
+		value, err := unquoteDotenvValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s on line %d: %w", key, lineNum, err)
+		}
+
+		if current == nil {
+			if defaultName == "" {
+				return nil, fmt.Errorf("line %d is not part of a credential, add a %q comment before it", lineNum, dotenvCredentialHeader+" <name>")
+			}
+			creds = append(creds, Credential{
+				ToolName: defaultName,
+				Type:     CredentialTypeTool,
+				Env:      map[string]string{},
+			})
+			current = &creds[len(creds)-1]
+		}
+		current.Env[key] = value
+	}
+
+	return creds, scanner.Err()
+}
+
+func unquoteDotenvValue(value string) (string, error) {
+	switch {
+	case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+		return strconv.Unquote(value)
+	case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+		return value[1 : len(value)-1], nil
+	}
+	// Unquoted values end at an inline comment.
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value, nil
+}
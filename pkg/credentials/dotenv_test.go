@@ -0,0 +1,65 @@
+package credentials
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDotenv(t *testing.T) {
+	creds := []Credential{
+		{
+			Context:  "default",
+			ToolName: "github.com/example/cred",
+			Type:     CredentialTypeTool,
+			Env:      map[string]string{"TOKEN": "s3cr3t", "MULTILINE": "line1\nline2 \"quoted\""},
+		},
+		{
+			Context:  "other",
+			ToolName: "my-db",
+			Type:     CredentialTypeTool,
+			Env:      map[string]string{"DB_PASSWORD": "p#ss word"},
+		},
+	}
+
+	data := MarshalDotenv(creds)
+	require.Equal(t, `# credential: github.com/example/cred
+# context: default
+MULTILINE="line1\nline2 \"quoted\""
+TOKEN="s3cr3t"
+
+# credential: my-db
+# context: other
+DB_PASSWORD="p#ss word"
+`, string(data))
+
+	parsed, err := UnmarshalDotenv(data, "")
+	require.NoError(t, err)
+	require.Equal(t, creds, parsed)
+}
+
+func TestUnmarshalDotenvWithoutHeader(t *testing.T) {
+	data := []byte(`# A plain dotenv file
+export API_KEY=abc123 # the key
+SINGLE='it''s literal'
+EMPTY=
+`)
+
+	_, err := UnmarshalDotenv(data, "")
+	require.ErrorContains(t, err, "line 2 is not part of a credential")
+
+	creds, err := UnmarshalDotenv(data, "my-api")
+	require.NoError(t, err)
+	require.Equal(t, []Credential{{
+		ToolName: "my-api",
+		Type:     CredentialTypeTool,
+		Env: map[string]string{
+			"API_KEY": "abc123",
+			"SINGLE":  "it''s literal",
+			"EMPTY":   "",
+		},
+	}}, creds)
+
+	_, err = UnmarshalDotenv([]byte("not a variable\n"), "my-api")
+	require.ErrorContains(t, err, "invalid line 1")
+}
@@ -0,0 +1,326 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/docker/cli/cli/config/types"
+	"github.com/gptscript-ai/gptscript/pkg/filelock"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	PassphraseEnvVar = "GPTSCRIPT_CREDENTIAL_PASSPHRASE"
+	KeyFileEnvVar    = "GPTSCRIPT_CREDENTIAL_KEY_FILE"
+
+	// EncryptedFileName is the name of the file, in the same directory as the CLI config, that the encrypted store uses.
+	EncryptedFileName = "credentials.enc"
+
+	encryptedFileVersion = 1
+)
+
+var (
+	// encryptedLock serializes the changes to encrypted files made by this process.
+	encryptedLock sync.Mutex
+	// derivedKeys caches the keys derived from a passphrase and salt, because deriving them is slow on purpose.
+	derivedKeys     = map[[sha256.Size]byte][]byte{}
+	derivedKeysLock sync.Mutex
+
+	errDecrypt = errors.New("failed to decrypt")
+)
+
+type encryptedFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// EncryptedFileStore is a credential store that keeps the credentials in a file encrypted with AES-GCM. The key is
+// derived with scrypt from the passphrase in GPTSCRIPT_CREDENTIAL_PASSPHRASE or from the contents of the file at
+// GPTSCRIPT_CREDENTIAL_KEY_FILE.
+type EncryptedFileStore struct {
+	path       string
+	passphrase []byte
+}
+
+func NewEncryptedFileStore(path string) (*EncryptedFileStore, error) {
+	passphrase, err := encryptionPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedFileStore{
+		path:       path,
+		passphrase: passphrase,
+	}, nil
+}
+
+func encryptionPassphrase() ([]byte, error) {
+	if passphrase := os.Getenv(PassphraseEnvVar); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+
+	if keyFile := os.Getenv(KeyFileEnvVar); keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read credential key file: %w", err)
+		}
+		data = []byte(strings.TrimSpace(string(data)))
+		if len(data) == 0 {
+			return nil, fmt.Errorf("credential key file %s is empty", keyFile)
+		}
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("encrypted credentials require %s or %s to be set", PassphraseEnvVar, KeyFileEnvVar)
+}
+
+func deriveKey(passphrase, salt []byte) ([]byte, error) {
+	cacheKey := sha256.Sum256(append(append([]byte{}, salt...), passphrase...))
+
+	derivedKeysLock.Lock()
+	defer derivedKeysLock.Unlock()
+
+	if key, ok := derivedKeys[cacheKey]; ok {
+		return key, nil
+	}
+
+	key, err := scrypt.Key(passphrase, salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	derivedKeys[cacheKey] = key
+	return key, nil
+}
+
+func newCipher(passphrase, salt []byte) (cipher.AEAD, error) {
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext. A new salt is generated if salt is nil.
+func seal(passphrase, salt, plaintext []byte) ([]byte, error) {
+	if salt == nil {
+		salt = make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+	}
+
+	gcm, err := newCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(encryptedFile{
+		Version: encryptedFileVersion,
+		Salt:    salt,
+		Nonce:   nonce,
+		Data:    gcm.Seal(nil, nonce, plaintext, nil),
+	})
+}
+
+// open decrypts data that was encrypted by seal, and returns the plaintext and the salt.
+func open(passphrase, data []byte) ([]byte, []byte, error) {
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse encrypted data: %w", err)
+	}
+	if file.Version != encryptedFileVersion {
+		return nil, nil, fmt.Errorf("unsupported version %d of encrypted data", file.Version)
+	}
+
+	gcm, err := newCipher(passphrase, file.Salt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, nil, errDecrypt
+	}
+	return plaintext, file.Salt, nil
+}
+
+// IsEncrypted returns true if data looks like it was encrypted by Encrypt or by the encrypted credential store.
+func IsEncrypted(data []byte) bool {
+	var file encryptedFile
+	return json.Unmarshal(data, &file) == nil && file.Version > 0 && len(file.Salt) > 0 && len(file.Nonce) > 0
+}
+
+// Encrypt encrypts data with the passphrase from GPTSCRIPT_CREDENTIAL_PASSPHRASE or GPTSCRIPT_CREDENTIAL_KEY_FILE, in
+// the same format as the encrypted credential store.
+func Encrypt(plaintext []byte) ([]byte, error) {
+	passphrase, err := encryptionPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	return seal(passphrase, nil, plaintext)
+}
+
+// Decrypt decrypts data that was encrypted by Encrypt.
+func Decrypt(data []byte) ([]byte, error) {
+	passphrase, err := encryptionPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, _, err := open(passphrase, data)
+	if errors.Is(err, errDecrypt) {
+		return nil, fmt.Errorf("failed to decrypt, check that %s or %s is correct", PassphraseEnvVar, KeyFileEnvVar)
+	}
+	return plaintext, err
+}
+
+func (e *EncryptedFileStore) read() (map[string]types.AuthConfig, []byte, error) {
+	data, err := os.ReadFile(e.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]types.AuthConfig{}, nil, nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to read encrypted credentials: %w", err)
+	}
+
+	plaintext, salt, err := open(e.passphrase, data)
+	if errors.Is(err, errDecrypt) {
+		return nil, nil, fmt.Errorf("failed to decrypt credentials %s, check that %s or %s is correct", e.path, PassphraseEnvVar, KeyFileEnvVar)
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to read encrypted credentials %s: %w", e.path, err)
+	}
+
+	auths := map[string]types.AuthConfig{}
+	if err := json.Unmarshal(plaintext, &auths); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse decrypted credentials: %w", err)
+	}
+	return auths, salt, nil
+}
+
+func (e *EncryptedFileStore) write(auths map[string]types.AuthConfig, salt []byte) error {
+	plaintext, err := json.Marshal(auths)
+	if err != nil {
+		return err
+	}
+
+	data, err := seal(e.passphrase, salt, plaintext)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(e.path), 0700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that the credentials are never left half written.
+	tmp, err := os.CreateTemp(filepath.Dir(e.path), filepath.Base(e.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), e.path)
+}
+
+// lock locks the file for this process and for other processes while it is read and written, until the returned
+// function is called. Reading without the lock is safe, because the file is replaced in one step.
+func (e *EncryptedFileStore) lock() (func(), error) {
+	encryptedLock.Lock()
+	unlock, err := filelock.Lock(e.path)
+	if err != nil {
+		encryptedLock.Unlock()
+		return nil, fmt.Errorf("failed to lock encrypted credentials: %w", err)
+	}
+	return func() {
+		unlock()
+		encryptedLock.Unlock()
+	}, nil
+}
+
+func (e *EncryptedFileStore) Erase(serverAddress string) error {
+	unlock, err := e.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	auths, salt, err := e.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := auths[serverAddress]; !ok {
+		return nil
+	}
+	delete(auths, serverAddress)
+	return e.write(auths, salt)
+}
+
+func (e *EncryptedFileStore) Get(serverAddress string) (types.AuthConfig, error) {
+	encryptedLock.Lock()
+	defer encryptedLock.Unlock()
+
+	auths, _, err := e.read()
+	if err != nil {
+		return types.AuthConfig{}, err
+	}
+	auth, ok := auths[serverAddress]
+	if !ok {
+		return types.AuthConfig{ServerAddress: serverAddress}, nil
+	}
+	auth.ServerAddress = serverAddress
+	return auth, nil
+}
+
+func (e *EncryptedFileStore) GetAll() (map[string]types.AuthConfig, error) {
+	encryptedLock.Lock()
+	defer encryptedLock.Unlock()
+
+	auths, _, err := e.read()
+	if err != nil {
+		return nil, err
+	}
+	for serverAddress, auth := range auths {
+		auth.ServerAddress = serverAddress
+		auths[serverAddress] = auth
+	}
+	return auths, nil
+}
+
+func (e *EncryptedFileStore) Store(authConfig types.AuthConfig) error {
+	unlock, err := e.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	auths, salt, err := e.read()
+	if err != nil {
+		return err
+	}
+	auths[authConfig.ServerAddress] = authConfig
+	return e.write(auths, salt)
+}
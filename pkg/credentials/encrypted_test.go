@@ -0,0 +1,122 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/cli/cli/config/types"
+	"github.com/gptscript-ai/gptscript/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedFileStore(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GPTSCRIPT_CONFIG_FILE", filepath.Join(dir, "config.json"))
+	t.Setenv(PassphraseEnvVar, "correct horse battery staple")
+
+	cfg, err := config.ReadCLIConfig("")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	newStore := func(credCtx string) CredentialStore {
+		store, err := NewStoreWithHelper(cfg, nil, "encrypted", []string{credCtx}, "", dir)
+		require.NoError(t, err)
+		return store
+	}
+
+	require.NoError(t, newStore("default").Add(ctx, Credential{
+		ToolName: "github.com/example/cred",
+		Type:     CredentialTypeTool,
+		Env:      map[string]string{"TOKEN": "s3cr3t"},
+	}))
+	require.NoError(t, newStore("other").Add(ctx, Credential{
+		ToolName: "github.com/example/cred",
+		Type:     CredentialTypeTool,
+		Env:      map[string]string{"TOKEN": "other-s3cr3t"},
+	}))
+
+	cred, ok, err := newStore("default").Get(ctx, "github.com/example/cred")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "s3cr3t", cred.Env["TOKEN"])
+	require.Equal(t, "default", cred.Context)
+
+	_, ok, err = newStore("default").Get(ctx, "github.com/example/missing")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	creds, err := newStore("*").List(ctx)
+	require.NoError(t, err)
+	require.Len(t, creds, 2)
+
+	creds, err = newStore("other").List(ctx)
+	require.NoError(t, err)
+	require.Len(t, creds, 1)
+	require.Equal(t, "other-s3cr3t", creds[0].Env["TOKEN"])
+
+	// The credentials are not stored in plain text, neither in the config nor in the encrypted file.
+	for _, file := range []string{"config.json", EncryptedFileName} {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if file == "config.json" && os.IsNotExist(err) {
+			continue
+		}
+		require.NoError(t, err)
+		require.False(t, strings.Contains(string(data), "s3cr3t"), file)
+	}
+
+	require.NoError(t, newStore("default").Remove(ctx, "github.com/example/cred"))
+	creds, err = newStore("*").List(ctx)
+	require.NoError(t, err)
+	require.Len(t, creds, 1)
+
+	t.Setenv(PassphraseEnvVar, "wrong")
+	_, _, err = newStore("other").Get(ctx, "github.com/example/cred")
+	require.ErrorContains(t, err, "failed to decrypt credentials")
+
+	t.Setenv(PassphraseEnvVar, "")
+	keyFile := filepath.Join(dir, "key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("correct horse battery staple\n"), 0600))
+	t.Setenv(KeyFileEnvVar, keyFile)
+	cred, ok, err = newStore("other").Get(ctx, "github.com/example/cred")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "other-s3cr3t", cred.Env["TOKEN"])
+
+	t.Setenv(KeyFileEnvVar, "")
+	_, _, err = newStore("other").Get(ctx, "github.com/example/cred")
+	require.ErrorContains(t, err, PassphraseEnvVar)
+}
+
+func TestEncryptedFileStoresOfOneFile(t *testing.T) {
+	t.Setenv(PassphraseEnvVar, "correct horse battery staple")
+	file := filepath.Join(t.TempDir(), EncryptedFileName)
+
+	// Each store is like the store of another process, so only the lock of the file keeps their credentials.
+	var wg sync.WaitGroup
+	for i := range 4 {
+		store, err := NewEncryptedFileStore(file)
+		require.NoError(t, err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 10 {
+				if err := store.Store(types.AuthConfig{ServerAddress: fmt.Sprintf("%d-%d", i, j), Password: "s3cr3t"}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	store, err := NewEncryptedFileStore(file)
+	require.NoError(t, err)
+	auths, err := store.GetAll()
+	require.NoError(t, err)
+	require.Len(t, auths, 40)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/docker/cli/cli/config/credentials"
@@ -23,18 +24,41 @@ type CredentialStore interface {
 }
 
 type Store struct {
-	credCtx        string
+	credCtxs       []string
+	writeCtx       string
+	helper         string
 	credBuilder    CredentialBuilder
 	credHelperDirs CredentialHelperDirs
 	cfg            *config.CLIConfig
 }
 
-func NewStore(cfg *config.CLIConfig, credentialBuilder CredentialBuilder, credCtx, cacheDir string) (CredentialStore, error) {
-	if err := validateCredentialCtx(credCtx); err != nil {
-		return nil, err
+// NewStore returns a store for the given credential contexts. Get searches the contexts in order, and Add and Remove
+// use the first one.
+func NewStore(cfg *config.CLIConfig, credentialBuilder CredentialBuilder, credCtxs []string, cacheDir string) (CredentialStore, error) {
+	return NewStoreWithHelper(cfg, credentialBuilder, cfg.CredentialsStore, credCtxs, "", cacheDir)
+}
+
+// NewStoreWithHelper returns a store that uses the given credential store, such as "file" or "encrypted", instead of the
+// one configured in cfg. Add and Remove use writeCtx, which must be one of credCtxs, or the first context if it is empty.
+func NewStoreWithHelper(cfg *config.CLIConfig, credentialBuilder CredentialBuilder, helper string, credCtxs []string, writeCtx, cacheDir string) (CredentialStore, error) {
+	if len(credCtxs) == 0 {
+		return nil, fmt.Errorf("at least one credential context is required")
+	}
+	for _, credCtx := range credCtxs {
+		if err := validateCredentialCtx(credCtx); err != nil {
+			return nil, err
+		}
+	}
+	if writeCtx == "" {
+		writeCtx = credCtxs[0]
+	} else if !slices.Contains(credCtxs, writeCtx) {
+		return nil, fmt.Errorf("credential context %q to write to is not one of the credential contexts %s", writeCtx, strings.Join(credCtxs, ", "))
 	}
+
 	return Store{
-		credCtx:        credCtx,
+		credCtxs:       credCtxs,
+		writeCtx:       writeCtx,
+		helper:         helper,
 		credBuilder:    credentialBuilder,
 		credHelperDirs: GetCredentialHelperDirs(cacheDir),
 		cfg:            cfg,
@@ -46,26 +70,35 @@ func (s Store) Get(ctx context.Context, toolName string) (*Credential, bool, err
 	if err != nil {
 		return nil, false, err
 	}
-	auth, err := store.Get(toolNameWithCtx(toolName, s.credCtx))
-	if err != nil {
-		return nil, false, err
-	} else if auth.Password == "" {
-		return nil, false, nil
-	}
 
-	if auth.ServerAddress == "" {
-		auth.ServerAddress = toolNameWithCtx(toolName, s.credCtx) // Not sure why we have to do this, but we do.
-	}
+	for _, credCtx := range s.credCtxs {
+		auth, err := store.Get(toolNameWithCtx(toolName, credCtx))
+		if err != nil {
+			return nil, false, err
+		} else if auth.Password == "" {
+			continue
+		}
 
-	cred, err := credentialFromDockerAuthConfig(auth)
-	if err != nil {
-		return nil, false, err
+		if auth.ServerAddress == "" {
+			auth.ServerAddress = toolNameWithCtx(toolName, credCtx) // Not sure why we have to do this, but we do.
+		}
+
+		cred, err := credentialFromDockerAuthConfig(auth)
+		if err != nil {
+			return nil, false, err
+		}
+		return &cred, true, nil
 	}
-	return &cred, true, nil
+
+	return nil, false, nil
 }
 
 func (s Store) Add(ctx context.Context, cred Credential) error {
-	cred.Context = s.credCtx
+	if s.writeCtx == "*" {
+		return fmt.Errorf("cannot add a credential to all contexts, use a single context")
+	}
+
+	cred.Context = s.writeCtx
 	store, err := s.getStore(ctx)
 	if err != nil {
 		return err
@@ -82,9 +115,11 @@ func (s Store) Remove(ctx context.Context, toolName string) error {
 	if err != nil {
 		return err
 	}
-	return store.Erase(toolNameWithCtx(toolName, s.credCtx))
+	return store.Erase(toolNameWithCtx(toolName, s.writeCtx))
 }
 
+// List returns the credentials that Get would return, so a credential is only listed from the first context that
+// has it. All credentials are returned if one of the contexts is "*".
 func (s Store) List(ctx context.Context) ([]Credential, error) {
 	store, err := s.getStore(ctx)
 	if err != nil {
@@ -95,6 +130,8 @@ func (s Store) List(ctx context.Context) ([]Credential, error) {
 		return nil, err
 	}
 
+	allContexts := slices.Contains(s.credCtxs, "*")
+	byTool := map[string]Credential{}
 	var creds []Credential
 	for serverAddress, authCfg := range list {
 		if authCfg.ServerAddress == "" {
@@ -105,21 +142,37 @@ func (s Store) List(ctx context.Context) ([]Credential, error) {
 		if err != nil {
 			return nil, err
 		}
-		if s.credCtx == "*" || c.Context == s.credCtx {
+
+		if allContexts {
 			creds = append(creds, c)
+			continue
 		}
+
+		i := slices.Index(s.credCtxs, c.Context)
+		if i < 0 {
+			continue
+		}
+		if existing, ok := byTool[c.ToolName]; ok && slices.Index(s.credCtxs, existing.Context) < i {
+			continue
+		}
+		byTool[c.ToolName] = c
 	}
 
+	for _, c := range byTool {
+		creds = append(creds, c)
+	}
 	return creds, nil
 }
 
 func (s *Store) getStore(ctx context.Context) (credentials.Store, error) {
-	return s.getStoreByHelper(ctx, config.GPTScriptHelperPrefix+s.cfg.CredentialsStore)
+	return s.getStoreByHelper(ctx, config.GPTScriptHelperPrefix+s.helper)
 }
 
 func (s *Store) getStoreByHelper(ctx context.Context, helper string) (credentials.Store, error) {
 	if helper == "" || helper == config.GPTScriptHelperPrefix+"file" {
 		return credentials.NewFileStore(s.cfg), nil
+	} else if helper == config.GPTScriptHelperPrefix+"encrypted" {
+		return NewEncryptedFileStore(filepath.Join(filepath.Dir(s.cfg.GetFilename()), EncryptedFileName))
 	}
 
 	// If the helper is referencing one of the credential helper programs, then reference the full path.
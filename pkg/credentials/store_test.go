@@ -0,0 +1,80 @@
+package credentials
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/gptscript-ai/gptscript/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayeredContexts(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GPTSCRIPT_CONFIG_FILE", filepath.Join(dir, "config.json"))
+
+	cfg, err := config.ReadCLIConfig("")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	newStore := func(writeCtx string, credCtxs ...string) CredentialStore {
+		store, err := NewStoreWithHelper(cfg, nil, "file", credCtxs, writeCtx, dir)
+		require.NoError(t, err)
+		return store
+	}
+
+	team := newStore("", "team")
+	require.NoError(t, team.Add(ctx, Credential{ToolName: "shared", Type: CredentialTypeTool, Env: map[string]string{"TOKEN": "team-shared"}}))
+	require.NoError(t, team.Add(ctx, Credential{ToolName: "api", Type: CredentialTypeTool, Env: map[string]string{"TOKEN": "team-api"}}))
+
+	layered := newStore("", "personal", "team")
+	require.NoError(t, layered.Add(ctx, Credential{ToolName: "api", Type: CredentialTypeTool, Env: map[string]string{"TOKEN": "personal-api"}}))
+
+	// The personal context overrides the team context, which is still used for the credentials it doesn't override.
+	cred, ok, err := layered.Get(ctx, "api")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "personal", cred.Context)
+	require.Equal(t, "personal-api", cred.Env["TOKEN"])
+
+	cred, ok, err = layered.Get(ctx, "shared")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "team", cred.Context)
+
+	_, ok, err = layered.Get(ctx, "missing")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	creds, err := layered.List(ctx)
+	require.NoError(t, err)
+	found := map[string]string{}
+	for _, c := range creds {
+		found[c.ToolName] = c.Context
+	}
+	require.Equal(t, map[string]string{"api": "personal", "shared": "team"}, found)
+
+	creds, err = newStore("", "*").List(ctx)
+	require.NoError(t, err)
+	require.Len(t, creds, 3)
+
+	// Writes go to the designated context.
+	require.NoError(t, newStore("team", "personal", "team").Add(ctx, Credential{ToolName: "new", Type: CredentialTypeTool, Env: map[string]string{"TOKEN": "new"}}))
+	cred, ok, err = team.Get(ctx, "new")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "team", cred.Context)
+
+	// Removing only removes the credential from the write context, which reveals the one in the next context.
+	require.NoError(t, layered.Remove(ctx, "api"))
+	cred, ok, err = layered.Get(ctx, "api")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "team-api", cred.Env["TOKEN"])
+
+	_, err = NewStoreWithHelper(cfg, nil, "file", []string{"personal"}, "team", dir)
+	require.ErrorContains(t, err, "is not one of the credential contexts")
+	_, err = NewStoreWithHelper(cfg, nil, "file", nil, "", dir)
+	require.Error(t, err)
+	require.Error(t, newStore("", "*").Add(ctx, Credential{ToolName: "all"}))
+}
@@ -0,0 +1,123 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusStarting   Status = "starting"
+	StatusRunning    Status = "running"
+	StatusRestarting Status = "restarting"
+	StatusExited     Status = "exited"
+	StatusFailed     Status = "failed"
+)
+
+// Info describes a daemon started by a gptscript process. It is written to the daemon directory so that
+// other processes (such as `gptscript daemons`) can list the daemons that are currently running.
+type Info struct {
+	ToolID        string    `json:"toolID,omitempty"`
+	ToolName      string    `json:"toolName,omitempty"`
+	Port          int64     `json:"port,omitempty"`
+	Socket        string    `json:"socket,omitempty"`
+	URL           string    `json:"url,omitempty"`
+	PID           int       `json:"pid,omitempty"`
+	SupervisorPID int       `json:"supervisorPID,omitempty"`
+	Status        Status    `json:"status,omitempty"`
+	Restart       string    `json:"restart,omitempty"`
+	Started       time.Time `json:"started,omitempty"`
+	Restarts      int       `json:"restarts"`
+	LogFile       string    `json:"logFile,omitempty"`
+	Error         string    `json:"error,omitempty"`
+}
+
+func (i Info) Uptime() time.Duration {
+	if i.Status != StatusRunning || i.Started.IsZero() {
+		return 0
+	}
+	return time.Since(i.Started)
+}
+
+// Dir returns the directory used for daemon logs and state files for the given cache directory.
+func Dir(cacheDir string) string {
+	return filepath.Join(cacheDir, "daemons")
+}
+
+func stateFile(dir string, info Info) string {
+	if info.Socket != "" {
+		return filepath.Join(dir, fmt.Sprintf("%d-%s.json", info.SupervisorPID, strings.TrimSuffix(filepath.Base(info.Socket), ".sock")))
+	}
+	return filepath.Join(dir, fmt.Sprintf("%d-%d.json", info.SupervisorPID, info.Port))
+}
+
+func WriteState(dir string, info Info) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(stateFile(dir, info), data, 0600)
+}
+
+func RemoveState(dir string, info Info) error {
+	if err := os.Remove(stateFile(dir, info)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List reads the state files in dir. State files left behind by gptscript processes that are no longer running
+// are removed.
+func List(dir string) (result []Info, _ error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		var info Info
+		if err := json.Unmarshal(data, &info); err != nil {
+			return nil, fmt.Errorf("failed to read daemon state %s: %w", entry.Name(), err)
+		}
+
+		if !processAlive(info.SupervisorPID) {
+			_ = RemoveState(dir, info)
+			continue
+		}
+
+		result = append(result, info)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ToolName == result[j].ToolName {
+			return result[i].Port < result[j].Port
+		}
+		return result[i].ToolName < result[j].ToolName
+	})
+
+	return result, nil
+}
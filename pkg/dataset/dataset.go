@@ -0,0 +1,94 @@
+// Package dataset evaluates a tool over the examples of a dataset, for the eval-dataset command.
+package dataset
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Example is an example of a dataset, which is a line of a JSONL file.
+type Example struct {
+	// ID identifies the example in reports. It is the line number of the example if it isn't set.
+	ID string `json:"id,omitempty"`
+	// Input is the input of the run. Inputs that aren't strings are converted to JSON, as the arguments of the tool.
+	Input any `json:"input,omitempty"`
+	// Expected is the expected output, which is compared to the output by the scorers.
+	Expected any `json:"expected,omitempty"`
+	// Criteria replace the criteria of the judge for the example.
+	Criteria string `json:"criteria,omitempty"`
+}
+
+// Load reads the examples of a JSONL file. Empty lines are skipped.
+func Load(file string) ([]Example, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+
+	var (
+		examples []Example
+		ids      = map[string]int{}
+		scanner  = bufio.NewScanner(bytes.NewReader(data))
+		lineNo   int
+	)
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var example Example
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&example); err != nil {
+			return nil, fmt.Errorf("failed to parse line %d of dataset %s: %w", lineNo, file, err)
+		}
+		if example.ID == "" {
+			example.ID = strconv.Itoa(lineNo)
+		}
+		if other, ok := ids[example.ID]; ok {
+			return nil, fmt.Errorf("line %d of dataset %s has the same ID %q as line %d", lineNo, file, example.ID, other)
+		}
+		ids[example.ID] = lineNo
+		examples = append(examples, example)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dataset %s: %w", file, err)
+	}
+	if len(examples) == 0 {
+		return nil, fmt.Errorf("dataset %s has no examples", file)
+	}
+
+	return examples, nil
+}
+
+// InputString returns the input of the run of the example.
+func (e Example) InputString() (string, error) {
+	return toString(e.Input)
+}
+
+// ExpectedString returns the expected output as text.
+func (e Example) ExpectedString() (string, error) {
+	return toString(e.Expected)
+}
+
+func toString(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
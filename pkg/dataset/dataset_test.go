@@ -0,0 +1,110 @@
+package dataset
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data.jsonl")
+	require.NoError(t, os.WriteFile(file, []byte(`{"id": "a", "input": "hi", "expected": "hello"}
+
+{"input": {"text": "cat"}, "expected": {"text": "CAT"}, "criteria": "be strict"}
+`), 0644))
+
+	examples, err := Load(file)
+	require.NoError(t, err)
+	require.Len(t, examples, 2)
+	require.Equal(t, "a", examples[0].ID)
+	require.Equal(t, "3", examples[1].ID)
+	require.Equal(t, "be strict", examples[1].Criteria)
+
+	input, err := examples[1].InputString()
+	require.NoError(t, err)
+	require.Equal(t, `{"text":"cat"}`, input)
+	expected, err := examples[0].ExpectedString()
+	require.NoError(t, err)
+	require.Equal(t, "hello", expected)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	dir := t.TempDir()
+	for content, msg := range map[string]string{
+		"\n\n":                             "has no examples",
+		`{"input": "a", "output": 1}`:      "failed to parse line 1",
+		"{\"id\": \"a\"}\n{\"id\": \"a\"}": `line 2 of dataset`,
+	} {
+		file := filepath.Join(dir, "data.jsonl")
+		require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+		_, err := Load(file)
+		require.ErrorContains(t, err, msg)
+	}
+}
+
+type fakeJudge struct {
+	criteria string
+}
+
+func (f *fakeJudge) Equal(_ context.Context, expected, actual, criteria string) (bool, string, error) {
+	f.criteria = criteria
+	return expected == actual, "they differ", nil
+}
+
+func TestScorers(t *testing.T) {
+	judge := &fakeJudge{}
+	scorers, err := NewScorers([]string{"exact", "contains", "json", "judge", "exact"}, ScorerOptions{
+		JSONFields: []string{"a", "b.c"},
+		Criteria:   "be fair",
+		Judge:      judge,
+	})
+	require.NoError(t, err)
+	require.Len(t, scorers, 4)
+	exact, contains, jsonFields, judged := scorers[0], scorers[1], scorers[2], scorers[3]
+
+	for _, test := range []struct {
+		scorer   Scorer
+		example  Example
+		output   string
+		score    float64
+		reason   string
+		criteria string
+	}{
+		{scorer: exact, example: Example{Expected: "cat"}, output: " cat\n", score: 1},
+		{scorer: exact, example: Example{Expected: "cat"}, output: "dog", reason: "the output is not the expected output"},
+		{scorer: exact, example: Example{Expected: map[string]any{"a": 1.0}}, output: `{ "a": 1 }`, score: 1},
+		{scorer: exact, example: Example{Expected: []any{"a"}}, output: "a", reason: "the output is not JSON"},
+		{scorer: contains, example: Example{Expected: "cat"}, output: "a cat", score: 1},
+		{scorer: contains, example: Example{Expected: []any{"cat", "dog"}}, output: "a cat", score: 0.5, reason: `the output doesn't contain "dog"`},
+		{scorer: jsonFields, example: Example{Expected: map[string]any{"a": "x", "b": map[string]any{"c": 2.0}}}, output: `{"a": "x", "b": {"c": 2}, "d": 3}`, score: 1},
+		{scorer: jsonFields, example: Example{Expected: `{"a": "x", "b": {"c": 2}}`}, output: `{"a": "y"}`, reason: "the fields that don't match are a, b.c"},
+		{scorer: jsonFields, example: Example{Expected: `{}`}, output: "text", reason: "the output is not a JSON object"},
+		{scorer: judged, example: Example{Expected: "cat"}, output: "cat", score: 1, criteria: "be fair"},
+		{scorer: judged, example: Example{Expected: "cat", Criteria: "be strict"}, output: "dog", reason: "they differ", criteria: "be strict"},
+	} {
+		score, reason, err := test.scorer.Score(context.Background(), test.example, test.output)
+		require.NoError(t, err)
+		require.Equal(t, test.score, score, "%s %v", test.scorer.Name(), test.example)
+		require.Equal(t, test.reason, reason)
+		if test.criteria != "" {
+			require.Equal(t, test.criteria, judge.criteria)
+		}
+	}
+
+	_, _, err = jsonFields.Score(context.Background(), Example{Expected: "not json"}, "{}")
+	require.ErrorContains(t, err, "the expected output is not a JSON object")
+}
+
+func TestNewScorersInvalid(t *testing.T) {
+	_, err := NewScorers([]string{"nope"}, ScorerOptions{})
+	require.EqualError(t, err, `unknown scorer "nope", the scorers are exact, contains, json, judge`)
+
+	_, err = NewScorers([]string{"judge"}, ScorerOptions{})
+	require.ErrorContains(t, err, "needs an OpenAI API key")
+
+	_, err = NewScorers(nil, ScorerOptions{})
+	require.EqualError(t, err, "no scorers")
+}
@@ -0,0 +1,148 @@
+package dataset
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// ReadReport reads a report that was written as JSON.
+func ReadReport(file string) (*Report, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", file, err)
+	}
+	return &report, nil
+}
+
+// WriteSummary writes the aggregate scores, token usage and latency of a report as a table.
+func WriteSummary(w io.Writer, report *Report) error {
+	tw := tabwriter.NewWriter(w, 10, 1, 3, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "EXAMPLES\t%d\n", report.Summary.Examples)
+	_, _ = fmt.Fprintf(tw, "PASSED\t%d\n", report.Summary.Passed)
+	_, _ = fmt.Fprintf(tw, "ERRORS\t%d\n", report.Summary.Errors)
+	for _, name := range report.Scorers {
+		_, _ = fmt.Fprintf(tw, "SCORE %s\t%.3f\n", name, report.Summary.Scores[name])
+	}
+	_, _ = fmt.Fprintf(tw, "TOKENS\t%d\n", report.Summary.Usage.TotalTokens)
+	_, _ = fmt.Fprintf(tw, "MEAN LATENCY\t%.2fs\n", report.Summary.MeanSeconds)
+	_, _ = fmt.Fprintf(tw, "P95 LATENCY\t%.2fs\n", report.Summary.P95Seconds)
+	return tw.Flush()
+}
+
+// WriteDiff compares two reports of the same dataset. It writes the change of the aggregates, and the scores of the
+// examples that changed.
+func WriteDiff(w io.Writer, base, head *Report) error {
+	tw := tabwriter.NewWriter(w, 10, 1, 3, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "\tBASE\tHEAD\tCHANGE\n")
+	_, _ = fmt.Fprintf(tw, "PASSED\t%d/%d\t%d/%d\t%+d\n", base.Summary.Passed, base.Summary.Examples, head.Summary.Passed, head.Summary.Examples, head.Summary.Passed-base.Summary.Passed)
+	_, _ = fmt.Fprintf(tw, "ERRORS\t%d\t%d\t%+d\n", base.Summary.Errors, head.Summary.Errors, head.Summary.Errors-base.Summary.Errors)
+	for _, name := range scorerNames(base, head) {
+		baseScore, inBase := base.Summary.Scores[name]
+		headScore, inHead := head.Summary.Scores[name]
+		_, _ = fmt.Fprintf(tw, "SCORE %s\t%s\t%s\t%s\n", name, score(baseScore, inBase), score(headScore, inHead), change(baseScore, inBase, headScore, inHead))
+	}
+	_, _ = fmt.Fprintf(tw, "TOKENS\t%d\t%d\t%+d\n", base.Summary.Usage.TotalTokens, head.Summary.Usage.TotalTokens, head.Summary.Usage.TotalTokens-base.Summary.Usage.TotalTokens)
+	_, _ = fmt.Fprintf(tw, "MEAN LATENCY\t%.2fs\t%.2fs\t%+.2fs\n", base.Summary.MeanSeconds, head.Summary.MeanSeconds, head.Summary.MeanSeconds-base.Summary.MeanSeconds)
+	_, _ = fmt.Fprintf(tw, "P95 LATENCY\t%.2fs\t%.2fs\t%+.2fs\n", base.Summary.P95Seconds, head.Summary.P95Seconds, head.Summary.P95Seconds-base.Summary.P95Seconds)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	var (
+		baseResults = map[string]Result{}
+		changed     [][]string
+		onlyHead    []string
+	)
+	for _, result := range base.Results {
+		baseResults[result.ID] = result
+	}
+	for _, result := range head.Results {
+		before, ok := baseResults[result.ID]
+		if !ok {
+			onlyHead = append(onlyHead, result.ID)
+			continue
+		}
+		delete(baseResults, result.ID)
+
+		if before.Error == "" && result.Error != "" {
+			changed = append(changed, []string{result.ID, "error", "", "", "now fails: " + result.Error})
+		} else if before.Error != "" && result.Error == "" {
+			changed = append(changed, []string{result.ID, "error", "", "", "no longer fails"})
+		}
+		for _, name := range scorerNames(base, head) {
+			baseScore, inBase := before.Scores[name]
+			headScore, inHead := result.Scores[name]
+			if !inBase || !inHead || baseScore == headScore {
+				continue
+			}
+			status := "improved"
+			if headScore < baseScore {
+				status = "regressed"
+			}
+			changed = append(changed, []string{result.ID, name, score(baseScore, true), score(headScore, true), status})
+		}
+	}
+
+	if len(changed) > 0 {
+		_, _ = fmt.Fprintf(w, "\n")
+		tw = tabwriter.NewWriter(w, 10, 1, 3, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "EXAMPLE\tSCORER\tBASE\tHEAD\tCHANGE\n")
+		for _, row := range changed {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", row[0], row[1], row[2], row[3], row[4])
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	var onlyBase []string
+	for id := range baseResults {
+		onlyBase = append(onlyBase, id)
+	}
+	sort.Strings(onlyBase)
+	if len(onlyBase) > 0 {
+		_, _ = fmt.Fprintf(w, "\nOnly in the base report: %v\n", onlyBase)
+	}
+	if len(onlyHead) > 0 {
+		_, _ = fmt.Fprintf(w, "\nOnly in the head report: %v\n", onlyHead)
+	}
+	return nil
+}
+
+// scorerNames returns the scorers of both reports, those of the base report first.
+func scorerNames(base, head *Report) []string {
+	var (
+		names []string
+		seen  = map[string]bool{}
+	)
+	for _, name := range append(append([]string{}, base.Scorers...), head.Scorers...) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func score(value float64, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.3f", value)
+}
+
+func change(base float64, inBase bool, head float64, inHead bool) string {
+	if !inBase || !inHead {
+		return "-"
+	}
+	return fmt.Sprintf("%+.3f", head-base)
+}
@@ -0,0 +1,80 @@
+package dataset
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gptscript-ai/gptscript/pkg/runner"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarize(t *testing.T) {
+	summary := summarize([]Scorer{exactScorer{}}, []Result{
+		{ID: "1", Scores: map[string]float64{"exact": 1}, Usage: types.Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3}, Seconds: 1},
+		{ID: "2", Scores: map[string]float64{"exact": 0}, Seconds: 3},
+		{ID: "3", Scores: map[string]float64{"exact": 0}, Error: "boom", Usage: types.Usage{TotalTokens: 1}, Seconds: 2},
+	})
+
+	require.Equal(t, 3, summary.Examples)
+	require.Equal(t, 1, summary.Passed)
+	require.Equal(t, 1, summary.Errors)
+	require.InDelta(t, 1.0/3, summary.Scores["exact"], 0.0001)
+	require.Equal(t, types.Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 4}, summary.Usage)
+	require.Equal(t, 2.0, summary.MeanSeconds)
+	require.Equal(t, 3.0, summary.P95Seconds)
+}
+
+func TestUsageMonitor(t *testing.T) {
+	usage := &usageCounter{}
+	mon := usageMonitor{usage: usage}
+	mon.Event(runner.Event{Type: runner.EventTypeChat, Usage: types.Usage{PromptTokens: 2, TotalTokens: 2}})
+	mon.Event(runner.Event{Type: runner.EventTypeCallFinish, Usage: types.Usage{TotalTokens: 100}})
+	mon.Event(runner.Event{Type: runner.EventTypeChat, Usage: types.Usage{CompletionTokens: 3, TotalTokens: 3}})
+	require.Equal(t, types.Usage{PromptTokens: 2, CompletionTokens: 3, TotalTokens: 5}, usage.get())
+
+	// Runs without a counter, such as those of other tools, are ignored.
+	usageMonitor{}.Event(runner.Event{Type: runner.EventTypeChat, Usage: types.Usage{TotalTokens: 1}})
+}
+
+func TestWriteDiff(t *testing.T) {
+	base := &Report{
+		Scorers: []string{"exact"},
+		Summary: Summary{Examples: 3, Passed: 1, Scores: map[string]float64{"exact": 0.5}, Usage: types.Usage{TotalTokens: 100}, MeanSeconds: 2, P95Seconds: 3},
+		Results: []Result{
+			{ID: "a", Scores: map[string]float64{"exact": 1}},
+			{ID: "b", Scores: map[string]float64{"exact": 0}},
+			{ID: "c", Scores: map[string]float64{"exact": 0}},
+		},
+	}
+	head := &Report{
+		Scorers: []string{"exact", "json"},
+		Summary: Summary{Examples: 3, Passed: 1, Errors: 1, Scores: map[string]float64{"exact": 0.5, "json": 1}, Usage: types.Usage{TotalTokens: 90}, MeanSeconds: 1.5, P95Seconds: 2},
+		Results: []Result{
+			{ID: "a", Scores: map[string]float64{"exact": 0, "json": 1}, Error: "boom"},
+			{ID: "b", Scores: map[string]float64{"exact": 1, "json": 1}},
+			{ID: "d", Scores: map[string]float64{"exact": 1, "json": 1}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteDiff(&buf, base, head))
+	require.Equal(t, `               BASE      HEAD      CHANGE
+PASSED         1/3       1/3       +0
+ERRORS         0         1         +1
+SCORE exact    0.500     0.500     +0.000
+SCORE json     -         1.000     -
+TOKENS         100       90        -10
+MEAN LATENCY   2.00s     1.50s     -0.50s
+P95 LATENCY    3.00s     2.00s     -1.00s
+
+EXAMPLE   SCORER    BASE      HEAD      CHANGE
+a         error                         now fails: boom
+a         exact     1.000     0.000     regressed
+b         exact     0.000     1.000     improved
+
+Only in the base report: [c]
+
+Only in the head report: [d]
+`, buf.String())
+}
@@ -0,0 +1,274 @@
+package dataset
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gptscript-ai/gptscript/pkg/cache"
+	"github.com/gptscript-ai/gptscript/pkg/gptscript"
+	"github.com/gptscript-ai/gptscript/pkg/monitor"
+	"github.com/gptscript-ai/gptscript/pkg/runner"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+)
+
+// Report is the result of evaluating a tool over a dataset.
+type Report struct {
+	Tool    string    `json:"tool"`
+	Dataset string    `json:"dataset"`
+	Scorers []string  `json:"scorers"`
+	Time    time.Time `json:"time"`
+	Summary Summary   `json:"summary"`
+	Results []Result  `json:"results"`
+}
+
+// Summary aggregates the results of a report.
+type Summary struct {
+	Examples int `json:"examples"`
+	// Passed is the number of examples with the top score of every scorer.
+	Passed int `json:"passed"`
+	Errors int `json:"errors"`
+	// Scores are the mean scores by scorer. The examples that failed to run score 0.
+	Scores      map[string]float64 `json:"scores"`
+	Usage       types.Usage        `json:"usage"`
+	MeanSeconds float64            `json:"meanSeconds"`
+	P95Seconds  float64            `json:"p95Seconds"`
+}
+
+// Result is the result of an example.
+type Result struct {
+	ID       string             `json:"id"`
+	Input    any                `json:"input,omitempty"`
+	Expected any                `json:"expected,omitempty"`
+	Output   string             `json:"output"`
+	Error    string             `json:"error,omitempty"`
+	Scores   map[string]float64 `json:"scores"`
+	Reasons  map[string]string  `json:"reasons,omitempty"`
+	Usage    types.Usage        `json:"usage"`
+	Seconds  float64            `json:"seconds"`
+}
+
+// Passed returns whether the example has the top score of every scorer.
+func (r Result) Passed() bool {
+	if r.Error != "" {
+		return false
+	}
+	for _, score := range r.Scores {
+		if score < 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// Runner runs the examples of datasets.
+type Runner struct {
+	gptscript *gptscript.GPTScript
+	env       []string
+}
+
+// NewRunner returns a runner that runs examples with the options.
+func NewRunner(ctx context.Context, opts ...gptscript.Options) (*Runner, error) {
+	opt := gptscript.Complete(opts...)
+
+	// The token usage of each run is collected from its events.
+	opt.Runner.MonitorFactory = monitor.NewMultiFactory(usageFactory{}, opt.Runner.MonitorFactory)
+
+	g, err := gptscript.New(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{
+		gptscript: g,
+		env:       opt.Env,
+	}, nil
+}
+
+// Cache returns the cache of the runner, for loading programs.
+func (r *Runner) Cache() *cache.Client {
+	return r.gptscript.Cache
+}
+
+func (r *Runner) Close() {
+	r.gptscript.Close(true)
+}
+
+// Options are the options of the evaluation of a dataset.
+type Options struct {
+	Scorers []Scorer
+	// Concurrency is the number of examples that run at the same time.
+	Concurrency int
+	// Progress, if set, is called after each example, in the order that they finish.
+	Progress func(result Result)
+}
+
+// Run runs the tool over the examples and scores the outputs.
+func (r *Runner) Run(ctx context.Context, prg types.Program, examples []Example, opts Options) *Report {
+	report := &Report{
+		Tool: prg.Name,
+		Time: time.Now(),
+	}
+	for _, scorer := range opts.Scorers {
+		report.Scorers = append(report.Scorers, scorer.Name())
+	}
+
+	var (
+		results = make([]Result, len(examples))
+		sem     = make(chan struct{}, max(opts.Concurrency, 1))
+		lock    sync.Mutex
+		wg      sync.WaitGroup
+	)
+	for i, example := range examples {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i] = r.runExample(ctx, prg, example, opts.Scorers)
+			if opts.Progress != nil {
+				lock.Lock()
+				opts.Progress(results[i])
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	report.Results = results
+	report.Summary = summarize(opts.Scorers, results)
+	return report
+}
+
+func (r *Runner) runExample(ctx context.Context, prg types.Program, example Example, scorers []Scorer) Result {
+	result := Result{
+		ID:       example.ID,
+		Input:    example.Input,
+		Expected: example.Expected,
+		Scores:   map[string]float64{},
+	}
+	for _, scorer := range scorers {
+		result.Scores[scorer.Name()] = 0
+	}
+
+	input, err := example.InputString()
+	if err != nil {
+		result.Error = fmt.Sprintf("invalid input: %v", err)
+		return result
+	}
+
+	var (
+		usage = &usageCounter{}
+		start = time.Now()
+	)
+	output, err := r.gptscript.Run(context.WithValue(ctx, usageKey{}, usage), prg, r.env, input)
+	result.Seconds = time.Since(start).Seconds()
+	result.Output = output
+	result.Usage = usage.get()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	for _, scorer := range scorers {
+		score, reason, err := scorer.Score(ctx, example, output)
+		if err != nil {
+			reason = fmt.Sprintf("failed to score: %v", err)
+		}
+		result.Scores[scorer.Name()] = score
+		if reason != "" {
+			if result.Reasons == nil {
+				result.Reasons = map[string]string{}
+			}
+			result.Reasons[scorer.Name()] = reason
+		}
+	}
+	return result
+}
+
+func summarize(scorers []Scorer, results []Result) Summary {
+	summary := Summary{
+		Examples: len(results),
+		Scores:   map[string]float64{},
+	}
+	if len(results) == 0 {
+		return summary
+	}
+
+	seconds := make([]float64, 0, len(results))
+	for _, result := range results {
+		if result.Error != "" {
+			summary.Errors++
+		} else if result.Passed() {
+			summary.Passed++
+		}
+		for name, score := range result.Scores {
+			summary.Scores[name] += score
+		}
+		summary.Usage.PromptTokens += result.Usage.PromptTokens
+		summary.Usage.CompletionTokens += result.Usage.CompletionTokens
+		summary.Usage.TotalTokens += result.Usage.TotalTokens
+		summary.MeanSeconds += result.Seconds
+		seconds = append(seconds, result.Seconds)
+	}
+
+	for _, scorer := range scorers {
+		summary.Scores[scorer.Name()] /= float64(len(results))
+	}
+	summary.MeanSeconds /= float64(len(results))
+	sort.Float64s(seconds)
+	summary.P95Seconds = seconds[(len(seconds)*95+99)/100-1]
+	return summary
+}
+
+type usageKey struct{}
+
+// usageCounter adds up the token usage of a run.
+type usageCounter struct {
+	lock  sync.Mutex
+	usage types.Usage
+}
+
+func (u *usageCounter) add(usage types.Usage) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.usage.PromptTokens += usage.PromptTokens
+	u.usage.CompletionTokens += usage.CompletionTokens
+	u.usage.TotalTokens += usage.TotalTokens
+}
+
+func (u *usageCounter) get() types.Usage {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	return u.usage
+}
+
+// usageFactory counts the token usage of the runs that have a usage counter in their context.
+type usageFactory struct{}
+
+func (usageFactory) Start(ctx context.Context, _ *types.Program, _ []string, _ string) (runner.Monitor, error) {
+	usage, _ := ctx.Value(usageKey{}).(*usageCounter)
+	return usageMonitor{usage: usage}, nil
+}
+
+func (usageFactory) Pause() func() {
+	return func() {}
+}
+
+type usageMonitor struct {
+	usage *usageCounter
+}
+
+func (u usageMonitor) Event(event runner.Event) {
+	if u.usage != nil && event.Type == runner.EventTypeChat {
+		u.usage.add(event.Usage)
+	}
+}
+
+func (usageMonitor) Pause() func() {
+	return func() {}
+}
+
+func (usageMonitor) Stop(context.Context, string, error) {}
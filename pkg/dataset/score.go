@@ -0,0 +1,238 @@
+package dataset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gptscript-ai/gptscript/pkg/types"
+)
+
+// Judge decides whether the output of a tool is equivalent to what is expected, using the criteria.
+type Judge interface {
+	Equal(ctx context.Context, expected, actual, criteria string) (equal bool, reasoning string, err error)
+}
+
+// defaultCriteria are the criteria of the judge when neither the options nor the example have any.
+const defaultCriteria = "The actual output must have the same meaning as the expected output, but can be worded differently."
+
+// ScorerNames are the names of the scorers, in the order that they are documented.
+var ScorerNames = []string{"exact", "contains", "json", "judge"}
+
+// Scorer scores the output of an example from 0 to 1.
+type Scorer interface {
+	Name() string
+	Score(ctx context.Context, example Example, output string) (score float64, reason string, err error)
+}
+
+// ScorerOptions configure the scorers.
+type ScorerOptions struct {
+	// JSONFields are the fields that the json scorer compares, as dotted paths. It compares all the fields of the
+	// expected output if there aren't any.
+	JSONFields []string
+	// Criteria are the criteria of the judge for the examples that don't have their own.
+	Criteria string
+	Judge    Judge
+}
+
+// NewScorers returns the scorers with the names.
+func NewScorers(names []string, opts ScorerOptions) ([]Scorer, error) {
+	var (
+		scorers []Scorer
+		seen    = map[string]bool{}
+	)
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		switch name {
+		case "exact":
+			scorers = append(scorers, exactScorer{})
+		case "contains":
+			scorers = append(scorers, containsScorer{})
+		case "json":
+			scorers = append(scorers, jsonScorer{fields: opts.JSONFields})
+		case "judge":
+			if opts.Judge == nil {
+				return nil, fmt.Errorf("the judge scorer needs a judge, which needs an OpenAI API key")
+			}
+			scorers = append(scorers, judgeScorer{judge: opts.Judge, criteria: opts.Criteria})
+		default:
+			return nil, fmt.Errorf("unknown scorer %q, the scorers are %s", name, strings.Join(ScorerNames, ", "))
+		}
+	}
+	if len(scorers) == 0 {
+		return nil, fmt.Errorf("no scorers")
+	}
+	return scorers, nil
+}
+
+// exactScorer scores 1 if the output is the expected output, ignoring whitespace at the start and end. An expected output
+// that isn't a string is compared to the output as JSON.
+type exactScorer struct{}
+
+func (exactScorer) Name() string {
+	return "exact"
+}
+
+func (exactScorer) Score(_ context.Context, example Example, output string) (float64, string, error) {
+	if _, ok := example.Expected.(string); !ok && example.Expected != nil {
+		var actual any
+		if err := json.Unmarshal([]byte(output), &actual); err != nil {
+			return 0, "the output is not JSON", nil
+		}
+		if reflect.DeepEqual(example.Expected, actual) {
+			return 1, "", nil
+		}
+		return 0, "the output is not the expected output", nil
+	}
+
+	expected, err := example.ExpectedString()
+	if err != nil {
+		return 0, "", err
+	}
+	if strings.TrimSpace(expected) == strings.TrimSpace(output) {
+		return 1, "", nil
+	}
+	return 0, "the output is not the expected output", nil
+}
+
+// containsScorer scores the share of the expected strings that the output contains. The expected output is a string or
+// a list of strings.
+type containsScorer struct{}
+
+func (containsScorer) Name() string {
+	return "contains"
+}
+
+func (containsScorer) Score(_ context.Context, example Example, output string) (float64, string, error) {
+	var expected []string
+	switch v := example.Expected.(type) {
+	case []any:
+		for _, item := range v {
+			s, err := toString(item)
+			if err != nil {
+				return 0, "", err
+			}
+			expected = append(expected, s)
+		}
+	default:
+		s, err := example.ExpectedString()
+		if err != nil {
+			return 0, "", err
+		}
+		expected = []string{s}
+	}
+	if len(expected) == 0 {
+		return 1, "", nil
+	}
+
+	var missing []string
+	for _, s := range expected {
+		if !strings.Contains(output, s) {
+			missing = append(missing, fmt.Sprintf("%q", s))
+		}
+	}
+	if len(missing) == 0 {
+		return 1, "", nil
+	}
+	return float64(len(expected)-len(missing)) / float64(len(expected)), "the output doesn't contain " + strings.Join(missing, ", "), nil
+}
+
+// jsonScorer scores the share of the fields of the expected output that have the same value in the output. Both are
+// JSON objects, and the expected output is parsed if it is a string.
+type jsonScorer struct {
+	fields []string
+}
+
+func (jsonScorer) Name() string {
+	return "json"
+}
+
+func (j jsonScorer) Score(_ context.Context, example Example, output string) (float64, string, error) {
+	expected, ok := example.Expected.(map[string]any)
+	if !ok {
+		s, err := example.ExpectedString()
+		if err != nil {
+			return 0, "", err
+		}
+		if err := json.Unmarshal([]byte(s), &expected); err != nil {
+			return 0, "", fmt.Errorf("the expected output is not a JSON object: %w", err)
+		}
+	}
+
+	var actual map[string]any
+	if err := json.Unmarshal([]byte(output), &actual); err != nil {
+		return 0, "the output is not a JSON object", nil
+	}
+
+	fields := j.fields
+	if len(fields) == 0 {
+		for field := range expected {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+	}
+	if len(fields) == 0 {
+		return 1, "", nil
+	}
+
+	var mismatched []string
+	for _, field := range fields {
+		want, _ := lookup(expected, field)
+		got, ok := lookup(actual, field)
+		if !ok || !reflect.DeepEqual(want, got) {
+			mismatched = append(mismatched, field)
+		}
+	}
+	if len(mismatched) == 0 {
+		return 1, "", nil
+	}
+	return float64(len(fields)-len(mismatched)) / float64(len(fields)), "the fields that don't match are " + strings.Join(mismatched, ", "), nil
+}
+
+// lookup returns the value of a dotted path in a JSON object.
+func lookup(obj map[string]any, path string) (any, bool) {
+	var value any = obj
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// judgeScorer scores 1 if the judge rules that the output is equivalent to the expected output.
+type judgeScorer struct {
+	judge    Judge
+	criteria string
+}
+
+func (judgeScorer) Name() string {
+	return "judge"
+}
+
+func (j judgeScorer) Score(ctx context.Context, example Example, output string) (float64, string, error) {
+	expected, err := example.ExpectedString()
+	if err != nil {
+		return 0, "", err
+	}
+
+	equal, reasoning, err := j.judge.Equal(ctx, expected, output, types.FirstSet(example.Criteria, j.criteria, defaultCriteria))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to ask the judge: %w", err)
+	}
+	if equal {
+		return 1, "", nil
+	}
+	return 0, reasoning, nil
+}
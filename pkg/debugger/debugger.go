@@ -0,0 +1,246 @@
+// Package debugger pauses runs before each tool call and request to the LLM, and asks the developer what to do.
+package debugger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
+	"github.com/gptscript-ai/gptscript/pkg/context"
+	"github.com/gptscript-ai/gptscript/pkg/engine"
+	"github.com/gptscript-ai/gptscript/pkg/runner"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+)
+
+// ErrStopped is returned when the developer stops the run.
+var ErrStopped = errors.New("run stopped by the debugger")
+
+// maxContent is how much of the content of each message is shown for requests to the LLM. The full request can be
+// shown with the "Show the full request" answer.
+const maxContent = 500
+
+const (
+	answerStep        = "Step to the next call or request"
+	answerContinue    = "Continue to the next breakpoint"
+	answerEdit        = "Edit the input"
+	answerSkip        = "Skip the call"
+	answerResult      = "Return a result without running the call"
+	answerShowRequest = "Show the full request"
+	answerBreakpoint  = "Add a breakpoint"
+	answerStop        = "Stop the run"
+)
+
+type Debugger struct {
+	lock        sync.Mutex
+	out         io.Writer
+	stepping    bool
+	breakpoints map[string]struct{}
+}
+
+// New returns a debugger that pauses at every step, or only at the calls of the tools with the breakpoint names if
+// there are any.
+func New(breakpoints ...string) *Debugger {
+	d := &Debugger{
+		out:         os.Stderr,
+		stepping:    len(breakpoints) == 0,
+		breakpoints: map[string]struct{}{},
+	}
+	for _, name := range breakpoints {
+		d.addBreakpoint(name)
+	}
+	return d
+}
+
+func (d *Debugger) addBreakpoint(name string) {
+	if name = strings.TrimSpace(name); name != "" {
+		d.breakpoints[strings.ToLower(name)] = struct{}{}
+	}
+}
+
+// stops returns whether the debugger pauses for the call, which it does when stepping or when the tool of the call
+// has a breakpoint.
+func (d *Debugger) stops(ctx engine.Context) bool {
+	if d.stepping {
+		return true
+	}
+	for _, name := range []string{ctx.Tool.Name, ctx.Tool.ID} {
+		if _, ok := d.breakpoints[strings.ToLower(name)]; ok && name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Step is the runner.DebuggerFunc of the debugger.
+func (d *Debugger) Step(ctx engine.Context, step runner.DebugStep) (runner.DebugResponse, error) {
+	defer context.GetPauseFuncFromCtx(ctx.Ctx)()()
+
+	// Calls can run in parallel, so only ask about one at a time.
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if !d.stops(ctx) {
+		return runner.DebugResponse{}, nil
+	}
+
+	_, _ = fmt.Fprint(d.out, describe(ctx, step))
+
+	options := []string{answerStep, answerContinue}
+	if step.Type == runner.DebugStepCall {
+		options = append(options, answerEdit, answerSkip, answerResult)
+	} else {
+		options = append(options, answerShowRequest)
+	}
+	options = append(options, answerBreakpoint, answerStop)
+
+	for {
+		var answer string
+		if err := survey.AskOne(&survey.Select{
+			Message: "Debugger",
+			Options: options,
+			Default: answerStep,
+		}, &answer); err != nil {
+			return runner.DebugResponse{}, err
+		}
+
+		switch answer {
+		case answerStep:
+			d.stepping = true
+			return runner.DebugResponse{Action: runner.DebugContinue}, nil
+		case answerContinue:
+			d.stepping = false
+			return runner.DebugResponse{Action: runner.DebugContinue}, nil
+		case answerEdit:
+			input := step.Input
+			if err := survey.AskOne(&survey.Editor{
+				Message:       "Input",
+				Default:       input,
+				AppendDefault: true,
+				HideDefault:   true,
+			}, &input); err != nil {
+				return runner.DebugResponse{}, err
+			}
+			d.stepping = true
+			return runner.DebugResponse{Action: runner.DebugContinue, Input: &input}, nil
+		case answerSkip:
+			return runner.DebugResponse{Action: runner.DebugSkip}, nil
+		case answerResult:
+			var result string
+			if err := survey.AskOne(&survey.Multiline{
+				Message: "Result",
+			}, &result); err != nil {
+				return runner.DebugResponse{}, err
+			}
+			return runner.DebugResponse{Action: runner.DebugResult, Result: result}, nil
+		case answerShowRequest:
+			data, err := json.MarshalIndent(step.Completion, "", "  ")
+			if err != nil {
+				return runner.DebugResponse{}, err
+			}
+			_, _ = fmt.Fprintf(d.out, "%s\n", data)
+		case answerBreakpoint:
+			var name string
+			if err := survey.AskOne(&survey.Input{
+				Message: "Tool name",
+			}, &name); err != nil {
+				return runner.DebugResponse{}, err
+			}
+			d.addBreakpoint(name)
+		case answerStop:
+			return runner.DebugResponse{}, ErrStopped
+		}
+	}
+}
+
+// describe returns what is about to happen in a step, and what the call knows when it happens.
+func describe(ctx engine.Context, step runner.DebugStep) string {
+	var (
+		buf  strings.Builder
+		name = toolName(ctx.Tool)
+	)
+
+	if step.Type == runner.DebugStepCompletion {
+		model := ""
+		if step.Completion != nil {
+			model = " with model " + step.Completion.Model
+		}
+		fmt.Fprintf(&buf, "\n%s request to the LLM for call %s of %s%s\n", color.CyanString("Debugger:"), ctx.ID, color.YellowString(name), model)
+	} else {
+		fmt.Fprintf(&buf, "\n%s call %s of %s", color.CyanString("Debugger:"), ctx.ID, color.YellowString(name))
+		if ctx.ToolCategory != engine.NoCategory {
+			fmt.Fprintf(&buf, " (%s)", ctx.ToolCategory)
+		}
+		if loc := ctx.Tool.Source.Location; loc != "" {
+			fmt.Fprintf(&buf, " from %s:%d", loc, ctx.Tool.Source.LineNo)
+		}
+		buf.WriteString("\n")
+		fmt.Fprintf(&buf, "Input:\n%s\n", indent(step.Input))
+	}
+
+	if len(ctx.InputContext) > 0 {
+		buf.WriteString("Input context:\n")
+		for _, input := range ctx.InputContext {
+			fmt.Fprintf(&buf, "  %s:\n%s\n", toolName(ctx.Program.ToolSet[input.ToolID]), indent(indent(input.Content)))
+		}
+	}
+
+	if len(step.Pending) > 0 {
+		buf.WriteString("Pending calls:\n")
+		ids := make([]string, 0, len(step.Pending))
+		for id := range step.Pending {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			call := step.Pending[id]
+			marker := " "
+			if id == ctx.ID {
+				marker = "*"
+			}
+			fmt.Fprintf(&buf, "%s %s %s %s\n", marker, id, toolName(ctx.Program.ToolSet[call.ToolID]), call.Input)
+		}
+	}
+
+	if step.Completion != nil {
+		buf.WriteString("Messages:\n")
+		for _, msg := range step.Completion.Messages {
+			role := string(msg.Role)
+			if msg.ToolCall != nil {
+				role += " (" + msg.ToolCall.Function.Name + ")"
+			}
+			fmt.Fprintf(&buf, "  %s:\n%s\n", role, indent(indent(truncate(msg.String()))))
+		}
+		if len(step.Completion.Tools) > 0 {
+			var tools []string
+			for _, tool := range step.Completion.Tools {
+				tools = append(tools, tool.Function.Name)
+			}
+			fmt.Fprintf(&buf, "Tools: %s\n", strings.Join(tools, ", "))
+		}
+	}
+
+	return buf.String()
+}
+
+// toolName returns the name of the tool, or the file that it is in for the first tool of a file without a name.
+func toolName(tool types.Tool) string {
+	return types.FirstSet(tool.Name, tool.Source.Location, tool.ID)
+}
+
+func truncate(s string) string {
+	if len(s) > maxContent {
+		return s[:maxContent] + "..."
+	}
+	return s
+}
+
+func indent(s string) string {
+	return "  " + strings.ReplaceAll(strings.TrimRight(s, "\n"), "\n", "\n  ")
+}
@@ -0,0 +1,86 @@
+package debugger
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/gptscript-ai/gptscript/pkg/engine"
+	"github.com/gptscript-ai/gptscript/pkg/runner"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func testContext() engine.Context {
+	search := types.Tool{
+		ToolDef: types.ToolDef{Parameters: types.Parameters{Name: "search"}},
+		ID:      "tools.gpt:search",
+	}
+	ctx := engine.Context{
+		Program: &types.Program{
+			ToolSet: types.ToolSet{
+				"tools.gpt:search": search,
+				"tools.gpt:date":   {ToolDef: types.ToolDef{Parameters: types.Parameters{Name: "date"}}},
+			},
+		},
+	}
+	ctx.ID = "2"
+	ctx.Tool = search
+	ctx.InputContext = []engine.InputContext{{ToolID: "tools.gpt:date", Content: "today"}}
+	return ctx
+}
+
+func TestStops(t *testing.T) {
+	ctx := testContext()
+
+	require.True(t, New().stops(ctx))
+	require.True(t, New("Search").stops(ctx))
+	require.True(t, New("tools.gpt:search").stops(ctx))
+	require.False(t, New("fetch").stops(ctx))
+}
+
+func TestDescribe(t *testing.T) {
+	color.NoColor = true
+	ctx := testContext()
+
+	require.Equal(t, `
+Debugger: call 2 of search
+Input:
+  {"query":"cats"}
+Input context:
+  date:
+    today
+Pending calls:
+  1 date {}
+* 2 search {"query":"cats"}
+`, describe(ctx, runner.DebugStep{
+		Type:  runner.DebugStepCall,
+		Input: `{"query":"cats"}`,
+		Pending: map[string]engine.Call{
+			"2": {ToolID: "tools.gpt:search", Input: `{"query":"cats"}`},
+			"1": {ToolID: "tools.gpt:date", Input: "{}"},
+		},
+	}))
+
+	require.Equal(t, `
+Debugger: request to the LLM for call 2 of search with model gpt-4o
+Input context:
+  date:
+    today
+Messages:
+  system:
+    Search
+  tool (date):
+    today
+Tools: date
+`, describe(ctx, runner.DebugStep{
+		Type: runner.DebugStepCompletion,
+		Completion: &types.CompletionRequest{
+			Model: "gpt-4o",
+			Messages: []types.CompletionMessage{
+				{Role: types.CompletionMessageRoleTypeSystem, Content: types.Text("Search")},
+				{Role: types.CompletionMessageRoleTypeTool, Content: types.Text("today"), ToolCall: &types.CompletionToolCall{Function: types.CompletionFunctionCall{Name: "date"}}},
+			},
+			Tools: []types.ChatCompletionTool{{Function: types.CompletionFunctionDefinition{Name: "date"}}},
+		},
+	}))
+}
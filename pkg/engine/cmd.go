@@ -66,15 +66,22 @@ func compressEnv(envs []string) (result []string) {
 }
 
 func (e *Engine) runCommand(ctx Context, tool types.Tool, input string, toolCategory ToolCategory) (cmdOut string, cmdErr error) {
-	id := counter.Next()
+	var (
+		id       = counter.Next()
+		exitCode *int
+	)
 
 	defer func() {
+		response := map[string]any{
+			"output": cmdOut,
+			"err":    cmdErr,
+		}
+		if exitCode != nil {
+			response["exitCode"] = *exitCode
+		}
 		e.Progress <- types.CompletionStatus{
 			CompletionID: id,
-			Response: map[string]any{
-				"output": cmdOut,
-				"err":    cmdErr,
-			},
+			Response:     response,
 		}
 	}()
 
@@ -129,6 +136,13 @@ func (e *Engine) runCommand(ctx Context, tool types.Tool, input string, toolCate
 	}
 	defer stop()
 
+	cacheKey := e.resultCacheKey(tool, input, strings.Join(instructions, "\n"), commandRuntime(tool, cmd))
+	if cached, ok, err := e.cachedResult(ctx.Ctx, tool, cacheKey); err != nil {
+		return "", err
+	} else if ok {
+		return cached, IsChatFinishMessage(cached)
+	}
+
 	e.Progress <- types.CompletionStatus{
 		CompletionID: id,
 		Request: map[string]any{
@@ -156,7 +170,15 @@ func (e *Engine) runCommand(ctx Context, tool types.Tool, input string, toolCate
 		result = stdout
 	}
 
-	if err := cmd.Run(); err != nil {
+	err = cmd.Run()
+	if cmd.ProcessState != nil {
+		code := cmd.ProcessState.ExitCode()
+		exitCode = &code
+		if e.CommandExited != nil {
+			e.CommandExited(code)
+		}
+	}
+	if err != nil {
 		if toolCategory == NoCategory {
 			return fmt.Sprintf("ERROR: got (%v) while running tool, OUTPUT: %s", err, stdoutAndErr), nil
 		}
@@ -164,9 +186,18 @@ func (e *Engine) runCommand(ctx Context, tool types.Tool, input string, toolCate
 		return "", fmt.Errorf("ERROR: %s: %w", result, err)
 	}
 
+	if err := e.storeResult(ctx.Ctx, cacheKey, result.String()); err != nil {
+		return "", err
+	}
 	return result.String(), IsChatFinishMessage(result.String())
 }
 
+// commandRuntime returns the program that runs the command and the directory of its runtime, so that the result of the
+// command isn't used from the cache when the runtime changes.
+func commandRuntime(tool types.Tool, cmd *exec.Cmd) []string {
+	return []string{env.Lookup(cmd.Env, tool.GetInterpreter()), env.Getenv("GPTSCRIPT_TOOL_DIR", cmd.Env)}
+}
+
 func (e *Engine) getRuntimeEnv(ctx context.Context, tool types.Tool, cmd, env []string) ([]string, error) {
 	var (
 		workdir = tool.WorkingDir
@@ -2,25 +2,40 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"math/rand"
 	"net/http"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
+	"github.com/gptscript-ai/gptscript/pkg/daemon"
 	"github.com/gptscript-ai/gptscript/pkg/system"
 	"github.com/gptscript-ai/gptscript/pkg/types"
 )
 
-var ports Ports
+var (
+	ports Ports
+
+	// restartBackoff is the delay before the first restart of a failed daemon, it doubles with each restart.
+	restartBackoff = time.Second
+)
 
 type Ports struct {
-	daemonPorts    map[string]int64
+	daemons        map[string]*daemonProcess
 	daemonsRunning map[string]struct{}
+	daemonRestarts map[string]int
 	daemonLock     sync.Mutex
+	daemonDir      string
 
 	startPort, endPort int64
 	usedPorts          map[int64]struct{}
@@ -29,6 +44,51 @@ type Ports struct {
 	daemonWG           sync.WaitGroup
 }
 
+type RestartPolicy string
+
+const (
+	// RestartOnDemand will start the daemon again the next time it is needed after it exits. This is the default.
+	RestartOnDemand RestartPolicy = "on-demand"
+	// RestartNever will never start the daemon again after it exits, all further calls will fail.
+	RestartNever RestartPolicy = "never"
+	// RestartOnFailure will restart the daemon with a backoff if it exits with an error or fails its health check.
+	RestartOnFailure RestartPolicy = "on-failure"
+
+	defaultMaxRestarts     = 5
+	maxRestartBackoff      = 30 * time.Second
+	healthFailureThreshold = 3
+)
+
+type daemonOptions struct {
+	// Path is the path appended to the daemon URL
+	Path string
+	// HealthPath is the path used for health checks, defaults to Path
+	HealthPath string
+	// HealthInterval is how often to check the health of a running daemon, zero disables periodic checks
+	HealthInterval time.Duration
+	Restart        RestartPolicy
+	MaxRestarts    int
+	// Socket will have the daemon listen on a Unix socket passed in GPTSCRIPT_SOCKET instead of a TCP port
+	Socket bool
+}
+
+type daemonProcess struct {
+	engine   *Engine
+	tool     types.Tool
+	opts     daemonOptions
+	port     int64
+	socket   string
+	host     string
+	url      string
+	logFile  string
+	pid      int
+	status   daemon.Status
+	started  time.Time
+	restarts int
+	err      error
+	ready    chan struct{}
+}
+
 func IsDaemonRunning(url string) bool {
 	ports.daemonLock.Lock()
 	defer ports.daemonLock.Unlock()
@@ -45,6 +105,32 @@ func SetPorts(start, end int64) {
 	}
 }
 
+// SetDaemonDir sets the directory that daemon output is logged to and daemon state is recorded in. If no
+// directory is set the daemons will write to the stdout and stderr of this process.
+func SetDaemonDir(dir string) {
+	ports.daemonLock.Lock()
+	defer ports.daemonLock.Unlock()
+	if ports.daemonDir == "" {
+		ports.daemonDir = dir
+	}
+}
+
+// ListDaemons returns the daemons started by this process.
+func ListDaemons() []daemon.Info {
+	ports.daemonLock.Lock()
+	defer ports.daemonLock.Unlock()
+
+	result := make([]daemon.Info, 0, len(ports.daemons))
+	for _, d := range ports.daemons {
+		result = append(result, d.info())
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Port < result[j].Port
+	})
+	return result
+}
+
 func CloseDaemons() {
 	ports.daemonLock.Lock()
 	if ports.daemonCtx == nil {
@@ -55,6 +141,14 @@ func CloseDaemons() {
 
 	ports.daemonClose()
 	ports.daemonWG.Wait()
+
+	// Daemons that will not be restarted are kept so that calls to them fail, clean them up now.
+	ports.daemonLock.Lock()
+	defer ports.daemonLock.Unlock()
+	for _, d := range ports.daemons {
+		d.remove(false)
+	}
+	closeSockets()
 }
 
 func nextPort() int64 {
@@ -87,40 +181,96 @@ func nextPort() int64 {
 	panic("Ran out of usable ports")
 }
 
-func getPath(instructions string) (string, string) {
+var spaceAroundEquals = regexp.MustCompile(`\s*=\s*`)
+
+// getDaemonOptions parses the options in the form "(path=/ready, restart=on-failure)" that can follow #!sys.daemon.
+// If the leading parenthesis does not contain valid options then it is assumed to be part of the command and the
+// instructions are returned unchanged.
+func getDaemonOptions(instructions string) (string, daemonOptions, error) {
 	instructions = strings.TrimSpace(instructions)
-	line := strings.TrimSpace(instructions)
+	defaults := daemonOptions{
+		Restart:     RestartOnDemand,
+		MaxRestarts: defaultMaxRestarts,
+	}
+	opts := defaults
 
-	if !strings.HasPrefix(line, "(") {
-		return instructions, ""
+	if !strings.HasPrefix(instructions, "(") {
+		return instructions, defaults, nil
 	}
 
-	line, rest, ok := strings.Cut(line[1:], ")")
+	line, rest, ok := strings.Cut(instructions[1:], ")")
 	if !ok {
-		return instructions, ""
+		return instructions, defaults, nil
+	}
+
+	fields := strings.FieldsFunc(spaceAroundEquals.ReplaceAllString(line, "="), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(fields) == 0 {
+		return instructions, defaults, nil
+	}
+
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return instructions, defaults, nil
+		}
+
+		var err error
+		switch strings.ToLower(key) {
+		case "path":
+			opts.Path = value
+		case "health":
+			opts.HealthPath = value
+		case "interval":
+			opts.HealthInterval, err = time.ParseDuration(value)
+			if err == nil && opts.HealthInterval < 0 {
+				err = fmt.Errorf("must not be negative")
+			}
+		case "restart":
+			switch RestartPolicy(strings.ToLower(value)) {
+			case RestartOnDemand, RestartNever, RestartOnFailure:
+				opts.Restart = RestartPolicy(strings.ToLower(value))
+			default:
+				err = fmt.Errorf("must be one of %s, %s, or %s", RestartOnDemand, RestartNever, RestartOnFailure)
+			}
+		case "socket":
+			opts.Socket, err = strconv.ParseBool(value)
+		case "retries", "maxrestarts":
+			opts.MaxRestarts, err = strconv.Atoi(value)
+			if err == nil && opts.MaxRestarts < 0 {
+				err = fmt.Errorf("must not be negative")
+			}
+		default:
+			return instructions, defaults, nil
+		}
+		if err != nil {
+			return "", daemonOptions{}, fmt.Errorf("invalid daemon option %s=%s: %w", key, value, err)
+		}
 	}
 
-	path, value, ok := strings.Cut(strings.TrimSpace(line), "=")
-	if !ok || strings.TrimSpace(path) != "path" {
-		return instructions, ""
+	if opts.HealthPath == "" {
+		opts.HealthPath = opts.Path
 	}
 
-	return strings.TrimSpace(rest), strings.TrimSpace(value)
+	return strings.TrimSpace(rest), opts, nil
 }
 
-func (e *Engine) startDaemon(tool types.Tool) (string, error) {
+func (e *Engine) startDaemon(ctx context.Context, tool types.Tool) (string, error) {
 	ports.daemonLock.Lock()
-	defer ports.daemonLock.Unlock()
 
-	instructions := strings.TrimPrefix(tool.Instructions, types.DaemonPrefix)
-	instructions, path := getPath(instructions)
-	tool.Instructions = types.CommandPrefix + instructions
+	if d, ok := ports.daemons[tool.ID]; ok {
+		ports.daemonLock.Unlock()
+		return d.wait(ctx)
+	}
 
-	port, ok := ports.daemonPorts[tool.ID]
-	url := fmt.Sprintf("http://127.0.0.1:%d%s", port, path)
-	if ok {
-		return url, nil
+	instructions := strings.TrimPrefix(tool.Instructions, types.DaemonPrefix)
+	instructions, opts, err := getDaemonOptions(instructions)
+	if err != nil {
+		ports.daemonLock.Unlock()
+		return "", err
 	}
+	tool.Instructions = types.CommandPrefix + instructions
 
 	if ports.daemonCtx == nil {
 		var cancel func()
@@ -131,25 +281,261 @@ func (e *Engine) startDaemon(tool types.Tool) (string, error) {
 		}
 	}
 
-	ctx := ports.daemonCtx
-	port = nextPort()
-	url = fmt.Sprintf("http://127.0.0.1:%d%s", port, path)
+	if ports.daemons == nil {
+		ports.daemons = map[string]*daemonProcess{}
+		ports.daemonsRunning = map[string]struct{}{}
+		ports.daemonRestarts = map[string]int{}
+	}
+
+	d := &daemonProcess{
+		engine:   e,
+		tool:     tool,
+		opts:     opts,
+		status:   daemon.StatusStarting,
+		restarts: ports.daemonRestarts[tool.ID],
+		ready:    make(chan struct{}),
+	}
+
+	logName := daemonLogName(tool)
+	if opts.Socket {
+		d.host, d.socket, err = nextSocket(logName)
+		if err != nil {
+			ports.daemonLock.Unlock()
+			return "", err
+		}
+		logName = fmt.Sprintf("%s-%d-%s", logName, os.Getpid(), strings.TrimSuffix(filepath.Base(d.socket), ".sock"))
+	} else {
+		d.port = nextPort()
+		d.host = fmt.Sprintf("127.0.0.1:%d", d.port)
+		logName = fmt.Sprintf("%s-%d", logName, d.port)
+	}
+	d.url = "http://" + d.host + opts.Path
+
+	if ports.daemonDir != "" {
+		d.logFile = filepath.Join(ports.daemonDir, logName+".log")
+	}
+
+	ports.daemons[tool.ID] = d
+	ports.daemonsRunning[d.url] = struct{}{}
+
+	ports.daemonWG.Add(1)
+	go d.supervise(ports.daemonCtx)
+
+	ports.daemonLock.Unlock()
+	return d.wait(ctx)
+}
+
+var nonAlphaNumeric = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+func daemonLogName(tool types.Tool) string {
+	name := strings.Trim(nonAlphaNumeric.ReplaceAllString(tool.Parameters.Name, "-"), "-")
+	if name == "" {
+		return "daemon"
+	}
+	return name
+}
+
+// wait blocks until the daemon is healthy or has failed.
+func (d *daemonProcess) wait(ctx context.Context) (string, error) {
+	for {
+		ports.daemonLock.Lock()
+		var (
+			status = d.status
+			err    = d.err
+			ready  = d.ready
+		)
+		ports.daemonLock.Unlock()
+
+		switch status {
+		case daemon.StatusRunning:
+			return d.url, nil
+		case daemon.StatusExited, daemon.StatusFailed:
+			return d.url, err
+		}
+
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return d.url, ctx.Err()
+		}
+	}
+}
+
+func (d *daemonProcess) info() daemon.Info {
+	info := daemon.Info{
+		ToolID:        d.tool.ID,
+		ToolName:      d.tool.Parameters.Name,
+		Port:          d.port,
+		Socket:        d.socket,
+		URL:           d.url,
+		PID:           d.pid,
+		SupervisorPID: os.Getpid(),
+		Status:        d.status,
+		Restart:       string(d.opts.Restart),
+		Started:       d.started,
+		Restarts:      d.restarts,
+		LogFile:       d.logFile,
+	}
+	if d.err != nil {
+		info.Error = d.err.Error()
+	}
+	return info
+}
+
+// setStatus must be called while holding ports.daemonLock
+func (d *daemonProcess) setStatus(status daemon.Status, err error) {
+	d.status = status
+	d.err = err
+
+	switch status {
+	case daemon.StatusRunning, daemon.StatusExited, daemon.StatusFailed:
+		select {
+		case <-d.ready:
+		default:
+			close(d.ready)
+		}
+	default:
+		select {
+		case <-d.ready:
+			d.ready = make(chan struct{})
+		default:
+		}
+	}
+
+	if ports.daemonDir != "" {
+		if err := daemon.WriteState(ports.daemonDir, d.info()); err != nil {
+			log.Debugf("failed to write daemon state for [%s]: %v", d.tool.Parameters.Name, err)
+		}
+	}
+}
+
+// remove must be called while holding ports.daemonLock. If the daemon had been running, then starting it again will
+// be counted as a restart.
+func (d *daemonProcess) remove(wasRunning bool) {
+	if ports.daemons[d.tool.ID] == d {
+		delete(ports.daemons, d.tool.ID)
+	}
+	delete(ports.daemonsRunning, d.url)
+	if d.socket != "" {
+		releaseSocket(d.host)
+	}
+	ports.daemonRestarts[d.tool.ID] = d.restarts
+	if wasRunning {
+		ports.daemonRestarts[d.tool.ID]++
+	}
+	if ports.daemonDir != "" {
+		if err := daemon.RemoveState(ports.daemonDir, d.info()); err != nil {
+			log.Debugf("failed to remove daemon state for [%s]: %v", d.tool.Parameters.Name, err)
+		}
+	}
+}
+
+func (d *daemonProcess) supervise(ctx context.Context) {
+	defer ports.daemonWG.Done()
+
+	var healthy bool
+	for {
+		err := d.runOnce(ctx, func() {
+			healthy = true
+		})
 
-	cmd, stop, err := e.newCommand(ctx, []string{
-		fmt.Sprintf("PORT=%d", port),
-		fmt.Sprintf("GPTSCRIPT_PORT=%d", port),
-	},
-		tool,
+		ports.daemonLock.Lock()
+		switch {
+		case ctx.Err() != nil:
+			d.setStatus(daemon.StatusExited, fmt.Errorf("daemon [%s] stopped: %w", d.tool.Parameters.Name, ctx.Err()))
+			d.remove(healthy)
+			ports.daemonLock.Unlock()
+			return
+		case !healthy:
+			// The daemon never became healthy, report the error to callers and let the next call try again
+			d.setStatus(daemon.StatusFailed, err)
+			d.remove(false)
+			ports.daemonLock.Unlock()
+			return
+		case d.opts.Restart == RestartOnFailure && err != nil && d.restarts < d.opts.MaxRestarts:
+			d.setStatus(daemon.StatusRestarting, err)
+			ports.daemonLock.Unlock()
+		case d.opts.Restart == RestartOnFailure && err != nil:
+			log.Errorf("daemon [%s] exceeded %d restarts, giving up: %v", d.tool.Parameters.Name, d.opts.MaxRestarts, err)
+			d.setStatus(daemon.StatusFailed, fmt.Errorf("daemon [%s] exceeded %d restarts: %w", d.tool.Parameters.Name, d.opts.MaxRestarts, err))
+			delete(ports.daemonsRunning, d.url)
+			ports.daemonLock.Unlock()
+			return
+		case d.opts.Restart == RestartNever:
+			log.Warnf("daemon [%s] exited and will not be restarted: %v", d.tool.Parameters.Name, err)
+			d.setStatus(daemon.StatusExited, fmt.Errorf("daemon [%s] exited and restart policy is %s: %v", d.tool.Parameters.Name, RestartNever, err))
+			delete(ports.daemonsRunning, d.url)
+			ports.daemonLock.Unlock()
+			return
+		default:
+			log.Warnf("daemon [%s] exited, it will be started again when next needed: %v", d.tool.Parameters.Name, err)
+			d.setStatus(daemon.StatusExited, err)
+			d.remove(true)
+			ports.daemonLock.Unlock()
+			return
+		}
+
+		backoff := restartDelay(d.restarts)
+		log.Warnf("daemon [%s] failed, restarting in %v: %v", d.tool.Parameters.Name, backoff, err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+
+		ports.daemonLock.Lock()
+		d.restarts++
+		ports.daemonLock.Unlock()
+	}
+}
+
+// runOnce launches the daemon process and blocks until it exits. onHealthy is called after the process has responded
+// to its first health check.
+func (d *daemonProcess) runOnce(ctx context.Context, onHealthy func()) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	env := []string{
+		fmt.Sprintf("PORT=%d", d.port),
+		fmt.Sprintf("GPTSCRIPT_PORT=%d", d.port),
+	}
+	if d.socket != "" {
+		env = []string{"GPTSCRIPT_SOCKET=" + d.socket}
+		// Remove the socket left behind if the daemon was restarted so that the new process can listen on it
+		if err := os.Remove(d.socket); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	cmd, stop, err := d.engine.newCommand(ctx, env,
+		d.tool,
 		"{}",
 		false,
 	)
 	if err != nil {
-		return url, err
+		return err
 	}
+	defer stop()
 
 	r, w, err := os.Pipe()
 	if err != nil {
-		return "", err
+		return err
+	}
+	defer func() {
+		_ = r.Close()
+		_ = w.Close()
+	}()
+
+	stdout, stderr := io.Writer(os.Stdout), io.Writer(os.Stderr)
+	if d.logFile != "" {
+		if err := os.MkdirAll(filepath.Dir(d.logFile), 0700); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(d.logFile, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		stdout, stderr = f, f
 	}
 
 	// Loop back to gptscript to help with process supervision
@@ -157,75 +543,141 @@ func (e *Engine) startDaemon(tool types.Tool) (string, error) {
 	cmd.Path = system.Bin()
 
 	cmd.Stdin = r
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
+	cmd.Stderr = stderr
+	cmd.Stdout = stdout
 	cmd.Cancel = func() error {
 		_ = r.Close()
 		return w.Close()
 	}
 
-	log.Infof("launched [%s][%s] port [%d] %v", tool.Parameters.Name, tool.ID, port, cmd.Args)
-	if err := cmd.Start(); err != nil {
-		stop()
-		return url, err
+	if d.socket != "" {
+		log.Infof("launched [%s][%s] socket [%s] %v", d.tool.Parameters.Name, d.tool.ID, d.socket, cmd.Args)
+	} else {
+		log.Infof("launched [%s][%s] port [%d] %v", d.tool.Parameters.Name, d.tool.ID, d.port, cmd.Args)
 	}
-
-	if ports.daemonPorts == nil {
-		ports.daemonPorts = map[string]int64{}
-		ports.daemonsRunning = map[string]struct{}{}
+	if err := cmd.Start(); err != nil {
+		return err
 	}
-	ports.daemonPorts[tool.ID] = port
-	ports.daemonsRunning[url] = struct{}{}
 
-	killedCtx, cancel := context.WithCancelCause(ctx)
-	defer cancel(nil)
+	ports.daemonLock.Lock()
+	d.pid = cmd.Process.Pid
+	d.started = time.Now()
+	ports.daemonLock.Unlock()
 
-	ports.daemonWG.Add(1)
+	exited := make(chan error, 1)
 	go func() {
 		err := cmd.Wait()
 		if err != nil {
-			log.Debugf("daemon exited tool [%s] %v: %v", tool.Parameters.Name, cmd.Args, err)
+			log.Debugf("daemon exited tool [%s] %v: %v", d.tool.Parameters.Name, cmd.Args, err)
 		}
-		_ = r.Close()
-		_ = w.Close()
+		exited <- err
+	}()
 
+	if err := d.waitForHealthy(exited); err != nil {
 		cancel(err)
-		stop()
-		ports.daemonLock.Lock()
-		defer ports.daemonLock.Unlock()
+		<-exited
+		return err
+	}
 
-		delete(ports.daemonPorts, tool.ID)
-		delete(ports.daemonsRunning, url)
-		ports.daemonWG.Done()
-	}()
+	ports.daemonLock.Lock()
+	d.setStatus(daemon.StatusRunning, nil)
+	ports.daemonLock.Unlock()
+	onHealthy()
+
+	if d.opts.HealthInterval == 0 {
+		return <-exited
+	}
+
+	var failures int
+	ticker := time.NewTicker(d.opts.HealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-exited:
+			return err
+		case <-ticker.C:
+			if err := d.checkHealth(min(d.opts.HealthInterval, 10*time.Second)); err == nil {
+				failures = 0
+				continue
+			} else if failures++; failures < healthFailureThreshold {
+				log.Debugf("daemon [%s] health check failed (%d/%d): %v", d.tool.Parameters.Name, failures, healthFailureThreshold, err)
+				continue
+			} else {
+				err = fmt.Errorf("daemon [%s] failed %d health checks: %w", d.tool.Parameters.Name, failures, err)
+				cancel(err)
+				<-exited
+				return err
+			}
+		}
+	}
+}
+
+func (d *daemonProcess) healthURL() string {
+	return "http://" + d.host + d.opts.HealthPath
+}
 
+func (d *daemonProcess) waitForHealthy(exited <-chan error) error {
 	for i := 0; i < 120; i++ {
-		resp, err := http.Get(url)
+		resp, err := DaemonClient.Get(d.healthURL())
 		if err == nil && resp.StatusCode == http.StatusOK {
 			go func() {
 				_, _ = io.ReadAll(resp.Body)
 				_ = resp.Body.Close()
 			}()
-			return url, nil
+			return nil
+		} else if err == nil {
+			_ = resp.Body.Close()
 		}
 		select {
-		case <-killedCtx.Done():
-			return url, fmt.Errorf("daemon failed to start: %w", context.Cause(killedCtx))
+		case err := <-exited:
+			if err == nil {
+				err = errors.New("exited before becoming healthy")
+			}
+			return fmt.Errorf("daemon failed to start: %w", err)
 		case <-time.After(time.Second):
 		}
 	}
 
-	return url, fmt.Errorf("timeout waiting for 200 response from GET %s", url)
+	return fmt.Errorf("timeout waiting for 200 response from GET %s", d.healthURL())
+}
+
+func (d *daemonProcess) checkHealth(timeout time.Duration) error {
+	client := http.Client{
+		Transport: DaemonTransport,
+		Timeout:   timeout,
+	}
+	resp, err := client.Get(d.healthURL())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status from GET %s: %s", d.healthURL(), resp.Status)
+	}
+	return nil
 }
 
 func (e *Engine) runDaemon(ctx context.Context, prg *types.Program, tool types.Tool, input string) (cmdRet *Return, cmdErr error) {
-	url, err := e.startDaemon(tool)
+	url, err := e.startDaemon(ctx, tool)
 	if err != nil {
 		return nil, err
 	}
 
-	tool.Instructions = strings.Join(append([]string{
-		types.CommandPrefix + url,
-	}, strings.Split(tool.Instructions, "\n")[1:]...), "\n")
+	// The rest of the instructions are the daemon program, not HTTP options, so only keep the URL
+	tool.Instructions = types.CommandPrefix + url
 	return e.runHTTP(ctx, prg, tool, input)
 }
+
+// restartDelay returns how long to wait before restarting a daemon that was restarted the number of times. The delay
+// doubles with each restart, up to maxRestartBackoff, so it can't overflow however many restarts are allowed.
+func restartDelay(restarts int) time.Duration {
+	backoff := restartBackoff
+	for range restarts {
+		if backoff >= maxRestartBackoff {
+			break
+		}
+		backoff *= 2
+	}
+	return min(backoff, maxRestartBackoff)
+}
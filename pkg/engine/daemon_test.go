@@ -0,0 +1,305 @@
+package engine
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gptscript-ai/gptscript/pkg/daemon"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+// testDaemonEnv selects how the test binary behaves when it is started as a daemon:
+//   - serve: stay healthy until stopped
+//   - crash: exit with an error shortly after the first health check
+//   - unhealthy: fail every health check after the first
+const testDaemonEnv = "GPTSCRIPT_TEST_DAEMON"
+
+func TestMain(m *testing.M) {
+	if len(os.Args) > 2 && os.Args[1] == "sys.daemon" {
+		if err := daemon.SysDaemon(); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if mode := os.Getenv(testDaemonEnv); mode != "" {
+		serveTestDaemon(mode)
+	}
+	os.Exit(m.Run())
+}
+
+func serveTestDaemon(mode string) {
+	var (
+		l   net.Listener
+		err error
+	)
+	if socket := os.Getenv("GPTSCRIPT_SOCKET"); socket != "" {
+		l, err = net.Listen("unix", socket)
+	} else {
+		l, err = net.Listen("tcp", "127.0.0.1:"+os.Getenv("PORT"))
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+
+	var checks atomic.Int32
+	_ = http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := checks.Add(1)
+		switch {
+		case mode == "crash" && n == 1:
+			time.AfterFunc(100*time.Millisecond, func() {
+				os.Exit(1)
+			})
+		case mode == "unhealthy" && n > 1:
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	os.Exit(1)
+}
+
+func setupTestDaemons(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	ports.daemonLock.Lock()
+	ports.daemonDir = dir
+	ports.daemonLock.Unlock()
+
+	backoff := restartBackoff
+	restartBackoff = 10 * time.Millisecond
+
+	t.Cleanup(func() {
+		CloseDaemons()
+		ports.daemonLock.Lock()
+		ports.daemonDir = ""
+		ports.daemonLock.Unlock()
+		restartBackoff = backoff
+	})
+	return dir
+}
+
+func testDaemonEngine(mode string) *Engine {
+	return &Engine{
+		Env: []string{testDaemonEnv + "=" + mode},
+	}
+}
+
+func testDaemonTool(name, options string) types.Tool {
+	return types.Tool{
+		ID: name,
+		ToolDef: types.ToolDef{
+			Parameters: types.Parameters{
+				Name: name,
+			},
+			Instructions: types.DaemonPrefix + " " + options + " " + os.Args[0],
+		},
+	}
+}
+
+func findDaemon(toolID string) (daemon.Info, bool) {
+	for _, info := range ListDaemons() {
+		if info.ToolID == toolID {
+			return info, true
+		}
+	}
+	return daemon.Info{}, false
+}
+
+func waitForDaemonStatus(t *testing.T, toolID string, status daemon.Status) daemon.Info {
+	t.Helper()
+
+	var info daemon.Info
+	require.Eventually(t, func() bool {
+		var ok bool
+		info, ok = findDaemon(toolID)
+		return ok && info.Status == status
+	}, 20*time.Second, 20*time.Millisecond, "daemon %s never reached status %s", toolID, status)
+	return info
+}
+
+func TestDaemonRestartOnFailure(t *testing.T) {
+	dir := setupTestDaemons(t)
+	tool := testDaemonTool("restart-on-failure", "(health=/healthz, restart=on-failure, retries=2)")
+	restartBackoff = 200 * time.Millisecond
+
+	start := time.Now()
+	url, err := testDaemonEngine("crash").startDaemon(context.Background(), tool)
+	require.NoError(t, err)
+
+	info := waitForDaemonStatus(t, tool.ID, daemon.StatusFailed)
+	require.Equal(t, 2, info.Restarts)
+	// The backoff doubles with each restart: 200ms then 400ms
+	require.GreaterOrEqual(t, time.Since(start), 600*time.Millisecond)
+	require.Contains(t, info.Error, "exceeded 2 restarts")
+	require.False(t, IsDaemonRunning(url))
+
+	// The state file is what `gptscript daemons` reads
+	states, err := daemon.List(dir)
+	require.NoError(t, err)
+	require.Len(t, states, 1)
+	require.Equal(t, daemon.StatusFailed, states[0].Status)
+	require.Equal(t, 2, states[0].Restarts)
+	require.Equal(t, os.Getpid(), states[0].SupervisorPID)
+	require.FileExists(t, states[0].LogFile)
+}
+
+func TestDaemonRestartNever(t *testing.T) {
+	setupTestDaemons(t)
+	tool := testDaemonTool("restart-never", "(health=/healthz, restart=never)")
+
+	_, err := testDaemonEngine("crash").startDaemon(context.Background(), tool)
+	require.NoError(t, err)
+
+	info := waitForDaemonStatus(t, tool.ID, daemon.StatusExited)
+	require.Equal(t, 0, info.Restarts)
+
+	// Calls after the daemon exited fail instead of starting it again
+	_, err = testDaemonEngine("serve").startDaemon(context.Background(), tool)
+	require.ErrorContains(t, err, "restart policy is never")
+}
+
+func TestDaemonRestartOnDemand(t *testing.T) {
+	setupTestDaemons(t)
+	tool := testDaemonTool("restart-on-demand", "(health=/healthz)")
+
+	_, err := testDaemonEngine("crash").startDaemon(context.Background(), tool)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		_, ok := findDaemon(tool.ID)
+		return !ok
+	}, 10*time.Second, 20*time.Millisecond)
+
+	url, err := testDaemonEngine("serve").startDaemon(context.Background(), tool)
+	require.NoError(t, err)
+	require.True(t, IsDaemonRunning(url))
+
+	info := waitForDaemonStatus(t, tool.ID, daemon.StatusRunning)
+	require.Equal(t, 1, info.Restarts)
+}
+
+func TestDaemonHealthCheckRestart(t *testing.T) {
+	setupTestDaemons(t)
+	tool := testDaemonTool("health-check", "(health=/healthz, interval=50ms, restart=on-failure, retries=1)")
+
+	_, err := testDaemonEngine("unhealthy").startDaemon(context.Background(), tool)
+	require.NoError(t, err)
+
+	info := waitForDaemonStatus(t, tool.ID, daemon.StatusFailed)
+	require.Equal(t, 1, info.Restarts)
+	require.Contains(t, info.Error, "failed 3 health checks")
+}
+
+func TestDaemonSocketCleanup(t *testing.T) {
+	dir := setupTestDaemons(t)
+	tool := testDaemonTool("socket", "(health=/healthz, socket=true)")
+
+	url, err := testDaemonEngine("serve").startDaemon(context.Background(), tool)
+	require.NoError(t, err)
+
+	resp, err := DaemonClient.Get(url + "/healthz")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	info, ok := findDaemon(tool.ID)
+	require.True(t, ok)
+	require.FileExists(t, info.Socket)
+
+	CloseDaemons()
+
+	require.NoFileExists(t, info.Socket)
+	require.NoDirExists(t, filepath.Dir(info.Socket))
+	require.False(t, IsDaemonRunning(url))
+
+	states, err := daemon.List(dir)
+	require.NoError(t, err)
+	require.Empty(t, states)
+}
+
+func TestGetDaemonOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		instructions string
+		command      string
+		opts         daemonOptions
+		err          bool
+	}{
+		{
+			name:         "no options",
+			instructions: "node server.js",
+			command:      "node server.js",
+			opts:         daemonOptions{Restart: RestartOnDemand, MaxRestarts: defaultMaxRestarts},
+		},
+		{
+			name:         "path only",
+			instructions: "(path=/api/ready) node server.js",
+			command:      "node server.js",
+			opts:         daemonOptions{Path: "/api/ready", HealthPath: "/api/ready", Restart: RestartOnDemand, MaxRestarts: defaultMaxRestarts},
+		},
+		{
+			name:         "all options",
+			instructions: "(path=/api, health = /healthz, interval=10s, restart=on-failure, retries=2) node server.js",
+			command:      "node server.js",
+			opts: daemonOptions{
+				Path:           "/api",
+				HealthPath:     "/healthz",
+				HealthInterval: 10 * time.Second,
+				Restart:        RestartOnFailure,
+				MaxRestarts:    2,
+			},
+		},
+		{
+			name:         "socket",
+			instructions: "(socket=true) node server.js",
+			command:      "node server.js",
+			opts:         daemonOptions{Socket: true, Restart: RestartOnDemand, MaxRestarts: defaultMaxRestarts},
+		},
+		{
+			name:         "unknown option is part of the command",
+			instructions: "(foo=bar) node server.js",
+			command:      "(foo=bar) node server.js",
+			opts:         daemonOptions{Restart: RestartOnDemand, MaxRestarts: defaultMaxRestarts},
+		},
+		{
+			name:         "invalid restart policy",
+			instructions: "(restart=always) node server.js",
+			err:          true,
+		},
+		{
+			name:         "invalid interval",
+			instructions: "(interval=often) node server.js",
+			err:          true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, opts, err := getDaemonOptions(tt.instructions)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.command, command)
+			require.Equal(t, tt.opts, opts)
+		})
+	}
+}
+
+func TestRestartDelay(t *testing.T) {
+	require.Equal(t, restartBackoff, restartDelay(0))
+	require.Equal(t, 4*restartBackoff, restartDelay(2))
+	// The delay doesn't overflow with many restarts.
+	for _, restarts := range []int{5, 34, 64, 1000} {
+		require.Equal(t, maxRestartBackoff, restartDelay(restarts))
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/gptscript-ai/gptscript/pkg/cache"
 	"github.com/gptscript-ai/gptscript/pkg/config"
 	gcontext "github.com/gptscript-ai/gptscript/pkg/context"
 	"github.com/gptscript-ai/gptscript/pkg/counter"
+	"github.com/gptscript-ai/gptscript/pkg/redact"
 	"github.com/gptscript-ai/gptscript/pkg/types"
 	"github.com/gptscript-ai/gptscript/pkg/version"
 )
@@ -30,6 +32,17 @@ type Engine struct {
 	RuntimeManager RuntimeManager
 	Env            []string
 	Progress       chan<- types.CompletionStatus
+	// CommandExited, if set, is called with the exit code of the command of a command tool after it runs.
+	CommandExited func(exitCode int)
+	// Cache stores the results of the command, HTTP, and OpenAPI tools that ask for their results to be cached.
+	Cache *cache.Client
+	// ResultCached, if set, is called when the result of a call comes from the cache instead of running the tool.
+	ResultCached func()
+	// Redactor has the secrets of the run. Results that contain them aren't cached, so that they aren't written to disk.
+	Redactor *redact.Redactor
+	// BeforeCompletion, if set, is called with each request before it is sent to the LLM. Returning an error stops the
+	// call.
+	BeforeCompletion func(completion types.CompletionRequest) error
 }
 
 type State struct {
@@ -265,6 +278,13 @@ func populateMessageParams(ctx Context, completion *types.CompletionRequest, too
 	completion.Cache = tool.Parameters.Cache
 	completion.Chat = tool.Parameters.Chat
 	completion.Temperature = tool.Parameters.Temperature
+	completion.TopP = tool.Parameters.TopP
+	completion.Stop = tool.Parameters.Stop
+	completion.Seed = tool.Parameters.Seed
+	completion.PresencePenalty = tool.Parameters.PresencePenalty
+	completion.FrequencyPenalty = tool.Parameters.FrequencyPenalty
+	completion.ReasoningEffort = tool.Parameters.ReasoningEffort
+	completion.ParallelToolCalls = tool.Parameters.ParallelToolCalls
 	completion.InternalSystemPrompt = tool.Parameters.InternalPrompt
 
 	if tool.Chat && completion.InternalSystemPrompt == nil {
@@ -292,13 +312,21 @@ func (e *Engine) Start(ctx Context, input string) (ret *Return, _ error) {
 
 	if tool.IsCommand() {
 		if tool.IsHTTP() {
-			return e.runHTTP(ctx.Ctx, ctx.Program, tool, input)
+			return e.withResultCache(ctx.Ctx, tool, input, func() (*Return, error) {
+				return e.runHTTP(ctx.Ctx, ctx.Program, tool, input)
+			})
 		} else if tool.IsDaemon() {
 			return e.runDaemon(ctx.Ctx, ctx.Program, tool, input)
 		} else if tool.IsOpenAPI() {
-			return e.runOpenAPI(tool, input)
+			return e.withResultCache(ctx.Ctx, tool, input, func() (*Return, error) {
+				return e.runOpenAPI(tool, input)
+			})
 		} else if tool.IsEcho() {
 			return e.runEcho(tool)
+		} else if tool.IsMCPInvoke() {
+			return e.runMCP(ctx.Ctx, tool, input)
+		} else if tool.IsMCP() {
+			return nil, fmt.Errorf("MCP server tool [%s] can not be called directly, it must be loaded from a file", tool.Parameters.Name)
 		}
 		s, err := e.runCommand(ctx, tool, input, ctx.ToolCategory)
 		if err != nil {
@@ -329,7 +357,7 @@ func (e *Engine) Start(ctx Context, input string) (ret *Return, _ error) {
 		})
 	}
 
-	return e.complete(ctx.Ctx, &State{
+	return e.complete(ctx.WrappedContext(e), &State{
 		Completion: completion,
 	})
 }
@@ -385,6 +413,12 @@ func (e *Engine) complete(ctx context.Context, state *State) (*Return, error) {
 		}
 	}()
 
+	if e.BeforeCompletion != nil {
+		if err := e.BeforeCompletion(state.Completion); err != nil {
+			return nil, err
+		}
+	}
+
 	resp, err := e.Model.Call(gcontext.WithEnv(ctx, e.Env), state.Completion, progress)
 	if err != nil {
 		return nil, err
@@ -507,5 +541,5 @@ func (e *Engine) Continue(ctx Context, state *State, results ...CallResult) (*Re
 		return nil, err
 	}
 
-	return e.complete(ctx.Ctx, state)
+	return e.complete(ctx.WrappedContext(e), state)
 }
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/gptscript-ai/gptscript/pkg/types"
@@ -33,7 +34,8 @@ func (e *Engine) runHTTP(ctx context.Context, prg *types.Program, tool types.Too
 		return nil, err
 	}
 
-	if strings.HasSuffix(parsed.Hostname(), DaemonURLSuffix) {
+	// The URL of a daemon listening on a socket already points to the running daemon and needs no lookup
+	if _, isSocket := socketForHost(parsed.Hostname()); !isSocket && strings.HasSuffix(parsed.Hostname(), DaemonURLSuffix) {
 		referencedToolName := strings.TrimSuffix(parsed.Hostname(), DaemonURLSuffix)
 		referencedToolRefs, ok := tool.ToolMapping[referencedToolName]
 		if !ok || len(referencedToolRefs) != 1 {
@@ -43,7 +45,7 @@ func (e *Engine) runHTTP(ctx context.Context, prg *types.Program, tool types.Too
 		if !ok {
 			return nil, fmt.Errorf("failed to find tool [%s] for [%s]", referencedToolName, parsed.Hostname())
 		}
-		toolURL, err = e.startDaemon(referencedTool)
+		toolURL, err = e.startDaemon(ctx, referencedTool)
 		if err != nil {
 			return nil, err
 		}
@@ -61,35 +63,89 @@ func (e *Engine) runHTTP(ctx context.Context, prg *types.Program, tool types.Too
 		}, nil
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, toolURL, strings.NewReader(input))
+	opts, err := getHTTPOptions(strings.Split(tool.Instructions, "\n")[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTP tool [%s]: %w", tool.Parameters.Name, err)
+	}
+
+	args := map[string]any{}
+	_ = json.Unmarshal([]byte(input), &args)
+
+	if len(opts.Query) > 0 {
+		query := parsed.Query()
+		for _, q := range opts.Query {
+			if value := expandArgs(q.Value, args, envMap, false); value != "" {
+				query.Add(q.Name, value)
+			}
+		}
+		parsed.RawQuery = query.Encode()
+		toolURL = parsed.String()
+	}
+
+	headers := http.Header{}
+	for _, h := range opts.Headers {
+		headers.Add(h.Name, os.Expand(h.Value, func(s string) string {
+			return envMap[s]
+		}))
+	}
+
+	var body io.Reader
+	if opts.Body != nil {
+		isJSON := strings.Contains(headers.Get("Content-Type"), "json") ||
+			(headers.Get("Content-Type") == "" && (strings.HasPrefix(*opts.Body, "{") || strings.HasPrefix(*opts.Body, "[")))
+		input = expandArgs(*opts.Body, args, envMap, isJSON)
+		body = strings.NewReader(input)
+	} else if opts.Method != http.MethodGet && opts.Method != http.MethodHead {
+		body = strings.NewReader(input)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, opts.Method, toolURL, body)
 	if err != nil {
 		return nil, err
 	}
 
+	req.Header = headers
 	req.Header.Set("X-GPTScript-Tool-Name", tool.Parameters.Name)
 
-	if err := json.Unmarshal([]byte(input), &map[string]any{}); err == nil {
-		req.Header.Set("Content-Type", "application/json")
-	} else {
-		req.Header.Set("Content-Type", "text/plain")
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		if err := json.Unmarshal([]byte(input), &map[string]any{}); err == nil {
+			req.Header.Set("Content-Type", "application/json")
+		} else {
+			req.Header.Set("Content-Type", "text/plain")
+		}
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := DaemonClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode > 299 {
-		_, _ = io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("error in request to [%s] [%d]: %s", toolURL, resp.StatusCode, resp.Status)
-	}
-
 	content, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
+	if resp.StatusCode > 299 {
+		// Report the failure to the LLM instead of failing the run. The query is left out of the URL because it may
+		// contain credentials.
+		parsed.RawQuery = ""
+		s := fmt.Sprintf("ERROR: %s request to [%s] failed with status %s: %s", opts.Method, parsed.String(), resp.Status, content)
+		return &Return{
+			Result: &s,
+		}, nil
+	}
+
+	if opts.Extract != "" {
+		s, err := extractJSONPointer(content, opts.Extract)
+		if err != nil {
+			s = fmt.Sprintf("ERROR: failed to extract [%s] from response: %v", opts.Extract, err)
+		}
+		return &Return{
+			Result: &s,
+		}, nil
+	}
+
 	if resp.Header.Get("Content-Type") == "application/json" && strings.HasPrefix(string(content), "\"") {
 		// This is dumb hack when something returns a string in JSON format, just decode it to a string
 		var s string
@@ -105,3 +161,140 @@ func (e *Engine) runHTTP(ctx context.Context, prg *types.Program, tool types.Too
 		Result: &s,
 	}, nil
 }
+
+type nameValue struct {
+	Name  string
+	Value string
+}
+
+type httpOptions struct {
+	Method  string
+	Headers []nameValue
+	Query   []nameValue
+	Body    *string
+	Extract string
+}
+
+// getHTTPOptions parses the lines that follow the URL of an HTTP tool. Each option is a line in the form "Key: value"
+// where the key is one of method, header, query, body, or extract. The body option must be last and includes all the
+// lines that follow it. All other lines are ignored so that the text that HTTP tools had after the URL before these
+// options existed still works.
+func getHTTPOptions(lines []string) (httpOptions, error) {
+	opts := httpOptions{
+		Method: http.MethodPost,
+	}
+
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "method":
+			if value == "" || strings.ContainsAny(value, " \t") {
+				return opts, fmt.Errorf("invalid method [%s]", value)
+			}
+			opts.Method = strings.ToUpper(value)
+		case "header":
+			name, headerValue, ok := strings.Cut(value, ":")
+			if !ok || strings.TrimSpace(name) == "" {
+				return opts, fmt.Errorf("invalid header [%s], expected \"header: name: value\"", value)
+			}
+			opts.Headers = append(opts.Headers, nameValue{
+				Name:  strings.TrimSpace(name),
+				Value: strings.TrimSpace(headerValue),
+			})
+		case "query":
+			name, queryValue, ok := strings.Cut(value, "=")
+			if !ok {
+				// "query: name" sends the argument with the same name
+				queryValue = "${" + strings.TrimSpace(name) + "}"
+			}
+			if strings.TrimSpace(name) == "" {
+				return opts, fmt.Errorf("invalid query [%s], expected \"query: name=value\"", value)
+			}
+			opts.Query = append(opts.Query, nameValue{
+				Name:  strings.TrimSpace(name),
+				Value: strings.TrimSpace(queryValue),
+			})
+		case "body":
+			body := strings.TrimSpace(strings.Join(append([]string{value}, lines[i+1:]...), "\n"))
+			opts.Body = &body
+			return opts, nil
+		case "extract":
+			if value != "" && !strings.HasPrefix(value, "/") {
+				return opts, fmt.Errorf("invalid extract [%s], expected a JSON pointer such as /data/items", value)
+			}
+			opts.Extract = value
+		}
+	}
+
+	return opts, nil
+}
+
+// expandArgs replaces ${name} in s with the argument of the same name, or the environment variable if there is no such
+// argument. If escapeJSON is set, string values are escaped so that they can be placed in a JSON string.
+func expandArgs(s string, args map[string]any, envMap map[string]string, escapeJSON bool) string {
+	return os.Expand(s, func(key string) string {
+		v, ok := args[key]
+		if !ok {
+			return envMap[key]
+		}
+		switch v := v.(type) {
+		case nil:
+			return ""
+		case string:
+			if escapeJSON {
+				data, _ := json.Marshal(v)
+				return string(data[1 : len(data)-1])
+			}
+			return v
+		default:
+			data, _ := json.Marshal(v)
+			return string(data)
+		}
+	})
+}
+
+// extractJSONPointer returns the value at the JSON pointer (RFC 6901) in content. String values are returned as is,
+// all other values are returned as JSON.
+func extractJSONPointer(content []byte, pointer string) (string, error) {
+	var value any
+	if err := json.Unmarshal(content, &value); err != nil {
+		return "", fmt.Errorf("response is not JSON: %w", err)
+	}
+
+	if pointer != "" {
+		for _, token := range strings.Split(pointer[1:], "/") {
+			token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+			switch v := value.(type) {
+			case map[string]any:
+				var ok bool
+				if value, ok = v[token]; !ok {
+					return "", fmt.Errorf("key [%s] not found", token)
+				}
+			case []any:
+				i, err := strconv.Atoi(token)
+				if err != nil || i < 0 || i >= len(v) {
+					return "", fmt.Errorf("invalid index [%s] for array of length %d", token, len(v))
+				}
+				value = v[i]
+			default:
+				return "", fmt.Errorf("cannot look up [%s] in a value that is not an object or array", token)
+			}
+		}
+	}
+
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetHTTPOptions(t *testing.T) {
+	opts, err := getHTTPOptions([]string{
+		"method: get",
+		"header: Authorization: Bearer ${TOKEN}",
+		"# comments are ignored",
+		"query: q=${query}",
+		"query: limit",
+		"extract: /items/0",
+	})
+	require.NoError(t, err)
+	require.Equal(t, httpOptions{
+		Method:  http.MethodGet,
+		Headers: []nameValue{{Name: "Authorization", Value: "Bearer ${TOKEN}"}},
+		Query:   []nameValue{{Name: "q", Value: "${query}"}, {Name: "limit", Value: "${limit}"}},
+		Extract: "/items/0",
+	}, opts)
+
+	opts, err = getHTTPOptions([]string{"Body: {", `  "name": "${name}"`, "}"})
+	require.NoError(t, err)
+	require.Equal(t, http.MethodPost, opts.Method)
+	require.Equal(t, "{\n  \"name\": \"${name}\"\n}", *opts.Body)
+
+	opts, err = getHTTPOptions([]string{"This tool posts its input to the API.", "Note: the API is slow", "timeout: 10s"})
+	require.NoError(t, err)
+	require.Equal(t, httpOptions{Method: http.MethodPost}, opts)
+
+	_, err = getHTTPOptions([]string{"method: GET POST"})
+	require.Error(t, err)
+	_, err = getHTTPOptions([]string{"extract: items"})
+	require.Error(t, err)
+}
+
+func TestRunHTTP(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/search":
+			if r.Method != http.MethodGet || r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte("bad credentials"))
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"items": [{"q": "` + r.URL.Query().Get("q") + `", "limit": "` + r.URL.Query().Get("limit") + `"}]}`))
+		case "/create":
+			_, _ = w.Write([]byte(r.Header.Get("Content-Type") + " " + string(body)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	e := &Engine{
+		Env: []string{"TOKEN=secret"},
+	}
+
+	run := func(instructions, input string) string {
+		t.Helper()
+		ret, err := e.runHTTP(context.Background(), nil, types.Tool{
+			ToolDef: types.ToolDef{
+				Instructions: instructions,
+			},
+		}, input)
+		require.NoError(t, err)
+		return *ret.Result
+	}
+
+	require.Equal(t, `{"limit":"5","q":"a b"}`, run("#!"+s.URL+"/search\n"+
+		"method: GET\n"+
+		"header: Authorization: Bearer ${TOKEN}\n"+
+		"query: q=${query}\n"+
+		"query: limit\n"+
+		"query: unset\n"+
+		"extract: /items/0", `{"query": "a b", "limit": 5}`))
+
+	require.Equal(t, `application/json {"name": "say \"hi\"", "count": 2}`, run("#!"+s.URL+"/create\n"+
+		`body: {"name": "${name}", "count": ${count}}`, `{"name": "say \"hi\"", "count": 2}`))
+
+	// HTTP tools written before the options existed have text after the URL that is not sent
+	require.Equal(t, `application/json {"name": "a"}`, run("#!"+s.URL+"/create\n"+
+		"Creates a thing with the given name.\n"+
+		"Returns: the content type and body of the request", `{"name": "a"}`))
+
+	require.Equal(t, "ERROR: GET request to ["+s.URL+"/search] failed with status 401 Unauthorized: bad credentials",
+		run("#!"+s.URL+"/search?token=${TOKEN}x\nmethod: GET", "{}"))
+}
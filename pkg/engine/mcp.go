@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gptscript-ai/gptscript/pkg/hash"
+	"github.com/gptscript-ai/gptscript/pkg/mcp"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+)
+
+var mcpSessions mcpSessionSet
+
+type mcpSessionSet struct {
+	lock     sync.Mutex
+	sessions map[string]*mcpSession
+}
+
+type mcpSession struct {
+	once   sync.Once
+	client *mcp.Client
+	err    error
+}
+
+// CloseMCPSessions disconnects from all the MCP servers used by tools, stopping the servers that were started for them.
+func CloseMCPSessions() {
+	mcpSessions.lock.Lock()
+	sessions := mcpSessions.sessions
+	mcpSessions.sessions = nil
+	mcpSessions.lock.Unlock()
+
+	for _, session := range sessions {
+		session.once.Do(func() {})
+		if session.client != nil {
+			_ = session.client.Close()
+		}
+	}
+}
+
+// get returns the session for the server, connecting to it if this is the first call. Sessions are shared by all
+// tools of the same server and live until CloseMCPSessions is called.
+func (m *mcpSessionSet) get(ctx context.Context, definition, dir string, env []string) (string, *mcp.Client, error) {
+	key := hash.ID(definition, dir)
+
+	m.lock.Lock()
+	if m.sessions == nil {
+		m.sessions = map[string]*mcpSession{}
+	}
+	session, ok := m.sessions[key]
+	if !ok {
+		session = &mcpSession{}
+		m.sessions[key] = session
+	}
+	m.lock.Unlock()
+
+	session.once.Do(func() {
+		var config mcp.Config
+		config, session.err = mcp.ParseConfig(definition, dir, env)
+		if session.err != nil {
+			return
+		}
+		// The server outlives this call, so don't use a context that is canceled when the call is done.
+		session.client, session.err = mcp.Connect(context.WithoutCancel(ctx), config)
+		if session.err == nil {
+			log.Infof("connected to MCP server [%s] %s", config, session.client.ServerInfo.Name)
+		}
+	})
+
+	if session.err != nil {
+		m.remove(key, nil)
+	}
+	return key, session.client, session.err
+}
+
+// remove forgets the session for key if it is still using client, so that the next call connects again.
+func (m *mcpSessionSet) remove(key string, client *mcp.Client) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if session, ok := m.sessions[key]; ok && session.client == client {
+		delete(m.sessions, key)
+	}
+}
+
+// withMCPSession calls fn with the session for the server, connecting again and retrying once if the server exited
+// since the last call.
+func (e *Engine) withMCPSession(ctx context.Context, definition, dir string, fn func(client *mcp.Client) error) error {
+	var err error
+	for i := 0; i < 2; i++ {
+		var (
+			key    string
+			client *mcp.Client
+		)
+		key, client, err = mcpSessions.get(ctx, definition, dir, e.Env)
+		if err != nil {
+			return err
+		}
+
+		err = fn(client)
+		if !errors.Is(err, mcp.ErrClosed) {
+			break
+		}
+		mcpSessions.remove(key, client)
+		_ = client.Close()
+	}
+	return err
+}
+
+func (e *Engine) runMCP(ctx context.Context, tool types.Tool, input string) (*Return, error) {
+	line, definition, _ := strings.Cut(tool.Instructions, "\n")
+	name := strings.TrimSpace(strings.TrimPrefix(line, types.MCPInvokePrefix))
+	definition = strings.TrimPrefix(strings.TrimSpace(definition), types.MCPPrefix)
+
+	if strings.TrimSpace(input) == "" {
+		input = "{}"
+	}
+	if !json.Valid([]byte(input)) {
+		return nil, fmt.Errorf("invalid input for MCP tool %s, expected a JSON object", name)
+	}
+
+	var result mcp.CallToolResult
+	if err := e.withMCPSession(ctx, definition, tool.WorkingDir, func(client *mcp.Client) (err error) {
+		result, err = client.CallTool(ctx, name, json.RawMessage(input))
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to call MCP tool %s: %w", name, err)
+	}
+
+	s := result.String()
+	if result.IsError {
+		s = "ERROR: " + s
+	}
+	return &Return{
+		Result: &s,
+	}, nil
+}
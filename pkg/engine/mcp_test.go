@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gptscript-ai/gptscript/pkg/mcp/mcptest"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunMCP(t *testing.T) {
+	s := httptest.NewServer(mcptest.NewServer())
+	defer s.Close()
+	t.Cleanup(CloseMCPSessions)
+
+	e := &Engine{}
+	run := func(name, input string) string {
+		t.Helper()
+		ret, err := e.runMCP(context.Background(), types.Tool{
+			ToolDef: types.ToolDef{
+				Instructions: types.MCPInvokePrefix + " " + name + "\n" + types.MCPPrefix + " " + s.URL,
+			},
+		}, input)
+		require.NoError(t, err)
+		return *ret.Result
+	}
+
+	require.Equal(t, "hi", run("echo", `{"message": "hi"}`))
+	require.Equal(t, "5", run("add", `{"a": 2, "b": 3}`))
+	require.Equal(t, "ERROR: this tool always fails", run("fail", ""))
+	require.Len(t, mcpSessions.sessions, 1)
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gptscript-ai/gptscript/pkg/env"
+	"github.com/gptscript-ai/gptscript/pkg/hash"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+)
+
+// resultCacheKey is what the cached result of a call of a command, HTTP, or OpenAPI tool depends on.
+type resultCacheKey struct {
+	Type     string            `json:"type"`
+	Tool     string            `json:"tool"`
+	Revision string            `json:"revision,omitempty"`
+	Runtime  []string          `json:"runtime,omitempty"`
+	Context  string            `json:"context,omitempty"`
+	Input    string            `json:"input"`
+	Env      map[string]string `json:"env,omitempty"`
+}
+
+type cachedResult struct {
+	Result string
+	Time   time.Time
+}
+
+func (e *Engine) resultCacheKey(tool types.Tool, input, context string, runtime []string) *resultCacheKey {
+	if e.Cache == nil || !tool.CachesResults() {
+		return nil
+	}
+
+	key := &resultCacheKey{
+		Type:    "toolResult",
+		Tool:    hash.Digest(tool.String()),
+		Runtime: runtime,
+		Context: context,
+		Input:   input,
+	}
+	if tool.Source.Repo != nil {
+		key.Revision = tool.Source.Repo.Revision
+	}
+	for _, name := range tool.Parameters.CacheEnv {
+		if key.Env == nil {
+			key.Env = map[string]string{}
+		}
+		key.Env[name] = env.Getenv(name, e.Env)
+	}
+	return key
+}
+
+// cachedResult returns the cached result for the key, if there is one that hasn't expired.
+func (e *Engine) cachedResult(ctx context.Context, tool types.Tool, key *resultCacheKey) (string, bool, error) {
+	if key == nil {
+		return "", false, nil
+	}
+
+	var cached cachedResult
+	if ok, err := e.Cache.Get(ctx, key, &cached); err != nil || !ok {
+		return "", false, err
+	}
+	if ttl := tool.Parameters.CacheTTL; ttl > 0 && time.Since(cached.Time) > ttl {
+		return "", false, nil
+	}
+
+	if e.ResultCached != nil {
+		e.ResultCached()
+	}
+	return cached.Result, true, nil
+}
+
+// storeResult caches the result for the key. Errors, which are returned to the LLM as results that start with
+// "ERROR:", and results that contain secrets of the run aren't cached.
+func (e *Engine) storeResult(ctx context.Context, key *resultCacheKey, result string) error {
+	if key == nil || strings.HasPrefix(result, "ERROR:") {
+		return nil
+	}
+	if e.Redactor.String(result) != result {
+		log.Debugf("Not caching the result of tool %s because it contains a secret", key.Tool)
+		return nil
+	}
+	return e.Cache.Store(ctx, key, cachedResult{
+		Result: result,
+		Time:   time.Now(),
+	})
+}
+
+// withResultCache returns the cached result of the call, or makes the call and caches its result.
+func (e *Engine) withResultCache(ctx context.Context, tool types.Tool, input string, call func() (*Return, error)) (*Return, error) {
+	key := e.resultCacheKey(tool, input, "", nil)
+	if result, ok, err := e.cachedResult(ctx, tool, key); err != nil {
+		return nil, err
+	} else if ok {
+		return &Return{
+			Result: &result,
+		}, nil
+	}
+
+	ret, err := call()
+	if err != nil || ret == nil || ret.Result == nil {
+		return ret, err
+	}
+	return ret, e.storeResult(ctx, key, *ret.Result)
+}
@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gptscript-ai/gptscript/pkg/cache"
+	"github.com/gptscript-ai/gptscript/pkg/redact"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithResultCache(t *testing.T) {
+	c, err := cache.New(cache.Options{CacheDir: t.TempDir()})
+	require.NoError(t, err)
+
+	var (
+		calls  int
+		hits   int
+		result = "result"
+		ctx    = context.Background()
+		e      = &Engine{
+			Cache: c,
+			Env:   []string{"TOKEN=a"},
+			ResultCached: func() {
+				hits++
+			},
+		}
+		call = func() (*Return, error) {
+			calls++
+			r := result
+			return &Return{Result: &r}, nil
+		}
+	)
+
+	tool := types.Tool{
+		ToolDef: types.ToolDef{
+			Parameters: types.Parameters{
+				Name:     "lookup",
+				CacheTTL: time.Hour,
+				CacheEnv: []string{"TOKEN"},
+			},
+			Instructions: "#!http://localhost/lookup",
+		},
+	}
+
+	run := func(tool types.Tool, input string) string {
+		t.Helper()
+		ret, err := e.withResultCache(ctx, tool, input, call)
+		require.NoError(t, err)
+		return *ret.Result
+	}
+
+	require.Equal(t, "result", run(tool, "{}"))
+	require.Equal(t, "result", run(tool, "{}"))
+	require.Equal(t, 1, calls)
+	require.Equal(t, 1, hits)
+
+	// The input, the declared environment variables, the tool, and the revision of its source are part of the key.
+	run(tool, `{"a":1}`)
+	require.Equal(t, 2, calls)
+
+	e.Env = []string{"TOKEN=b"}
+	run(tool, "{}")
+	require.Equal(t, 3, calls)
+
+	changed := tool
+	changed.Instructions = "#!http://localhost/lookup2"
+	run(changed, "{}")
+	require.Equal(t, 4, calls)
+
+	changed = tool
+	changed.Source.Repo = &types.Repo{Revision: "abc"}
+	run(changed, "{}")
+	run(changed, "{}")
+	require.Equal(t, 5, calls)
+	changed.Source.Repo = &types.Repo{Revision: "def"}
+	run(changed, "{}")
+	require.Equal(t, 6, calls)
+
+	// Expired results aren't used.
+	expiring := tool
+	expiring.Parameters.CacheTTL = time.Nanosecond
+	run(expiring, "{}")
+	run(expiring, "{}")
+	require.Equal(t, 8, calls)
+
+	// Errors aren't cached.
+	result = "ERROR: failed"
+	run(tool, "error")
+	run(tool, "error")
+	require.Equal(t, 10, calls)
+
+	// Results with secrets of the run aren't cached.
+	e.Redactor = redact.New()
+	e.Redactor.Add("s3cr3t-token")
+	result = "token: s3cr3t-token"
+	run(tool, "secret")
+	require.Equal(t, "token: s3cr3t-token", run(tool, "secret"))
+	require.Equal(t, 12, calls)
+
+	// Tools that don't ask for caching aren't cached.
+	result = "result"
+	uncached := tool
+	uncached.Parameters.CacheTTL = 0
+	run(uncached, "{}")
+	run(uncached, "{}")
+	require.Equal(t, 14, calls)
+
+	cached := true
+	uncached.Parameters.Cache = &cached
+	run(uncached, "{}")
+	run(uncached, "{}")
+	require.Equal(t, 15, calls)
+
+	// Cached results can only be read by the user.
+	entries, err := os.ReadDir(c.CacheDir())
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		require.NoError(t, err)
+		if info.Mode().IsRegular() {
+			require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	// DaemonTransport is an http.RoundTripper that dials daemons listening on a Unix socket when a request is sent
+	// to their *.daemon.gptscript.local host. All other requests are sent over TCP like http.DefaultTransport.
+	DaemonTransport http.RoundTripper = newDaemonTransport()
+	// DaemonClient is an http.Client that uses DaemonTransport.
+	DaemonClient = &http.Client{
+		Transport: DaemonTransport,
+	}
+
+	sockets socketRegistry
+)
+
+type socketRegistry struct {
+	lock  sync.Mutex
+	dir   string
+	next  int
+	hosts map[string]string
+}
+
+func newDaemonTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		if socket, ok := socketForHost(host); ok {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+	return transport
+}
+
+// socketForHost returns the socket path of the daemon that is reachable with the given host name.
+func socketForHost(host string) (string, bool) {
+	if !strings.HasSuffix(host, DaemonURLSuffix) {
+		return "", false
+	}
+	sockets.lock.Lock()
+	defer sockets.lock.Unlock()
+	socket, ok := sockets.hosts[host]
+	return socket, ok
+}
+
+// nextSocket allocates a socket path and the host name used to reach it. The sockets for a run are created in a
+// private directory that is removed by CloseDaemons.
+func nextSocket(name string) (host, socket string, _ error) {
+	sockets.lock.Lock()
+	defer sockets.lock.Unlock()
+
+	if sockets.dir == "" {
+		// Keep this short, the maximum length of a socket path is around 100 bytes on most platforms.
+		dir, err := os.MkdirTemp("", "gptscript-")
+		if err != nil {
+			return "", "", fmt.Errorf("failed to create daemon socket directory: %w", err)
+		}
+		sockets.dir = dir
+	}
+
+	if sockets.hosts == nil {
+		sockets.hosts = map[string]string{}
+	}
+
+	sockets.next++
+	host = fmt.Sprintf("%s-%d.sock%s", name, sockets.next, DaemonURLSuffix)
+	socket = filepath.Join(sockets.dir, fmt.Sprintf("%d.sock", sockets.next))
+	sockets.hosts[host] = socket
+	return host, socket, nil
+}
+
+func releaseSocket(host string) {
+	sockets.lock.Lock()
+	defer sockets.lock.Unlock()
+	if socket, ok := sockets.hosts[host]; ok {
+		delete(sockets.hosts, host)
+		_ = os.Remove(socket)
+	}
+}
+
+func closeSockets() {
+	sockets.lock.Lock()
+	defer sockets.lock.Unlock()
+	if sockets.dir != "" {
+		_ = os.RemoveAll(sockets.dir)
+		sockets.dir = ""
+	}
+	sockets.hosts = nil
+}
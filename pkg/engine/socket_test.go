@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDaemonClientDialsSocket(t *testing.T) {
+	t.Cleanup(closeSockets)
+
+	host, socket, err := nextSocket("test")
+	require.NoError(t, err)
+
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	s := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(r.URL.Path))
+		}),
+	}
+	go func() {
+		_ = s.Serve(l)
+	}()
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+
+	resp, err := DaemonClient.Get("http://" + host + "/hello")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "/hello", string(body))
+
+	releaseSocket(host)
+	_, ok := socketForHost(host)
+	require.False(t, ok)
+}
@@ -0,0 +1,33 @@
+// Package filelock locks files between processes, so that a file that is read and then written by several gptscript
+// processes at the same time isn't corrupted.
+package filelock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Lock waits until no other process holds the lock of path and takes it. The lock is a separate file, path with a
+// ".lock" suffix, so that path can be replaced while it is locked. The lock is released by calling unlock, or when the
+// process exits.
+func Lock(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create directory for lock of %s: %w", path, err)
+	}
+
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock of %s: %w", path, err)
+	}
+
+	if err := lock(f); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	return func() {
+		_ = unlock(f)
+		_ = f.Close()
+	}, nil
+}
@@ -0,0 +1,105 @@
+package filelock
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testCountEnv is set to the file that the test binary counts in, instead of running the tests.
+const testCountEnv = "GPTSCRIPT_TEST_FILELOCK_COUNT"
+
+const testCount = 50
+
+func TestMain(m *testing.M) {
+	if file := os.Getenv(testCountEnv); file != "" {
+		for range testCount {
+			if err := increment(file); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// increment reads the number in file and writes the next one, which loses counts if it isn't locked.
+func increment(file string) error {
+	unlock, err := Lock(file)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var n int
+	if len(data) > 0 {
+		if n, err = strconv.Atoi(string(data)); err != nil {
+			return err
+		}
+	}
+	time.Sleep(time.Millisecond)
+	return os.WriteFile(file, []byte(strconv.Itoa(n+1)), 0600)
+}
+
+func TestLock(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "dir", "file")
+
+	unlock, err := Lock(file)
+	require.NoError(t, err)
+	require.FileExists(t, file+".lock")
+
+	locked := make(chan func())
+	go func() {
+		unlock, err := Lock(file)
+		if err != nil {
+			t.Error(err)
+		}
+		locked <- unlock
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("the lock was taken while it was held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case unlock := <-locked:
+		unlock()
+	case <-time.After(5 * time.Second):
+		t.Fatal("the lock wasn't taken after it was released")
+	}
+}
+
+func TestLockFromProcesses(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "count")
+
+	var cmds []*exec.Cmd
+	for range 4 {
+		cmd := exec.Command(os.Args[0])
+		cmd.Env = append(os.Environ(), testCountEnv+"="+file)
+		cmd.Stderr = os.Stderr
+		require.NoError(t, cmd.Start())
+		cmds = append(cmds, cmd)
+	}
+	for _, cmd := range cmds {
+		require.NoError(t, cmd.Wait())
+	}
+
+	// No process read the number while another one was changing it.
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, strconv.Itoa(4*testCount), string(data))
+}
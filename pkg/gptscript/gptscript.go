@@ -9,18 +9,22 @@ import (
 	"slices"
 	"strings"
 
+	"github.com/gptscript-ai/gptscript/pkg/audit"
 	"github.com/gptscript-ai/gptscript/pkg/builtin"
 	"github.com/gptscript-ai/gptscript/pkg/cache"
 	"github.com/gptscript-ai/gptscript/pkg/config"
 	context2 "github.com/gptscript-ai/gptscript/pkg/context"
 	"github.com/gptscript-ai/gptscript/pkg/credentials"
+	"github.com/gptscript-ai/gptscript/pkg/daemon"
 	"github.com/gptscript-ai/gptscript/pkg/engine"
 	"github.com/gptscript-ai/gptscript/pkg/hash"
 	"github.com/gptscript-ai/gptscript/pkg/llm"
+	"github.com/gptscript-ai/gptscript/pkg/mock"
 	"github.com/gptscript-ai/gptscript/pkg/monitor"
 	"github.com/gptscript-ai/gptscript/pkg/mvl"
 	"github.com/gptscript-ai/gptscript/pkg/openai"
 	"github.com/gptscript-ai/gptscript/pkg/prompt"
+	"github.com/gptscript-ai/gptscript/pkg/redact"
 	"github.com/gptscript-ai/gptscript/pkg/remote"
 	"github.com/gptscript-ai/gptscript/pkg/repos/runtimes"
 	"github.com/gptscript-ai/gptscript/pkg/runner"
@@ -45,11 +49,21 @@ type Options struct {
 	Monitor              monitor.Options
 	Runner               runner.Options
 	DefaultModelProvider string
-	CredentialContext    string
-	Quiet                *bool
-	Workspace            string
-	DisablePromptServer  bool
-	Env                  []string
+	// CredentialContexts are searched in order for credentials. New credentials are stored in CredentialWriteContext,
+	// or in the first context if it is not set.
+	CredentialContexts     []string
+	CredentialWriteContext string
+	Quiet                  *bool
+	Workspace              string
+	DisablePromptServer    bool
+	DisableRedaction       bool
+	// AuditLog is the file to write the audit log to. The auditLog of the CLI config is used if it is not set.
+	AuditLog string
+	// ModelAliases map names that tools can use as their model to the models that are used for them.
+	ModelAliases map[string]string
+	// Model, if set, is used for the requests to LLMs instead of the registry of model providers.
+	Model engine.Model
+	Env   []string
 }
 
 func Complete(opts ...Options) Options {
@@ -60,12 +74,24 @@ func Complete(opts ...Options) Options {
 		result.Runner = runner.Complete(result.Runner, opt.Runner)
 		result.OpenAI = openai.Complete(result.OpenAI, opt.OpenAI)
 
-		result.CredentialContext = types.FirstSet(opt.CredentialContext, result.CredentialContext)
+		if len(opt.CredentialContexts) > 0 {
+			result.CredentialContexts = opt.CredentialContexts
+		}
+		result.CredentialWriteContext = types.FirstSet(opt.CredentialWriteContext, result.CredentialWriteContext)
 		result.Quiet = types.FirstSet(opt.Quiet, result.Quiet)
 		result.Workspace = types.FirstSet(opt.Workspace, result.Workspace)
 		result.Env = append(result.Env, opt.Env...)
 		result.DisablePromptServer = types.FirstSet(opt.DisablePromptServer, result.DisablePromptServer)
+		result.DisableRedaction = types.FirstSet(opt.DisableRedaction, result.DisableRedaction)
 		result.DefaultModelProvider = types.FirstSet(opt.DefaultModelProvider, result.DefaultModelProvider)
+		result.AuditLog = types.FirstSet(opt.AuditLog, result.AuditLog)
+		result.Model = types.FirstSet(opt.Model, result.Model)
+		for alias, model := range opt.ModelAliases {
+			if result.ModelAliases == nil {
+				result.ModelAliases = map[string]string{}
+			}
+			result.ModelAliases[alias] = model
+		}
 	}
 
 	if result.Quiet == nil {
@@ -74,8 +100,8 @@ func Complete(opts ...Options) Options {
 	if len(result.Env) == 0 {
 		result.Env = os.Environ()
 	}
-	if result.CredentialContext == "" {
-		result.CredentialContext = "default"
+	if len(result.CredentialContexts) == 0 {
+		result.CredentialContexts = []string{"default"}
 	}
 
 	return result
@@ -84,6 +110,7 @@ func Complete(opts ...Options) Options {
 func New(ctx context.Context, o ...Options) (*GPTScript, error) {
 	opts := Complete(o...)
 	registry := llm.NewRegistry()
+	registry.SetModelAliases(opts.ModelAliases)
 
 	cacheClient, err := cache.New(opts.Cache)
 	if err != nil {
@@ -99,15 +126,33 @@ func New(ctx context.Context, o ...Options) (*GPTScript, error) {
 		opts.Runner.RuntimeManager = runtimes.Default(cacheClient.CacheDir())
 	}
 
+	if auditLog := types.FirstSet(opts.AuditLog, cliCfg.AuditLog); auditLog != "" && opts.Runner.Audit == nil {
+		if opts.Runner.Audit, err = audit.Open(auditLog); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Runner.Cache == nil {
+		opts.Runner.Cache = cacheClient
+	}
+
+	if opts.Runner.DaemonDir == "" {
+		opts.Runner.DaemonDir = daemon.Dir(cacheClient.CacheDir())
+	}
+
 	if err := opts.Runner.RuntimeManager.SetUpCredentialHelpers(context.Background(), cliCfg); err != nil {
 		return nil, err
 	}
 
-	credStore, err := credentials.NewStore(cliCfg, opts.Runner.RuntimeManager, opts.CredentialContext, cacheClient.CacheDir())
+	credStore, err := credentials.NewStoreWithHelper(cliCfg, opts.Runner.RuntimeManager, cliCfg.CredentialsStore, opts.CredentialContexts, opts.CredentialWriteContext, cacheClient.CacheDir())
 	if err != nil {
 		return nil, err
 	}
 
+	if err := registry.AddClient(mock.NewClient()); err != nil {
+		return nil, err
+	}
+
 	if opts.DefaultModelProvider == "" {
 		oaiClient, err := openai.NewClient(ctx, credStore, opts.OpenAI, openai.Options{
 			Cache:   cacheClient,
@@ -122,11 +167,32 @@ func New(ctx context.Context, o ...Options) (*GPTScript, error) {
 		}
 	}
 
+	if opts.DisableRedaction {
+		opts.Runner.Redactor = nil
+	} else if opts.Runner.Redactor == nil {
+		opts.Runner.Redactor = redact.New()
+	}
+
 	if opts.Runner.MonitorFactory == nil {
 		opts.Runner.MonitorFactory = monitor.NewConsole(opts.Monitor, monitor.Options{DebugMessages: *opts.Quiet})
 	}
 
-	runner, err := runner.New(registry, credStore, opts.Runner)
+	stopTracing := func(context.Context) error { return nil }
+	if opts.Monitor.TraceEndpoint != "" {
+		var tracing runner.MonitorFactory
+		tracing, stopTracing, err = monitor.NewOTLPTracingFactory(ctx, opts.Monitor.TraceEndpoint)
+		if err != nil {
+			return nil, err
+		}
+		opts.Runner.MonitorFactory = monitor.NewMultiFactory(opts.Runner.MonitorFactory, tracing)
+	}
+
+	var model engine.Model = registry
+	if opts.Model != nil {
+		model = opts.Model
+	}
+
+	runner, err := runner.New(model, credStore, opts.Runner)
 	if err != nil {
 		return nil, err
 	}
@@ -138,7 +204,7 @@ func New(ctx context.Context, o ...Options) (*GPTScript, error) {
 	if !opts.DisablePromptServer {
 		var ctx context.Context
 		ctx, closeServer = context.WithCancel(context2.AddPauseFuncToCtx(context.Background(), opts.Runner.MonitorFactory.Pause))
-		extraEnv, err = prompt.NewServer(ctx, opts.Env)
+		extraEnv, err = prompt.NewServer(ctx, opts.Env, opts.Runner.Redactor)
 		if err != nil {
 			closeServer()
 			return nil, err
@@ -160,7 +226,12 @@ func New(ctx context.Context, o ...Options) (*GPTScript, error) {
 		WorkspacePath:          opts.Workspace,
 		DeleteWorkspaceOnClose: opts.Workspace == "",
 		ExtraEnv:               extraEnv,
-		close:                  closeServer,
+		close: func() {
+			closeServer()
+			if err := stopTracing(context.Background()); err != nil {
+				log.Errorf("failed to export traces: %v", err)
+			}
+		},
 	}, nil
 }
 
@@ -228,6 +299,7 @@ func (g *GPTScript) Close(closeDaemons bool) {
 
 	if closeDaemons {
 		engine.CloseDaemons()
+		engine.CloseMCPSessions()
 	}
 }
 
@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	"github.com/gptscript-ai/gptscript/pkg/builtin"
+	"github.com/gptscript-ai/gptscript/pkg/engine"
 	"github.com/gptscript-ai/gptscript/pkg/openai"
 )
 
@@ -92,6 +93,7 @@ func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	newURL.Path = path.Join(newURL.Path, req.URL.Path)
 
 	rp := httputil.ReverseProxy{
+		Transport: engine.DaemonTransport,
 		Director: func(proxyReq *http.Request) {
 			proxyReq.Body = io.NopCloser(bytes.NewReader(inBytes))
 			proxyReq.URL = newURL
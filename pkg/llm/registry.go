@@ -9,6 +9,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gptscript-ai/gptscript/pkg/env"
+	"github.com/gptscript-ai/gptscript/pkg/mock"
 	"github.com/gptscript-ai/gptscript/pkg/openai"
 	"github.com/gptscript-ai/gptscript/pkg/remote"
 	"github.com/gptscript-ai/gptscript/pkg/types"
@@ -25,6 +26,7 @@ type Registry struct {
 	proxyURL   string
 	proxyLock  sync.Mutex
 	clients    []Client
+	aliases    map[string]string
 }
 
 func NewRegistry() *Registry {
@@ -38,6 +40,18 @@ func (r *Registry) AddClient(client Client) error {
 	return nil
 }
 
+// SetModelAliases sets names that can be used as models, and the models that are used for them.
+func (r *Registry) SetModelAliases(aliases map[string]string) {
+	r.aliases = aliases
+}
+
+func (r *Registry) resolveAlias(modelName string) string {
+	if model, ok := r.aliases[modelName]; ok {
+		return model
+	}
+	return modelName
+}
+
 func (r *Registry) ListModels(ctx context.Context, providers ...string) (result []string, _ error) {
 	for _, v := range r.clients {
 		models, err := v.ListModels(ctx, providers...)
@@ -51,12 +65,24 @@ func (r *Registry) ListModels(ctx context.Context, providers ...string) (result
 }
 
 func (r *Registry) fastPath(modelName string) Client {
+	clients := r.clients
+
+	// The mock client is added first, and only supports the models that are named for it.
+	if len(clients) > 0 {
+		if c, ok := clients[0].(*mock.Client); ok {
+			if mock.IsModel(modelName) {
+				return c
+			}
+			clients = clients[1:]
+		}
+	}
+
 	// This is optimization hack to avoid doing List Models
-	if len(r.clients) == 1 {
-		return r.clients[0]
+	if len(clients) == 1 {
+		return clients[0]
 	}
 
-	if len(r.clients) != 2 {
+	if len(clients) != 2 {
 		return nil
 	}
 
@@ -65,17 +91,17 @@ func (r *Registry) fastPath(modelName string) Client {
 		return nil
 	}
 
-	_, ok := r.clients[0].(*openai.Client)
+	_, ok := clients[0].(*openai.Client)
 	if !ok {
 		return nil
 	}
 
-	_, ok = r.clients[1].(*remote.Client)
+	_, ok = clients[1].(*remote.Client)
 	if !ok {
 		return nil
 	}
 
-	return r.clients[0]
+	return clients[0]
 }
 
 func (r *Registry) getClient(ctx context.Context, modelName string) (Client, error) {
@@ -123,6 +149,7 @@ func (r *Registry) Call(ctx context.Context, messageRequest types.CompletionRequ
 	if messageRequest.Model == "" {
 		return nil, fmt.Errorf("model is required")
 	}
+	messageRequest.Model = r.resolveAlias(messageRequest.Model)
 
 	if c := r.fastPath(messageRequest.Model); c != nil {
 		return c.Call(ctx, messageRequest, status)
@@ -0,0 +1,187 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gptscript-ai/gptscript/pkg/cache"
+	"github.com/gptscript-ai/gptscript/pkg/repos/archive"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+)
+
+type archiveCacheKey struct {
+	Archive string
+	Digest  string
+}
+
+type archiveCacheValue struct {
+	Revision string
+	Time     time.Time
+}
+
+// loadArchive loads a tool from an archive (.tar.gz, .tgz or .zip, local or remote), an OCI image layout directory, or
+// an image of an OCI registry (oci://host/repository:tag). Any of them can be pinned to a digest with @sha256:<digest>.
+// The archive or image is extracted into the cache, and the tool is the default file at its root or in its only
+// directory.
+func loadArchive(ctx context.Context, cache *cache.Client, base *source, name string) (*source, bool, error) {
+	ref, digest := archive.SplitDigest(name)
+
+	var (
+		dir     = archive.Dir(cacheDir(cache))
+		root    string
+		extract func() (string, error)
+	)
+
+	switch {
+	case strings.HasPrefix(ref, archive.OCIScheme):
+		root = ref
+		extract = func() (string, error) {
+			return archive.FromRegistry(ctx, dir, ref, digest)
+		}
+	case archive.IsArchive(ref) && isURL(ref), archive.IsArchive(ref) && base.Remote && !filepath.IsAbs(ref):
+		root = ref
+		if !isURL(ref) {
+			// Don't use path.Join because this is a URL and will break the :// protocol by cleaning it
+			root = base.Path + "/" + ref
+		}
+		extract = func() (string, error) {
+			return archive.FromURL(ctx, dir, root, digest)
+		}
+	case base.Remote:
+		return nil, false, nil
+	case archive.IsArchive(ref):
+		file, ok := localPath(base, ref)
+		if !ok {
+			return nil, false, nil
+		}
+		root = file
+		return loadExtracted(dir, root, func() (string, error) {
+			return archive.FromFile(dir, file, digest)
+		})
+	default:
+		layout, tag := ref, ""
+		if !archive.IsLayout(localFile(base, layout)) {
+			if i := strings.LastIndex(ref, ":"); i > 0 && archive.IsLayout(localFile(base, ref[:i])) {
+				layout, tag = ref[:i], ref[i+1:]
+			} else {
+				return nil, false, nil
+			}
+		}
+		file, _ := localPath(base, layout)
+		root = file
+		if tag != "" {
+			root += ":" + tag
+		}
+		return loadExtracted(dir, root, func() (string, error) {
+			return archive.FromLayout(ctx, dir, file, tag, digest)
+		})
+	}
+
+	// Remote archives and images that aren't pinned to a digest can change, so they are only cached for a while.
+	var (
+		cachedKey   = archiveCacheKey{Archive: root, Digest: digest}
+		cachedValue archiveCacheValue
+	)
+	if ok, err := cache.Get(ctx, cachedKey, &cachedValue); err != nil {
+		return nil, false, err
+	} else if ok && (digest != "" || time.Since(cachedValue.Time) < CacheTimeout) && archive.Extracted(dir, cachedValue.Revision) {
+		return loadExtracted(dir, root, func() (string, error) {
+			return cachedValue.Revision, nil
+		})
+	}
+
+	return loadExtracted(dir, root, func() (string, error) {
+		revision, err := extract()
+		if err != nil {
+			return "", err
+		}
+		return revision, cache.Store(ctx, cachedKey, archiveCacheValue{
+			Revision: revision,
+			Time:     time.Now(),
+		})
+	})
+}
+
+// loadExtracted extracts an archive and loads the tool that it contains.
+func loadExtracted(dir, root string, extract func() (string, error)) (*source, bool, error) {
+	revision, err := extract()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load %s: %w", root, err)
+	}
+
+	toolPath, err := findTool(filepath.Join(dir, revision))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load %s: %w", root, err)
+	}
+
+	s, ok, err := loadLocal(&source{}, path.Join(filepath.ToSlash(dir), revision, toolPath))
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	s.Repo = &types.Repo{
+		VCS:      archive.VCS,
+		Root:     root,
+		Path:     path.Dir(toolPath),
+		Name:     path.Base(toolPath),
+		Revision: revision,
+	}
+	return s, true, nil
+}
+
+// findTool returns the path of the tool of an extracted archive, which is the default file at its root or in its only
+// directory.
+func findTool(dir string) (string, error) {
+	for _, def := range types.DefaultFiles {
+		if s, err := os.Stat(filepath.Join(dir, def)); err == nil && !s.IsDir() {
+			return def, nil
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		for _, def := range types.DefaultFiles {
+			if s, err := os.Stat(filepath.Join(dir, entries[0].Name(), def)); err == nil && !s.IsDir() {
+				return path.Join(entries[0].Name(), def), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no %s found in the archive", strings.Join(types.DefaultFiles, " or "))
+}
+
+// localPath returns the absolute path of a local file, and whether it exists.
+func localPath(base *source, name string) (string, bool) {
+	file, err := filepath.Abs(localFile(base, name))
+	if err != nil {
+		return "", false
+	}
+	_, err = os.Stat(file)
+	return file, err == nil
+}
+
+func localFile(base *source, name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.FromSlash(path.Join(base.Path, name))
+}
+
+func isURL(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+func cacheDir(c *cache.Client) string {
+	if c == nil {
+		return cache.Complete().CacheDir
+	}
+	return c.CacheDir()
+}
@@ -0,0 +1,72 @@
+package loader
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gptscript-ai/gptscript/pkg/cache"
+	"github.com/gptscript-ai/gptscript/pkg/repos/archive"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchive(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{
+		"bundle/tool.gpt": "Tools: ./helpers/helper.gpt\n\nUse the helper",
+		"bundle/helpers/helper.gpt": `Name: helper
+
+#!/usr/bin/env python3 helper.py
+`,
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "bundle.tar.gz")
+	require.NoError(t, os.WriteFile(file, buf.Bytes(), 0644))
+	sum := sha256.Sum256(buf.Bytes())
+	digest := hex.EncodeToString(sum[:])
+
+	c, err := cache.New(cache.Options{CacheDir: filepath.Join(dir, "cache")})
+	require.NoError(t, err)
+
+	prg, err := Program(context.Background(), file+archive.DigestPrefix+digest, "", Options{Cache: c})
+	require.NoError(t, err)
+
+	entry := prg.ToolSet[prg.EntryToolID]
+	require.Equal(t, &types.Repo{
+		VCS:      archive.VCS,
+		Root:     file,
+		Path:     "bundle",
+		Name:     "tool.gpt",
+		Revision: digest,
+	}, entry.Source.Repo)
+	require.Equal(t, filepath.Join(archive.Dir(c.CacheDir()), digest, "bundle"), filepath.FromSlash(entry.WorkingDir))
+
+	helper := prg.ToolSet[entry.ToolMapping["./helpers/helper.gpt"][0].ToolID]
+	require.Equal(t, &types.Repo{
+		VCS:      archive.VCS,
+		Root:     file,
+		Path:     "bundle/helpers",
+		Name:     "helper.gpt",
+		Revision: digest,
+	}, helper.Source.Repo)
+
+	_, err = Program(context.Background(), file+archive.DigestPrefix+strings.Repeat("0", 64), "", Options{Cache: c})
+	require.ErrorContains(t, err, "integrity check")
+}
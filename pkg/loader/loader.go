@@ -106,9 +106,27 @@ func loadLocal(base *source, name string) (*source, bool, error) {
 		Path:     path.Dir(filePath),
 		Name:     path.Base(filePath),
 		Location: filePath,
+		Repo:     localRepo(base, filePath),
 	}, true, nil
 }
 
+// localRepo returns the repo of a file that is referenced by a source of a repo, such as an extracted archive, if the
+// file is in the same repo.
+func localRepo(base *source, filePath string) *types.Repo {
+	if base.Repo == nil {
+		return nil
+	}
+	rel, err := filepath.Rel(filepath.FromSlash(base.Path), filepath.FromSlash(filePath))
+	if err != nil || !filepath.IsLocal(rel) {
+		return nil
+	}
+	newRepo := *base.Repo
+	newPath := path.Join(newRepo.Path, filepath.ToSlash(rel))
+	newRepo.Path = path.Dir(newPath)
+	newRepo.Name = path.Base(newPath)
+	return &newRepo
+}
+
 func loadProgram(data []byte, into *types.Program, targetToolName string) (types.Tool, error) {
 	var ext types.Program
 
@@ -220,6 +238,15 @@ func readTool(ctx context.Context, cache *cache.Client, prg *types.Program, base
 		return nil, fmt.Errorf("no tools found in %s", base)
 	}
 
+	var mcpDir string
+	if !base.Remote {
+		mcpDir = base.Path
+	}
+	tools, err := expandMCPTools(ctx, prg, tools, mcpDir)
+	if err != nil {
+		return nil, err
+	}
+
 	var (
 		localTools  = types.ToolSet{}
 		targetTools []types.Tool
@@ -367,6 +394,7 @@ func ProgramFromSource(ctx context.Context, content, subToolName string, opts ..
 		}()
 	}
 	opt := complete(opts...)
+	ctx = withEnv(ctx, opt.Env)
 
 	var locationPath, locationName string
 	if opt.Location != "" {
@@ -393,12 +421,18 @@ func ProgramFromSource(ctx context.Context, content, subToolName string, opts ..
 type Options struct {
 	Cache    *cache.Client
 	Location string
+	// Env is the environment of the run that the program is loaded for, which MCP servers are started with to list
+	// their tools. It is the environment of the process if it is not set.
+	Env []string
 }
 
 func complete(opts ...Options) (result Options) {
 	for _, opt := range opts {
 		result.Cache = types.FirstSet(opt.Cache, result.Cache)
 		result.Location = types.FirstSet(opt.Location, result.Location)
+		if opt.Env != nil {
+			result.Env = opt.Env
+		}
 	}
 
 	if result.Location == "" {
@@ -420,6 +454,7 @@ func Program(ctx context.Context, name, subToolName string, opts ...Options) (ty
 	}
 
 	opt := complete(opts...)
+	ctx = withEnv(ctx, opt.Env)
 
 	if subToolName == "" {
 		name, subToolName = types.SplitToolRef(name)
@@ -468,6 +503,11 @@ func input(ctx context.Context, cache *cache.Client, base *source, name string)
 		base = base.WithRemote(true)
 	}
 
+	s, ok, err := loadArchive(ctx, cache, base, name)
+	if err != nil || ok {
+		return s, err
+	}
+
 	if !base.Remote {
 		s, ok, err := loadLocal(base, name)
 		if err != nil || ok {
@@ -475,7 +515,7 @@ func input(ctx context.Context, cache *cache.Client, base *source, name string)
 		}
 	}
 
-	s, ok, err := loadURL(ctx, cache, base, name)
+	s, ok, err = loadURL(ctx, cache, base, name)
 	if err != nil || ok {
 		return s, err
 	}
@@ -0,0 +1,123 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	gcontext "github.com/gptscript-ai/gptscript/pkg/context"
+	"github.com/gptscript-ai/gptscript/pkg/hash"
+	"github.com/gptscript-ai/gptscript/pkg/mcp"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+)
+
+// expandMCPTools replaces each tool that defines an MCP server with a tool that exports a generated tool for each
+// tool of the server. The generated tools have Instructions in the format "#!sys.mcp.invoke {tool name}" followed by
+// the definition of the server on the next line, so that the engine can start the server when the tool is called.
+func expandMCPTools(ctx context.Context, prg *types.Program, tools []types.Tool, dir string) ([]types.Tool, error) {
+	var result []types.Tool
+	for _, tool := range tools {
+		if !tool.IsMCP() {
+			result = append(result, tool)
+			continue
+		}
+
+		definition := strings.TrimPrefix(tool.Instructions, types.MCPPrefix)
+		mcpTools, err := listMCPTools(ctx, prg, definition, dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tools of MCP server %s: %w", tool.Parameters.Name, err)
+		}
+
+		var generated []types.Tool
+		exportTool := tool
+		exportTool.Instructions = ""
+		exportTool.Parameters.Export = nil
+		if exportTool.Parameters.Description == "" {
+			exportTool.Parameters.Description = fmt.Sprintf("This is a tool set for the %s MCP server", types.FirstSet(tool.Parameters.Name, strings.TrimSpace(definition)))
+		}
+
+		for _, mcpTool := range mcpTools {
+			name := toolNameRegex.ReplaceAllString(mcpTool.Name, "_")
+			newTool := types.Tool{
+				ToolDef: types.ToolDef{
+					Parameters: types.Parameters{
+						Name:        name,
+						Description: mcpTool.Description,
+						Credentials: tool.Parameters.Credentials,
+					},
+					Instructions: types.MCPInvokePrefix + " " + mcpTool.Name + "\n" + tool.Instructions,
+				},
+				Source: tool.Source,
+			}
+
+			if len(mcpTool.InputSchema) > 0 {
+				var schema openapi3.Schema
+				if err := json.Unmarshal(mcpTool.InputSchema, &schema); err != nil {
+					return nil, fmt.Errorf("invalid input schema for MCP tool %s: %w", mcpTool.Name, err)
+				}
+				// OpenAI will get upset if we have an object schema with no properties,
+				// so we just nil this out if there are no properties.
+				if len(schema.Properties) > 0 {
+					newTool.Parameters.Arguments = &schema
+				}
+			}
+
+			exportTool.Parameters.Export = append(exportTool.Parameters.Export, name)
+			generated = append(generated, newTool)
+		}
+
+		result = append(result, exportTool)
+		result = append(result, generated...)
+	}
+
+	return result, nil
+}
+
+// withEnv sets the environment that MCP servers are started with while loading.
+func withEnv(ctx context.Context, env []string) context.Context {
+	if env == nil {
+		return ctx
+	}
+	return gcontext.WithEnv(ctx, env)
+}
+
+func listMCPTools(ctx context.Context, prg *types.Program, definition, dir string) ([]mcp.Tool, error) {
+	cacheKey := hash.ID(definition, dir)
+	if tools, ok := prg.MCPCache[cacheKey].([]mcp.Tool); ok {
+		return tools, nil
+	}
+
+	env := gcontext.GetEnv(ctx)
+	if env == nil {
+		env = os.Environ()
+	}
+
+	config, err := mcp.ParseConfig(definition, dir, env)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	client, err := mcp.Connect(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if prg.MCPCache == nil {
+		prg.MCPCache = map[string]any{}
+	}
+	prg.MCPCache[cacheKey] = tools
+	return tools, nil
+}
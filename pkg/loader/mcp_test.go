@@ -0,0 +1,74 @@
+package loader
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gptscript-ai/gptscript/pkg/mcp/mcptest"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMCP(t *testing.T) {
+	s := httptest.NewServer(mcptest.NewServer())
+	defer s.Close()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tool.gpt"), []byte(`
+Tools: stub
+
+Say hello
+
+---
+Name: stub
+Credential: token
+
+#!sys.mcp `+s.URL+`
+
+---
+Name: token
+
+#!sys.echo
+`), 0644))
+
+	prg, err := Program(context.Background(), filepath.Join(dir, "tool.gpt"), "")
+	require.NoError(t, err)
+
+	stub := prg.ToolSet[prg.ToolSet[prg.EntryToolID].ToolMapping["stub"][0].ToolID]
+	require.Empty(t, stub.Instructions)
+	require.Equal(t, []string{"echo", "add", "fail"}, stub.Export)
+
+	echo := prg.ToolSet[stub.ToolMapping["echo"][0].ToolID]
+	require.True(t, echo.IsMCPInvoke())
+	require.Equal(t, types.MCPInvokePrefix+" echo\n#!sys.mcp "+s.URL, echo.Instructions)
+	require.Equal(t, "Returns the message", echo.Description)
+	require.Equal(t, []string{"token"}, echo.Credentials)
+	require.Contains(t, echo.Arguments.Properties, "message")
+
+	fail := prg.ToolSet[stub.ToolMapping["fail"][0].ToolID]
+	require.Nil(t, fail.Arguments)
+
+	tools, err := prg.ToolSet[prg.EntryToolID].GetChatCompletionTools(prg)
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Function.Name)
+	}
+	require.Subset(t, names, []string{"echo", "add", "fail"})
+}
+
+func TestLoadMCPEnv(t *testing.T) {
+	s := httptest.NewServer(mcptest.NewServer())
+	defer s.Close()
+
+	// The address of the server is only in the environment of the run that the program is loaded for.
+	prg, err := ProgramFromSource(context.Background(), "tools: stub\n\n---\nname: stub\n\n#!sys.mcp http://${MCP_HOST}\n", "", Options{
+		Env: []string{"MCP_HOST=" + s.Listener.Addr().String()},
+	})
+	require.NoError(t, err)
+
+	stub := prg.ToolSet[prg.ToolSet[prg.EntryToolID].ToolMapping["stub"][0].ToolID]
+	require.Equal(t, []string{"echo", "add", "fail"}, stub.Export)
+}
@@ -0,0 +1,529 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/shlex"
+	"github.com/gptscript-ai/gptscript/pkg/mvl"
+	"github.com/gptscript-ai/gptscript/pkg/version"
+)
+
+var (
+	log = mvl.Package()
+
+	// ErrClosed is returned for calls on a client whose connection to the server has been closed.
+	ErrClosed = errors.New("MCP server connection is closed")
+)
+
+// Config describes how to connect to an MCP server. Either URL or Command is set.
+type Config struct {
+	// URL is the endpoint of a server that uses the streamable HTTP transport
+	URL string
+	// Command and Args are run to start a server that uses the stdio transport
+	Command string
+	Args    []string
+	Env     []string
+	Dir     string
+}
+
+// ParseConfig parses the server definition that follows #!sys.mcp. The definition is either an http(s) URL or a
+// command. ${VAR} references are expanded from env, and ${GPTSCRIPT_TOOL_DIR} is expanded to dir.
+func ParseConfig(definition, dir string, env []string) (Config, error) {
+	definition = strings.TrimSpace(definition)
+	if definition == "" {
+		return Config{}, fmt.Errorf("missing MCP server command or URL")
+	}
+
+	envMap := map[string]string{}
+	for _, e := range env {
+		k, v, _ := strings.Cut(e, "=")
+		envMap[k] = v
+	}
+	if dir != "" {
+		envMap["GPTSCRIPT_TOOL_DIR"] = dir
+	}
+	expand := func(s string) string {
+		return os.Expand(s, func(key string) string {
+			return envMap[key]
+		})
+	}
+
+	if strings.HasPrefix(definition, "http://") || strings.HasPrefix(definition, "https://") {
+		return Config{
+			URL: expand(definition),
+		}, nil
+	}
+
+	args, err := shlex.Split(definition)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid MCP server command [%s]: %w", definition, err)
+	}
+	for i, arg := range args {
+		args[i] = expand(arg)
+	}
+
+	return Config{
+		Command: args[0],
+		Args:    args[1:],
+		Env:     env,
+		Dir:     dir,
+	}, nil
+}
+
+func (c Config) String() string {
+	if c.URL != "" {
+		return c.URL
+	}
+	return strings.Join(append([]string{c.Command}, c.Args...), " ")
+}
+
+type transport interface {
+	call(ctx context.Context, msg Message) (Message, error)
+	notify(ctx context.Context, msg Message) error
+	close() error
+}
+
+// Client is a connection to an MCP server.
+type Client struct {
+	config     Config
+	transport  transport
+	nextID     atomic.Int64
+	ServerInfo Implementation
+	// ProtocolVersion is the version of the protocol that the server chose.
+	ProtocolVersion string
+}
+
+// Connect starts or connects to the server and completes the initialization handshake.
+func Connect(ctx context.Context, config Config) (*Client, error) {
+	var (
+		t   transport
+		err error
+	)
+	if config.URL != "" {
+		t = newHTTPTransport(config.URL)
+	} else {
+		t, err = newStdioTransport(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c := &Client{
+		config:    config,
+		transport: t,
+	}
+
+	var result initializeResult
+	if err := c.call(ctx, methodInitialize, initializeParams{
+		ProtocolVersion: ClientProtocolVersion,
+		Capabilities:    map[string]any{},
+		ClientInfo: Implementation{
+			Name:    version.ProgramName,
+			Version: version.Get().String(),
+		},
+	}, &result); err != nil {
+		_ = t.close()
+		return nil, fmt.Errorf("failed to initialize MCP server [%s]: %w", config, err)
+	}
+	if !slices.Contains(SupportedProtocolVersions, result.ProtocolVersion) {
+		_ = t.close()
+		return nil, fmt.Errorf("failed to initialize MCP server [%s]: unsupported protocol version %q", config, result.ProtocolVersion)
+	}
+	c.ServerInfo = result.ServerInfo
+	c.ProtocolVersion = result.ProtocolVersion
+	if ht, ok := t.(*httpTransport); ok {
+		ht.setProtocolVersion(result.ProtocolVersion)
+	}
+
+	if err := t.notify(ctx, Message{
+		JSONRPC: "2.0",
+		Method:  methodInitialized,
+	}); err != nil {
+		_ = t.close()
+		return nil, fmt.Errorf("failed to initialize MCP server [%s]: %w", config, err)
+	}
+
+	return c, nil
+}
+
+func (c *Client) call(ctx context.Context, method string, params, result any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.transport.call(ctx, Message{
+		JSONRPC: "2.0",
+		ID:      json.RawMessage(strconv.FormatInt(c.nextID.Add(1), 10)),
+		Method:  method,
+		Params:  data,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// ListTools returns all the tools advertised by the server.
+func (c *Client) ListTools(ctx context.Context) (result []Tool, _ error) {
+	var cursor string
+	for {
+		var page listToolsResult
+		if err := c.call(ctx, methodToolsList, listToolsParams{Cursor: cursor}, &page); err != nil {
+			return nil, err
+		}
+		result = append(result, page.Tools...)
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return result, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool calls the named tool with arguments, which must be a JSON object.
+func (c *Client) CallTool(ctx context.Context, name string, arguments json.RawMessage) (result CallToolResult, _ error) {
+	if len(bytes.TrimSpace(arguments)) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	err := c.call(ctx, methodToolsCall, callToolParams{
+		Name:      name,
+		Arguments: arguments,
+	}, &result)
+	return result, err
+}
+
+// Close disconnects from the server, stopping it if it was started by this client.
+func (c *Client) Close() error {
+	return c.transport.close()
+}
+
+type stdioTransport struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+	lock    sync.Mutex
+	pending map[string]chan Message
+	err     error
+	done    chan struct{}
+}
+
+func newStdioTransport(config Config) (*stdioTransport, error) {
+	cmd := exec.Command(config.Command, config.Args...)
+	cmd.Env = config.Env
+	cmd.Dir = config.Dir
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start MCP server [%s]: %w", config, err)
+	}
+	log.Debugf("started MCP server [%s] pid [%d]", config, cmd.Process.Pid)
+
+	t := &stdioTransport{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: map[string]chan Message{},
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			log.Debugf("MCP server [%s]: %s", config.Command, scanner.Text())
+		}
+	}()
+
+	go t.read(stdout)
+	return t, nil
+}
+
+func (t *stdioTransport) read(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var msg Message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			log.Debugf("invalid message from MCP server: %v", err)
+			continue
+		}
+
+		switch {
+		case msg.isResponse():
+			t.lock.Lock()
+			ch, ok := t.pending[string(msg.ID)]
+			delete(t.pending, string(msg.ID))
+			t.lock.Unlock()
+			if ok {
+				ch <- msg
+			}
+		case msg.isRequest():
+			// The server is asking the client for something, the only request supported is ping.
+			resp := Message{
+				JSONRPC: "2.0",
+				ID:      msg.ID,
+			}
+			if msg.Method == methodPing {
+				resp.Result = json.RawMessage("{}")
+			} else {
+				resp.Error = &Error{Code: codeMethodNotFound, Message: "method not supported: " + msg.Method}
+			}
+			_ = t.write(resp)
+		}
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = t.cmd.Wait()
+	}
+	if err == nil {
+		err = ErrClosed
+	} else {
+		err = fmt.Errorf("%w: %v", ErrClosed, err)
+	}
+
+	t.lock.Lock()
+	t.err = err
+	for id, ch := range t.pending {
+		close(ch)
+		delete(t.pending, id)
+	}
+	t.lock.Unlock()
+	close(t.done)
+}
+
+func (t *stdioTransport) write(msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err = t.stdin.Write(append(data, '\n'))
+	return err
+}
+
+func (t *stdioTransport) call(ctx context.Context, msg Message) (Message, error) {
+	ch := make(chan Message, 1)
+
+	t.lock.Lock()
+	if t.err != nil {
+		t.lock.Unlock()
+		return Message{}, t.err
+	}
+	t.pending[string(msg.ID)] = ch
+	t.lock.Unlock()
+
+	if err := t.write(msg); err != nil {
+		t.lock.Lock()
+		delete(t.pending, string(msg.ID))
+		t.lock.Unlock()
+		return Message{}, fmt.Errorf("%w: %v", ErrClosed, err)
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			t.lock.Lock()
+			defer t.lock.Unlock()
+			return Message{}, t.err
+		}
+		return resp, nil
+	case <-ctx.Done():
+		t.lock.Lock()
+		delete(t.pending, string(msg.ID))
+		t.lock.Unlock()
+		return Message{}, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) notify(_ context.Context, msg Message) error {
+	return t.write(msg)
+}
+
+func (t *stdioTransport) close() error {
+	// Closing stdin is how the stdio transport asks a server to exit, kill it if it doesn't.
+	_ = t.stdin.Close()
+	select {
+	case <-t.done:
+	case <-time.After(5 * time.Second):
+		_ = t.cmd.Process.Kill()
+		<-t.done
+	}
+	return nil
+}
+
+type httpTransport struct {
+	url             string
+	client          *http.Client
+	lock            sync.Mutex
+	sessionID       string
+	protocolVersion string
+}
+
+func newHTTPTransport(url string) *httpTransport {
+	return &httpTransport{
+		url:    url,
+		client: http.DefaultClient,
+	}
+}
+
+// setProtocolVersion sets the version sent in the MCP-Protocol-Version header of the requests after initialization.
+func (t *httpTransport) setProtocolVersion(version string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.protocolVersion = version
+}
+
+func (t *httpTransport) post(ctx context.Context, msg Message) (*http.Response, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	t.lock.Lock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set("MCP-Protocol-Version", t.protocolVersion)
+	}
+	t.lock.Unlock()
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if sessionID := resp.Header.Get("Mcp-Session-Id"); sessionID != "" {
+		t.lock.Lock()
+		t.sessionID = sessionID
+		t.lock.Unlock()
+	}
+
+	if resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unexpected response from MCP server [%s] %s: %s", t.url, resp.Status, bytes.TrimSpace(body))
+	}
+
+	return resp, nil
+}
+
+func (t *httpTransport) call(ctx context.Context, msg Message) (Message, error) {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return Message{}, err
+	}
+	defer resp.Body.Close()
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		var result Message
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return Message{}, fmt.Errorf("invalid response from MCP server [%s]: %w", t.url, err)
+		}
+		return result, nil
+	}
+
+	// The server can send other messages before the response, so read events until the response is found.
+	var (
+		data    bytes.Buffer
+		scanner = bufio.NewScanner(resp.Body)
+	)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data.WriteString(strings.TrimPrefix(value, " "))
+			continue
+		} else if line != "" || data.Len() == 0 {
+			continue
+		}
+
+		var result Message
+		err := json.Unmarshal(data.Bytes(), &result)
+		data.Reset()
+		if err == nil && result.isResponse() && bytes.Equal(result.ID, msg.ID) {
+			return result, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Message{}, err
+	}
+	return Message{}, fmt.Errorf("MCP server [%s] closed the stream without a response", t.url)
+}
+
+func (t *httpTransport) notify(ctx context.Context, msg Message) error {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+func (t *httpTransport) close() error {
+	t.lock.Lock()
+	sessionID := t.sessionID
+	t.lock.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+
+	// Let the server know that the session is no longer needed, servers are not required to support this.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Mcp-Session-Id", sessionID)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil
+	}
+	return resp.Body.Close()
+}
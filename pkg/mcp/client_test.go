@@ -0,0 +1,154 @@
+package mcp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gptscript-ai/gptscript/pkg/mcp"
+	"github.com/gptscript-ai/gptscript/pkg/mcp/mcptest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	mcptest.ServeStdioIfRequested()
+	os.Exit(m.Run())
+}
+
+func TestParseConfig(t *testing.T) {
+	config, err := mcp.ParseConfig(`npx -y "@example/server" --dir ${GPTSCRIPT_TOOL_DIR} --token=${TOKEN}`, "/tools", []string{"TOKEN=abc"})
+	require.NoError(t, err)
+	require.Equal(t, "npx", config.Command)
+	require.Equal(t, []string{"-y", "@example/server", "--dir", "/tools", "--token=abc"}, config.Args)
+
+	config, err = mcp.ParseConfig(" http://localhost:${PORT}/mcp", "", []string{"PORT=8080"})
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost:8080/mcp", config.URL)
+
+	_, err = mcp.ParseConfig(" ", "", nil)
+	require.Error(t, err)
+}
+
+func testClient(t *testing.T, config mcp.Config) {
+	ctx := context.Background()
+
+	c, err := mcp.Connect(ctx, config)
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.Equal(t, "stub", c.ServerInfo.Name)
+
+	tools, err := c.ListTools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 3)
+	require.Equal(t, "echo", tools[0].Name)
+
+	result, err := c.CallTool(ctx, "echo", json.RawMessage(`{"message":"hello"}`))
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	require.Equal(t, "hello", result.String())
+
+	result, err = c.CallTool(ctx, "add", json.RawMessage(`{"a":1,"b":2}`))
+	require.NoError(t, err)
+	require.Equal(t, "3", result.String())
+
+	result, err = c.CallTool(ctx, "fail", nil)
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+
+	_, err = c.CallTool(ctx, "missing", nil)
+	require.Error(t, err)
+}
+
+func TestStdioClient(t *testing.T) {
+	testClient(t, mcp.Config{
+		Command: os.Args[0],
+		Env:     append(os.Environ(), mcptest.StubEnv+"=true"),
+	})
+}
+
+func TestHTTPClient(t *testing.T) {
+	s := httptest.NewServer(mcptest.NewServer())
+	defer s.Close()
+
+	testClient(t, mcp.Config{
+		URL: s.URL,
+	})
+}
+
+// versionedServer answers initialize with the given protocol version and records the version that the client asked
+// for and the MCP-Protocol-Version headers of the later requests.
+func versionedServer(version string, requested *string, headers *[]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg mcp.Message
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var result any
+		switch msg.Method {
+		case "initialize":
+			var params struct {
+				ProtocolVersion string `json:"protocolVersion"`
+			}
+			_ = json.Unmarshal(msg.Params, &params)
+			*requested = params.ProtocolVersion
+			result = map[string]any{
+				"protocolVersion": version,
+				"capabilities":    map[string]any{"tools": map[string]any{}},
+				"serverInfo":      map[string]any{"name": "old", "version": "v1"},
+			}
+		case "tools/list":
+			*headers = append(*headers, r.Header.Get("MCP-Protocol-Version"))
+			result = map[string]any{"tools": []map[string]any{{"name": "echo"}}}
+		default:
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		data, _ := json.Marshal(result)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(mcp.Message{
+			JSONRPC: "2.0",
+			ID:      msg.ID,
+			Result:  data,
+		})
+	})
+}
+
+func TestClientOlderServer(t *testing.T) {
+	var (
+		requested string
+		headers   []string
+	)
+	s := httptest.NewServer(versionedServer("2024-11-05", &requested, &headers))
+	defer s.Close()
+
+	c, err := mcp.Connect(context.Background(), mcp.Config{URL: s.URL})
+	require.NoError(t, err)
+	defer c.Close()
+
+	require.Equal(t, mcp.ClientProtocolVersion, requested)
+	require.Equal(t, "2024-11-05", c.ProtocolVersion)
+
+	tools, err := c.ListTools(context.Background())
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	require.Equal(t, []string{"2024-11-05"}, headers)
+}
+
+func TestClientUnsupportedVersion(t *testing.T) {
+	var (
+		requested string
+		headers   []string
+	)
+	s := httptest.NewServer(versionedServer("2023-01-01", &requested, &headers))
+	defer s.Close()
+
+	_, err := mcp.Connect(context.Background(), mcp.Config{URL: s.URL})
+	require.ErrorContains(t, err, `unsupported protocol version "2023-01-01"`)
+}
@@ -0,0 +1,86 @@
+// Package mcptest provides a small MCP server for testing MCP clients.
+package mcptest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/gptscript-ai/gptscript/pkg/mcp"
+)
+
+// StubEnv is the environment variable that tells a test binary to run as the stub server over stdio, see
+// ServeStdioIfRequested.
+const StubEnv = "GPTSCRIPT_MCP_STUB"
+
+type stub struct{}
+
+// NewServer returns a server with the following tools:
+//   - echo: returns its "message" argument
+//   - add: returns the sum of its "a" and "b" arguments
+//   - fail: returns an error result
+func NewServer() *mcp.Server {
+	return &mcp.Server{
+		Info: mcp.Implementation{
+			Name:    "stub",
+			Version: "v0.0.0",
+		},
+		Handler: stub{},
+	}
+}
+
+func (stub) ListTools(context.Context) ([]mcp.Tool, error) {
+	return []mcp.Tool{
+		{
+			Name:        "echo",
+			Description: "Returns the message",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{"message":{"type":"string","description":"The message to return"}},"required":["message"]}`),
+		},
+		{
+			Name:        "add",
+			Description: "Adds two numbers",
+			InputSchema: json.RawMessage(`{"type":"object","properties":{"a":{"type":"number"},"b":{"type":"number"}},"required":["a","b"]}`),
+		},
+		{
+			Name:        "fail",
+			Description: "Always fails",
+			InputSchema: json.RawMessage(`{"type":"object"}`),
+		},
+	}, nil
+}
+
+func (stub) CallTool(_ context.Context, name string, arguments json.RawMessage) (mcp.CallToolResult, error) {
+	var args struct {
+		Message string  `json:"message"`
+		A       float64 `json:"a"`
+		B       float64 `json:"b"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return mcp.ErrorResult(fmt.Sprintf("invalid arguments: %v", err)), nil
+	}
+
+	switch name {
+	case "echo":
+		return mcp.TextResult(args.Message), nil
+	case "add":
+		return mcp.TextResult(fmt.Sprint(args.A + args.B)), nil
+	case "fail":
+		return mcp.ErrorResult("this tool always fails"), nil
+	default:
+		return mcp.CallToolResult{}, fmt.Errorf("unknown tool %s", name)
+	}
+}
+
+// ServeStdioIfRequested runs the stub server over stdin and stdout and exits if StubEnv is set. Tests call this from
+// TestMain so that the test binary can be used as a stdio MCP server with os.Args[0].
+func ServeStdioIfRequested() {
+	if os.Getenv(StubEnv) == "" {
+		return
+	}
+	if err := NewServer().ServeStdio(context.Background(), os.Stdin, os.Stdout); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
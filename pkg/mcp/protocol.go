@@ -0,0 +1,177 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ProtocolVersion is the newest version of the protocol. The server uses it when the client asks for a version that
+	// isn't supported. Elicitation needs this version.
+	ProtocolVersion = "2025-06-18"
+	// ClientProtocolVersion is the version that the client asks servers for. The client doesn't use any of the features
+	// of newer versions.
+	ClientProtocolVersion = "2025-03-26"
+
+	methodInitialize  = "initialize"
+	methodInitialized = "notifications/initialized"
+	methodPing        = "ping"
+	methodToolsList   = "tools/list"
+	methodToolsCall   = "tools/call"
+	methodElicit      = "elicitation/create"
+
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// SupportedProtocolVersions are the versions of the protocol that the client and the server can use.
+var SupportedProtocolVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// Message is a JSON-RPC 2.0 request, notification, or response.
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+func (m Message) isRequest() bool {
+	return m.Method != "" && len(m.ID) > 0
+}
+
+func (m Message) isResponse() bool {
+	return m.Method == "" && len(m.ID) > 0
+}
+
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("MCP error %d: %s", e.Code, e.Message)
+}
+
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type initializeParams struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	Capabilities    map[string]any `json:"capabilities"`
+	ClientInfo      Implementation `json:"clientInfo"`
+}
+
+type initializeResult struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	Capabilities    map[string]any `json:"capabilities"`
+	ServerInfo      Implementation `json:"serverInfo"`
+	Instructions    string         `json:"instructions,omitempty"`
+}
+
+// Tool is a tool advertised by an MCP server.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+}
+
+type listToolsParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+type listToolsResult struct {
+	Tools      []Tool `json:"tools"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type callToolParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// Content is a single item of the content returned by a tool call.
+type Content struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	Data     string          `json:"data,omitempty"`
+	MimeType string          `json:"mimeType,omitempty"`
+	Resource json.RawMessage `json:"resource,omitempty"`
+}
+
+// CallToolResult is the result of a tool call.
+type CallToolResult struct {
+	Content           []Content       `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
+}
+
+// String returns the text content of the result. Content that is not text is returned as JSON.
+func (r CallToolResult) String() string {
+	var parts []string
+	for _, content := range r.Content {
+		if content.Type == "text" {
+			parts = append(parts, content.Text)
+			continue
+		}
+		data, err := json.Marshal(content)
+		if err == nil {
+			parts = append(parts, string(data))
+		}
+	}
+	if len(parts) == 0 && len(r.StructuredContent) > 0 {
+		return string(r.StructuredContent)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// ElicitRequest asks the user of the client for input. The schema can only contain primitive properties.
+type ElicitRequest struct {
+	Message         string       `json:"message"`
+	RequestedSchema ElicitSchema `json:"requestedSchema"`
+}
+
+type ElicitSchema struct {
+	Type       string                    `json:"type"`
+	Properties map[string]ElicitProperty `json:"properties"`
+	Required   []string                  `json:"required,omitempty"`
+}
+
+type ElicitProperty struct {
+	Type        string `json:"type"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+const (
+	ElicitAccept  = "accept"
+	ElicitDecline = "decline"
+	ElicitCancel  = "cancel"
+)
+
+type ElicitResult struct {
+	Action  string         `json:"action"`
+	Content map[string]any `json:"content,omitempty"`
+}
+
+// TextResult returns a successful result with the given text as its content.
+func TextResult(text string) CallToolResult {
+	return CallToolResult{
+		Content: []Content{{Type: "text", Text: text}},
+	}
+}
+
+// ErrorResult returns a failed result with the given text as its content.
+func ErrorResult(text string) CallToolResult {
+	result := TextResult(text)
+	result.IsError = true
+	return result
+}
@@ -0,0 +1,316 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// ErrElicitationUnsupported is returned by Elicit when the client can not be asked for input.
+var ErrElicitationUnsupported = errors.New("the MCP client does not support elicitation")
+
+// ToolHandler provides the tools that a Server exposes.
+type ToolHandler interface {
+	ListTools(ctx context.Context) ([]Tool, error)
+	CallTool(ctx context.Context, name string, arguments json.RawMessage) (CallToolResult, error)
+}
+
+// Server serves the tools of a ToolHandler over the stdio or streamable HTTP transport.
+type Server struct {
+	Info         Implementation
+	Instructions string
+	Handler      ToolHandler
+}
+
+type sessionKey struct{}
+
+// session is the connection to a client that can receive requests from the server.
+type session struct {
+	write        func(*Message)
+	lock         sync.Mutex
+	nextID       int64
+	pending      map[string]chan Message
+	capabilities map[string]any
+	version      string
+	closed       bool
+}
+
+func (s *session) deliver(msg Message) {
+	s.lock.Lock()
+	ch, ok := s.pending[string(msg.ID)]
+	delete(s.pending, string(msg.ID))
+	s.lock.Unlock()
+	if ok {
+		ch <- msg
+	}
+}
+
+func (s *session) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+	for id, ch := range s.pending {
+		close(ch)
+		delete(s.pending, id)
+	}
+}
+
+// Elicit asks the user of the client that made the current tool call for input. ErrElicitationUnsupported is
+// returned if the transport or the client does not support it.
+func Elicit(ctx context.Context, req ElicitRequest) (ElicitResult, error) {
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	if !ok {
+		return ElicitResult{}, ErrElicitationUnsupported
+	}
+
+	if req.RequestedSchema.Type == "" {
+		req.RequestedSchema.Type = "object"
+	}
+	if req.RequestedSchema.Properties == nil {
+		req.RequestedSchema.Properties = map[string]ElicitProperty{}
+	}
+	params, err := json.Marshal(req)
+	if err != nil {
+		return ElicitResult{}, err
+	}
+
+	s.lock.Lock()
+	if _, ok := s.capabilities["elicitation"]; !ok || s.version != ProtocolVersion {
+		s.lock.Unlock()
+		return ElicitResult{}, ErrElicitationUnsupported
+	}
+	if s.closed {
+		s.lock.Unlock()
+		return ElicitResult{}, ErrClosed
+	}
+	s.nextID++
+	id := json.RawMessage(strconv.Quote("server-" + strconv.FormatInt(s.nextID, 10)))
+	ch := make(chan Message, 1)
+	s.pending[string(id)] = ch
+	s.lock.Unlock()
+
+	s.write(&Message{
+		JSONRPC: "2.0",
+		ID:      id,
+		Method:  methodElicit,
+		Params:  params,
+	})
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return ElicitResult{}, ErrClosed
+		}
+		if resp.Error != nil {
+			return ElicitResult{}, resp.Error
+		}
+		var result ElicitResult
+		return result, json.Unmarshal(resp.Result, &result)
+	case <-ctx.Done():
+		s.lock.Lock()
+		delete(s.pending, string(id))
+		s.lock.Unlock()
+		return ElicitResult{}, ctx.Err()
+	}
+}
+
+func (s *Server) handle(ctx context.Context, msg Message) *Message {
+	if msg.JSONRPC != "2.0" || msg.Method == "" {
+		if len(msg.ID) == 0 {
+			return nil
+		}
+		return errorResponse(msg.ID, codeInvalidRequest, "invalid request")
+	}
+
+	// Notifications, such as notifications/initialized, need no response.
+	if len(msg.ID) == 0 {
+		return nil
+	}
+
+	var (
+		result any
+		err    error
+	)
+	switch msg.Method {
+	case methodInitialize:
+		var params initializeParams
+		_ = json.Unmarshal(msg.Params, &params)
+		// Use the version that the client asked for if it is supported, otherwise offer the newest version
+		protocolVersion := params.ProtocolVersion
+		if !slices.Contains(SupportedProtocolVersions, protocolVersion) {
+			protocolVersion = ProtocolVersion
+		}
+		if session, ok := ctx.Value(sessionKey{}).(*session); ok {
+			session.lock.Lock()
+			session.capabilities = params.Capabilities
+			session.version = protocolVersion
+			session.lock.Unlock()
+		}
+		result = initializeResult{
+			ProtocolVersion: protocolVersion,
+			Capabilities: map[string]any{
+				"tools": map[string]any{},
+			},
+			ServerInfo:   s.Info,
+			Instructions: s.Instructions,
+		}
+	case methodPing:
+		result = map[string]any{}
+	case methodToolsList:
+		var tools []Tool
+		tools, err = s.Handler.ListTools(ctx)
+		if tools == nil {
+			tools = []Tool{}
+		}
+		result = listToolsResult{Tools: tools}
+	case methodToolsCall:
+		var params callToolParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil || params.Name == "" {
+			return errorResponse(msg.ID, codeInvalidParams, "invalid params for "+methodToolsCall)
+		}
+		var callResult CallToolResult
+		callResult, err = s.Handler.CallTool(ctx, params.Name, params.Arguments)
+		if callResult.Content == nil {
+			callResult.Content = []Content{}
+		}
+		result = callResult
+	default:
+		return errorResponse(msg.ID, codeMethodNotFound, "method not found: "+msg.Method)
+	}
+
+	if err != nil {
+		return errorResponse(msg.ID, codeInternalError, err.Error())
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(msg.ID, codeInternalError, err.Error())
+	}
+
+	return &Message{
+		JSONRPC: "2.0",
+		ID:      msg.ID,
+		Result:  data,
+	}
+}
+
+func errorResponse(id json.RawMessage, code int, message string) *Message {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &Message{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error: &Error{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
+
+// ServeStdio reads newline delimited messages from r and writes responses to w until r is closed. Requests are
+// handled concurrently, and tool handlers can use Elicit to ask the client for input.
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		writeLock sync.Mutex
+		wg        sync.WaitGroup
+		enc       = json.NewEncoder(w)
+	)
+
+	write := func(msg *Message) {
+		if msg == nil {
+			return
+		}
+		writeLock.Lock()
+		defer writeLock.Unlock()
+		_ = enc.Encode(msg)
+	}
+
+	sess := &session{
+		write:   write,
+		pending: map[string]chan Message{},
+	}
+	ctx = context.WithValue(ctx, sessionKey{}, sess)
+
+	// Once the client is gone there is no one to send results to, so stop the calls in progress.
+	defer func() {
+		sess.close()
+		cancel()
+		wg.Wait()
+	}()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var msg Message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			write(errorResponse(nil, codeParseError, err.Error()))
+			continue
+		}
+
+		if msg.isResponse() {
+			sess.deliver(msg)
+			continue
+		} else if msg.Method == "" {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			write(s.handle(ctx, msg))
+		}()
+	}
+
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// ServeHTTP implements the streamable HTTP transport. Each response is returned as a single JSON document.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var msg Message
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse(nil, codeParseError, err.Error()))
+		return
+	}
+
+	resp := s.handle(r.Context(), msg)
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, msg *Message) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(msg)
+}
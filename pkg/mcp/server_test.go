@@ -0,0 +1,157 @@
+package mcp_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/gptscript-ai/gptscript/pkg/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+type elicitHandler struct{}
+
+func (elicitHandler) ListTools(context.Context) ([]mcp.Tool, error) {
+	return []mcp.Tool{{Name: "ask"}}, nil
+}
+
+func (elicitHandler) CallTool(ctx context.Context, _ string, _ json.RawMessage) (mcp.CallToolResult, error) {
+	result, err := mcp.Elicit(ctx, mcp.ElicitRequest{
+		Message: "Name?",
+		RequestedSchema: mcp.ElicitSchema{
+			Properties: map[string]mcp.ElicitProperty{
+				"name": {Type: "string"},
+			},
+		},
+	})
+	if err != nil {
+		return mcp.ErrorResult(err.Error()), nil
+	}
+	return mcp.TextResult(result.Action + " " + result.Content["name"].(string)), nil
+}
+
+func startStdioServer(t *testing.T) (*json.Encoder, *bufio.Scanner) {
+	t.Helper()
+
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+
+	server := &mcp.Server{
+		Info:    mcp.Implementation{Name: "test"},
+		Handler: elicitHandler{},
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.ServeStdio(context.Background(), serverR, serverW)
+	}()
+	t.Cleanup(func() {
+		_ = clientW.Close()
+		require.NoError(t, <-done)
+		_ = serverW.Close()
+	})
+
+	return json.NewEncoder(clientW), bufio.NewScanner(clientR)
+}
+
+func readMessage(t *testing.T, scanner *bufio.Scanner) mcp.Message {
+	t.Helper()
+	require.True(t, scanner.Scan())
+	var msg mcp.Message
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &msg))
+	return msg
+}
+
+func TestServeStdioElicit(t *testing.T) {
+	enc, scanner := startStdioServer(t)
+
+	require.NoError(t, enc.Encode(mcp.Message{
+		JSONRPC: "2.0",
+		ID:      json.RawMessage("1"),
+		Method:  "initialize",
+		Params:  json.RawMessage(`{"capabilities":{"elicitation":{}}}`),
+	}))
+	require.Nil(t, readMessage(t, scanner).Error)
+
+	require.NoError(t, enc.Encode(mcp.Message{
+		JSONRPC: "2.0",
+		ID:      json.RawMessage("2"),
+		Method:  "tools/call",
+		Params:  json.RawMessage(`{"name":"ask"}`),
+	}))
+
+	req := readMessage(t, scanner)
+	require.Equal(t, "elicitation/create", req.Method)
+	require.JSONEq(t, `{"message":"Name?","requestedSchema":{"type":"object","properties":{"name":{"type":"string"}}}}`, string(req.Params))
+
+	require.NoError(t, enc.Encode(mcp.Message{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  json.RawMessage(`{"action":"accept","content":{"name":"bob"}}`),
+	}))
+
+	resp := readMessage(t, scanner)
+	require.Equal(t, "2", string(resp.ID))
+	require.JSONEq(t, `{"content":[{"type":"text","text":"accept bob"}]}`, string(resp.Result))
+}
+
+func TestServeStdioElicitUnsupported(t *testing.T) {
+	enc, scanner := startStdioServer(t)
+
+	require.NoError(t, enc.Encode(mcp.Message{
+		JSONRPC: "2.0",
+		ID:      json.RawMessage("1"),
+		Method:  "initialize",
+		Params:  json.RawMessage(`{"capabilities":{}}`),
+	}))
+	require.Nil(t, readMessage(t, scanner).Error)
+
+	require.NoError(t, enc.Encode(mcp.Message{
+		JSONRPC: "2.0",
+		ID:      json.RawMessage("2"),
+		Method:  "tools/call",
+		Params:  json.RawMessage(`{"name":"ask"}`),
+	}))
+
+	var result mcp.CallToolResult
+	require.NoError(t, json.Unmarshal(readMessage(t, scanner).Result, &result))
+	require.True(t, result.IsError)
+	require.Equal(t, mcp.ErrElicitationUnsupported.Error(), result.String())
+}
+
+func TestServeStdioNegotiateVersion(t *testing.T) {
+	enc, scanner := startStdioServer(t)
+
+	initialize := func(id, version string) string {
+		t.Helper()
+		require.NoError(t, enc.Encode(mcp.Message{
+			JSONRPC: "2.0",
+			ID:      json.RawMessage(id),
+			Method:  "initialize",
+			Params:  json.RawMessage(`{"protocolVersion":"` + version + `","capabilities":{"elicitation":{}}}`),
+		}))
+		var result struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		require.NoError(t, json.Unmarshal(readMessage(t, scanner).Result, &result))
+		return result.ProtocolVersion
+	}
+
+	require.Equal(t, mcp.ProtocolVersion, initialize("1", "1999-01-01"))
+	require.Equal(t, "2024-11-05", initialize("2", "2024-11-05"))
+
+	// Elicitation is not part of the older version, even if the client says that it supports it
+	require.NoError(t, enc.Encode(mcp.Message{
+		JSONRPC: "2.0",
+		ID:      json.RawMessage("3"),
+		Method:  "tools/call",
+		Params:  json.RawMessage(`{"name":"ask"}`),
+	}))
+
+	var result mcp.CallToolResult
+	require.NoError(t, json.Unmarshal(readMessage(t, scanner).Result, &result))
+	require.True(t, result.IsError)
+	require.Equal(t, mcp.ErrElicitationUnsupported.Error(), result.String())
+}
@@ -0,0 +1,134 @@
+// Package mock is a model provider that responds with the turns of a script file instead of calling an LLM, so that
+// scripts can run without an API key, such as in CI. Its models are named mock:<script-file>.
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gptscript-ai/gptscript/pkg/engine"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+)
+
+// Prefix is the start of the names of the models of the provider, which are followed by the script file.
+const Prefix = "mock:"
+
+// mainTool is the name of the turns of the tools without a name.
+const mainTool = "main"
+
+// IsModel returns whether the model is one of the provider.
+func IsModel(modelName string) bool {
+	return strings.HasPrefix(modelName, Prefix)
+}
+
+type Client struct {
+	lock    sync.Mutex
+	scripts map[string]*Script
+	// turns are the turns of each tool of each script that was used, by run, script file and tool name.
+	turns map[turnsKey]*Turns
+}
+
+type turnsKey struct {
+	run, file, tool string
+}
+
+func NewClient() *Client {
+	return &Client{
+		scripts: map[string]*Script{},
+		turns:   map[turnsKey]*Turns{},
+	}
+}
+
+func (c *Client) Supports(_ context.Context, modelName string) (bool, error) {
+	return IsModel(modelName), nil
+}
+
+func (c *Client) ListModels(context.Context, ...string) ([]string, error) {
+	return nil, nil
+}
+
+// Call responds with the next turn of the tool of the request. The Nth request of a tool in a run gets the Nth turn of
+// the tool, so a tool that is called again gets the turns after the ones that were already used. Each run starts with
+// the first turn, except that a chat that continues starts after the responses that it already has.
+func (c *Client) Call(ctx context.Context, messageRequest types.CompletionRequest, status chan<- types.CompletionStatus) (*types.CompletionMessage, error) {
+	file := strings.TrimPrefix(messageRequest.Model, Prefix)
+	script, err := c.load(file)
+	if err != nil {
+		return nil, err
+	}
+
+	key := turnsKey{
+		file: file,
+		tool: mainTool,
+	}
+	runCtx := ctx
+	if engineContext, ok := engine.FromContext(ctx); ok {
+		if engineContext.Tool.Name != "" {
+			key.tool = engineContext.Tool.Name
+		}
+		root := engineContext
+		for root.Parent != nil {
+			root = root.Parent
+		}
+		key.run = root.ID
+		if root.Ctx != nil {
+			runCtx = root.Ctx
+		}
+	}
+
+	turns, ok := script.Tools[key.tool]
+	if !ok {
+		return nil, fmt.Errorf("mock script %s has no turns for tool %s", file, key.tool)
+	}
+
+	c.lock.Lock()
+	toolTurns, ok := c.turns[key]
+	if !ok {
+		toolTurns = NewTurns(turns)
+		toolTurns.next = responses(messageRequest)
+		c.turns[key] = toolTurns
+		context.AfterFunc(runCtx, func() {
+			c.lock.Lock()
+			delete(c.turns, key)
+			c.lock.Unlock()
+		})
+	}
+	c.lock.Unlock()
+
+	msg, err := toolTurns.Call(ctx, messageRequest, status)
+	var outOfTurns *OutOfTurnsError
+	if errors.As(err, &outOfTurns) {
+		return nil, fmt.Errorf("mock script %s ran out of turns for tool %s: %w", file, key.tool, err)
+	}
+	return msg, err
+}
+
+// responses returns the number of responses of the model that the request has.
+func responses(messageRequest types.CompletionRequest) int {
+	var n int
+	for _, msg := range messageRequest.Messages {
+		if msg.Role == types.CompletionMessageRoleTypeAssistant {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *Client) load(file string) (*Script, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if script, ok := c.scripts[file]; ok {
+		return script, nil
+	}
+
+	script, err := LoadScript(file)
+	if err != nil {
+		return nil, err
+	}
+	c.scripts[file] = script
+	return script, nil
+}
@@ -0,0 +1,234 @@
+package mock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gptscript-ai/gptscript/pkg/engine"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+const script = `
+tools:
+  main:
+    - toolCalls:
+        - name: SEARCH
+          arguments: {query: cats}
+        - name: missing
+          arguments: '{"a": 1}'
+    - text: done
+  search:
+    - error: rate limited
+`
+
+func toolContext(name string) context.Context {
+	c := engine.Context{Ctx: context.Background()}
+	c.Tool.Name = name
+	return c.WrappedContext(nil)
+}
+
+func TestCall(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "script.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(script), 0644))
+
+	c := NewClient()
+	ok, err := c.Supports(context.Background(), Prefix+file)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = c.Supports(context.Background(), "gpt-4o")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	req := types.CompletionRequest{
+		Model: Prefix + file,
+		Tools: []types.ChatCompletionTool{
+			{Function: types.CompletionFunctionDefinition{Name: "other"}},
+			{Function: types.CompletionFunctionDefinition{Name: "search"}},
+		},
+		Messages: []types.CompletionMessage{
+			{Role: types.CompletionMessageRoleTypeUser, Content: types.Text("hi")},
+		},
+	}
+
+	// The first tool has no name, so it uses the turns of main.
+	msg, err := c.Call(toolContext(""), req, nil)
+	require.NoError(t, err)
+	require.Len(t, msg.Content, 2)
+	search := msg.Content[0].ToolCall
+	require.Equal(t, "search", search.Function.Name)
+	require.Equal(t, `{"query":"cats"}`, search.Function.Arguments)
+	require.Equal(t, 1, *search.Index)
+	missing := msg.Content[1].ToolCall
+	require.Equal(t, "missing", missing.Function.Name)
+	require.Equal(t, `{"a": 1}`, missing.Function.Arguments)
+	require.Nil(t, missing.Index)
+
+	req.Messages = append(req.Messages, *msg, types.CompletionMessage{Role: types.CompletionMessageRoleTypeTool, Content: types.Text("two cats")})
+	msg, err = c.Call(toolContext("main"), req, nil)
+	require.NoError(t, err)
+	require.Equal(t, "done", msg.String())
+
+	req.Messages = append(req.Messages, *msg)
+	_, err = c.Call(toolContext(""), req, nil)
+	require.ErrorContains(t, err, "ran out of turns for tool main: request 3 has no turn, there are only 2")
+
+	_, err = c.Call(toolContext("search"), types.CompletionRequest{Model: Prefix + file}, nil)
+	require.EqualError(t, err, "rate limited")
+
+	_, err = c.Call(toolContext("other"), types.CompletionRequest{Model: Prefix + file}, nil)
+	require.ErrorContains(t, err, "has no turns for tool other")
+}
+
+func TestCallToolAgain(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "script.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+tools:
+  lookup:
+    - text: first
+    - text: second
+`), 0644))
+
+	c := NewClient()
+	req := types.CompletionRequest{
+		Model: Prefix + file,
+		Messages: []types.CompletionMessage{
+			{Role: types.CompletionMessageRoleTypeUser, Content: types.Text("hi")},
+		},
+	}
+
+	// Each call of the tool starts a new conversation, but gets the next turn.
+	msg, err := c.Call(toolContext("lookup"), req, nil)
+	require.NoError(t, err)
+	require.Equal(t, "first", msg.String())
+
+	msg, err = c.Call(toolContext("lookup"), req, nil)
+	require.NoError(t, err)
+	require.Equal(t, "second", msg.String())
+
+	_, err = c.Call(toolContext("lookup"), req, nil)
+	require.ErrorContains(t, err, "ran out of turns for tool lookup: request 3 has no turn, there are only 2")
+}
+
+func TestCallRuns(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "script.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+tools:
+  main:
+    - text: first
+    - text: second
+`), 0644))
+
+	c := NewClient()
+	req := types.CompletionRequest{Model: Prefix + file}
+	run := func(id string) context.Context {
+		root := engine.Context{Ctx: context.Background()}
+		root.ID = id
+		sub := engine.Context{Ctx: context.Background(), Parent: &root}
+		sub.ID = id + "-sub"
+		return sub.WrappedContext(nil)
+	}
+
+	// Each run starts with the first turn, whichever run finishes first.
+	for _, id := range []string{"1", "2"} {
+		msg, err := c.Call(run(id), req, nil)
+		require.NoError(t, err)
+		require.Equal(t, "first", msg.String())
+	}
+	msg, err := c.Call(run("1"), req, nil)
+	require.NoError(t, err)
+	require.Equal(t, "second", msg.String())
+
+	// A chat that continues in a new run starts after the responses that it has.
+	req.Messages = []types.CompletionMessage{
+		{Role: types.CompletionMessageRoleTypeUser, Content: types.Text("hi")},
+		{Role: types.CompletionMessageRoleTypeAssistant, Content: types.Text("first")},
+		{Role: types.CompletionMessageRoleTypeUser, Content: types.Text("again")},
+	}
+	msg, err = c.Call(run("3"), req, nil)
+	require.NoError(t, err)
+	require.Equal(t, "second", msg.String())
+
+	// The turns of a run are forgotten once it is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	root := engine.Context{Ctx: ctx}
+	root.ID = "4"
+	_, err = c.Call(root.WrappedContext(nil), types.CompletionRequest{Model: Prefix + file}, nil)
+	require.NoError(t, err)
+	cancel()
+	require.Eventually(t, func() bool {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		_, ok := c.turns[turnsKey{run: "4", file: file, tool: mainTool}]
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestCallIDs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "script.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+tools:
+  main:
+    - toolCalls: [{name: lookup}]
+  lookup:
+    - toolCalls: [{name: search}, {name: search}]
+`), 0644))
+
+	c := NewClient()
+	req := types.CompletionRequest{Model: Prefix + file}
+
+	// The IDs of the calls are the IDs of the calls of the tools, so they can't repeat across tools.
+	ids := map[string]bool{}
+	for _, tool := range []string{"main", "lookup"} {
+		msg, err := c.Call(toolContext(tool), req, nil)
+		require.NoError(t, err)
+		for _, part := range msg.Content {
+			require.False(t, ids[part.ToolCall.ID], "duplicate call ID %s", part.ToolCall.ID)
+			ids[part.ToolCall.ID] = true
+		}
+	}
+	require.Len(t, ids, 3)
+}
+
+func TestIgnoredParameters(t *testing.T) {
+	require.Empty(t, ignoredParameters(types.CompletionRequest{Temperature: new(float32), MaxTokens: 10}))
+
+	seed := 42
+	require.Equal(t, []string{"stop sequences", "seed", "reasoning effort"}, ignoredParameters(types.CompletionRequest{
+		Stop:            []string{"END"},
+		Seed:            &seed,
+		ReasoningEffort: "high",
+	}))
+
+	// The responses don't change with the parameters.
+	file := filepath.Join(t.TempDir(), "script.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(script), 0644))
+
+	c := NewClient()
+	msg, err := c.Call(toolContext("main"), types.CompletionRequest{
+		Model: Prefix + file,
+		Stop:  []string{"cats"},
+		Seed:  &seed,
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "SEARCH", msg.Content[0].ToolCall.Function.Name)
+}
+
+func TestLoadScript(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := LoadScript(filepath.Join(dir, "missing.yaml"))
+	require.ErrorContains(t, err, "failed to read mock script")
+
+	file := filepath.Join(dir, "empty.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("tools: {}\n"), 0644))
+	_, err = LoadScript(file)
+	require.ErrorContains(t, err, "has no tools")
+
+	require.NoError(t, os.WriteFile(file, []byte("turns: {}\n"), 0644))
+	_, err = LoadScript(file)
+	require.ErrorContains(t, err, "failed to parse mock script")
+}
@@ -0,0 +1,64 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Script is what the mock model responds with. The requests of each tool get the turns of the tool in order.
+type Script struct {
+	// Tools are the turns of each tool by name. The tools without a name, such as the first tool of most scripts, use
+	// the turns of "main".
+	Tools map[string][]Turn `json:"tools"`
+}
+
+// Turn is a response of the model, which is text, calls of tools, or both, or an error.
+type Turn struct {
+	Text      string     `json:"text,omitempty"`
+	ToolCalls []ToolCall `json:"toolCalls,omitempty"`
+	// Error is returned as the error of the request instead of a response.
+	Error string `json:"error,omitempty"`
+}
+
+type ToolCall struct {
+	Name string `json:"name"`
+	// Arguments are the arguments of the call as a JSON string, or as an object that is converted to JSON.
+	Arguments any `json:"arguments,omitempty"`
+}
+
+// LoadScript reads a script from a YAML or JSON file.
+func LoadScript(file string) (*Script, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mock script: %w", err)
+	}
+
+	var script Script
+	if err := yaml.UnmarshalStrict(data, &script); err != nil {
+		return nil, fmt.Errorf("failed to parse mock script %s: %w", file, err)
+	}
+	if len(script.Tools) == 0 {
+		return nil, fmt.Errorf("mock script %s has no tools", file)
+	}
+
+	return &script, nil
+}
+
+// arguments returns the arguments of the call as JSON.
+func (t ToolCall) arguments() (string, error) {
+	switch args := t.Arguments.(type) {
+	case nil:
+		return "{}", nil
+	case string:
+		return args, nil
+	default:
+		data, err := json.Marshal(args)
+		if err != nil {
+			return "", fmt.Errorf("invalid arguments of the call of %s: %w", t.Name, err)
+		}
+		return string(data), nil
+	}
+}
@@ -0,0 +1,149 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gptscript-ai/gptscript/pkg/counter"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+)
+
+// OutOfTurnsError is returned for a request that comes after all the turns were used.
+type OutOfTurnsError struct {
+	// Request is the number of the request, starting at 1.
+	Request int
+	// Turns is the number of turns that there are.
+	Turns int
+}
+
+func (e *OutOfTurnsError) Error() string {
+	return fmt.Sprintf("request %d has no turn, there are only %d", e.Request, e.Turns)
+}
+
+// Turns is a model that responds to each request with the next turn, in order.
+type Turns struct {
+	lock  sync.Mutex
+	turns []Turn
+	next  int
+}
+
+func NewTurns(turns []Turn) *Turns {
+	return &Turns{
+		turns: turns,
+	}
+}
+
+// Remaining returns the number of turns that weren't used.
+func (t *Turns) Remaining() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return max(len(t.turns)-t.next, 0)
+}
+
+func (t *Turns) Call(_ context.Context, messageRequest types.CompletionRequest, status chan<- types.CompletionStatus) (*types.CompletionMessage, error) {
+	t.lock.Lock()
+	index := t.next
+	t.next++
+	t.lock.Unlock()
+
+	if index >= len(t.turns) {
+		return nil, &OutOfTurnsError{Request: index + 1, Turns: len(t.turns)}
+	}
+
+	if ignored := ignoredParameters(messageRequest); len(ignored) > 0 {
+		log.Debugf("Ignoring the %s of request %d, the response is scripted", strings.Join(ignored, ", "), index+1)
+	}
+
+	turn := t.turns[index]
+	if turn.Error != "" {
+		return nil, errors.New(turn.Error)
+	}
+
+	msg, err := turn.message(messageRequest)
+	if err != nil {
+		return nil, fmt.Errorf("turn %d: %w", index+1, err)
+	}
+
+	if status != nil {
+		id := fmt.Sprint(counter.Next())
+		status <- types.CompletionStatus{
+			CompletionID: id,
+			Request:      messageRequest,
+		}
+		status <- types.CompletionStatus{
+			CompletionID: id,
+			Response:     msg,
+		}
+	}
+	return msg, nil
+}
+
+func (t *Turns) ProxyInfo() (string, string, error) {
+	return "", "", nil
+}
+
+// message returns the response of the turn. The calls of tools that the request has are for the tools by the index of
+// the tool in the request, and the calls of other tools are returned as they are. The IDs of the calls are unique in
+// the process, because they are the IDs of the calls of the tools.
+func (t Turn) message(messageRequest types.CompletionRequest) (*types.CompletionMessage, error) {
+	msg := &types.CompletionMessage{
+		Role: types.CompletionMessageRoleTypeAssistant,
+	}
+	if t.Text != "" || len(t.ToolCalls) == 0 {
+		msg.Content = types.Text(t.Text)
+	}
+
+	for _, call := range t.ToolCalls {
+		args, err := call.arguments()
+		if err != nil {
+			return nil, err
+		}
+		toolCall := &types.CompletionToolCall{
+			ID: "call_" + counter.Next(),
+			Function: types.CompletionFunctionCall{
+				Name:      call.Name,
+				Arguments: args,
+			},
+		}
+		for j, tool := range messageRequest.Tools {
+			if strings.EqualFold(tool.Function.Name, call.Name) {
+				toolCall.Index = &j
+				toolCall.Function.Name = tool.Function.Name
+				break
+			}
+		}
+		msg.Content = append(msg.Content, types.ContentPart{ToolCall: toolCall})
+	}
+
+	return msg, nil
+}
+
+// ignoredParameters returns the sampling and tool call parameters of the request, which have no effect on scripted
+// responses.
+func ignoredParameters(req types.CompletionRequest) (result []string) {
+	if req.TopP != nil {
+		result = append(result, "top p")
+	}
+	if len(req.Stop) > 0 {
+		result = append(result, "stop sequences")
+	}
+	if req.Seed != nil {
+		result = append(result, "seed")
+	}
+	if req.PresencePenalty != nil {
+		result = append(result, "presence penalty")
+	}
+	if req.FrequencyPenalty != nil {
+		result = append(result, "frequency penalty")
+	}
+	if req.ReasoningEffort != "" {
+		result = append(result, "reasoning effort")
+	}
+	if req.ParallelToolCalls != nil {
+		result = append(result, "parallel tool calls")
+	}
+	return
+}
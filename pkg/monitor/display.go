@@ -22,12 +22,14 @@ import (
 type Options struct {
 	DumpState     string `usage:"Dump the internal execution state to a file"`
 	DebugMessages bool   `usage:"Enable logging of chat completion calls"`
+	TraceEndpoint string `usage:"Export OpenTelemetry traces of runs to this OTLP/HTTP endpoint (ex: http://localhost:4318)"`
 }
 
 func Complete(opts ...Options) (result Options) {
 	for _, opt := range opts {
 		result.DumpState = types.FirstSet(opt.DumpState, result.DumpState)
 		result.DebugMessages = types.FirstSet(opt.DebugMessages, result.DebugMessages)
+		result.TraceEndpoint = types.FirstSet(opt.TraceEndpoint, result.TraceEndpoint)
 	}
 	return
 }
@@ -277,6 +279,10 @@ func (d *display) Event(event runner.Event) {
 			Response:     event.ChatResponse,
 			Cached:       event.ChatResponseCached,
 		})
+	case runner.EventTypeCallPolicy:
+		log.Fields("decision", event.Policy.Decision, "rule", event.Policy.RuleID).Infof("policy   [%s]", callName)
+	case runner.EventTypeCallCacheHit:
+		log.Infof("cached   [%s]", callName)
 	case runner.EventTypeCallFinish:
 		d.livePrinter.progressEnd(currentCall)
 		d.livePrinter.end()
@@ -0,0 +1,75 @@
+package monitor
+
+import (
+	"context"
+
+	"github.com/gptscript-ai/gptscript/pkg/runner"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+)
+
+type multiFactory []runner.MonitorFactory
+
+// NewMultiFactory creates a monitor factory that sends the events of each run to the monitors of all the factories.
+func NewMultiFactory(factories ...runner.MonitorFactory) runner.MonitorFactory {
+	var result multiFactory
+	for _, f := range factories {
+		if f != nil {
+			result = append(result, f)
+		}
+	}
+	if len(result) == 1 {
+		return result[0]
+	}
+	return result
+}
+
+func (m multiFactory) Start(ctx context.Context, prg *types.Program, env []string, input string) (runner.Monitor, error) {
+	var monitors multiMonitor
+	for _, f := range m {
+		mon, err := f.Start(ctx, prg, env, input)
+		if err != nil {
+			monitors.Stop(ctx, "", err)
+			return nil, err
+		}
+		monitors = append(monitors, mon)
+	}
+	return monitors, nil
+}
+
+func (m multiFactory) Pause() func() {
+	var unpause []func()
+	for _, f := range m {
+		unpause = append(unpause, f.Pause())
+	}
+	return func() {
+		for _, u := range unpause {
+			u()
+		}
+	}
+}
+
+type multiMonitor []runner.Monitor
+
+func (m multiMonitor) Event(event runner.Event) {
+	for _, mon := range m {
+		mon.Event(event)
+	}
+}
+
+func (m multiMonitor) Pause() func() {
+	var unpause []func()
+	for _, mon := range m {
+		unpause = append(unpause, mon.Pause())
+	}
+	return func() {
+		for _, u := range unpause {
+			u()
+		}
+	}
+}
+
+func (m multiMonitor) Stop(ctx context.Context, output string, err error) {
+	for _, mon := range m {
+		mon.Stop(ctx, output, err)
+	}
+}
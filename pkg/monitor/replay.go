@@ -0,0 +1,120 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gptscript-ai/gptscript/pkg/runner"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+)
+
+type ReplayOptions struct {
+	// Speed is how many times faster than they were recorded the events are replayed. Events are replayed without
+	// waiting between them if Speed is zero.
+	Speed float64
+	// CallID limits the replay to the events of the call with this ID and the calls that it made.
+	CallID string
+}
+
+// Replay reads the events of a file that was written with --events-stream-to and sends them to the monitors of the
+// factory, as if the runs were happening again.
+func Replay(ctx context.Context, r io.Reader, factory runner.MonitorFactory, opts ReplayOptions) error {
+	var (
+		dec = json.NewDecoder(r)
+		// open are the monitors of the runs that have started but not finished. Runs can start while another is
+		// running, such as the runs of credential tools, so call events go to the run that started last.
+		open     []runner.Monitor
+		included = map[string]bool{}
+		last     time.Time
+	)
+	defer func() {
+		for i := len(open) - 1; i >= 0; i-- {
+			open[i].Stop(ctx, "", errors.New("the events file ended before the run finished"))
+		}
+	}()
+
+	start := func(prg *types.Program, input string) (runner.Monitor, error) {
+		if prg == nil {
+			prg = &types.Program{}
+		}
+		mon, err := factory.Start(ctx, prg, nil, input)
+		if err != nil {
+			return nil, err
+		}
+		open = append(open, mon)
+		return mon, nil
+	}
+
+	for i := 1; ; i++ {
+		var e Event
+		if err := dec.Decode(&e); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read event %d: %w", i, err)
+		}
+
+		if e.Type != runner.EventTypeRunStart && e.Type != runner.EventTypeRunFinish {
+			if e.CallContext == nil {
+				continue
+			}
+			if opts.CallID != "" {
+				if e.CallContext.ID == opts.CallID || included[e.CallContext.ParentID] {
+					included[e.CallContext.ID] = true
+				} else {
+					continue
+				}
+			}
+		}
+
+		if err := wait(ctx, last, e.Time, opts.Speed); err != nil {
+			return err
+		}
+		last = e.Time
+
+		switch e.Type {
+		case runner.EventTypeRunStart:
+			if _, err := start(e.Program, e.Input); err != nil {
+				return err
+			}
+		case runner.EventTypeRunFinish:
+			if len(open) == 0 {
+				continue
+			}
+			var err error
+			if e.Err != "" {
+				err = errors.New(e.Err)
+			}
+			open[len(open)-1].Stop(ctx, e.Output, err)
+			open = open[:len(open)-1]
+		default:
+			// The events file doesn't start at the beginning of a run, so start one for the events that are in it.
+			if len(open) == 0 {
+				if _, err := start(e.Program, e.Input); err != nil {
+					return err
+				}
+			}
+			open[len(open)-1].Event(e.Event)
+		}
+	}
+}
+
+// wait sleeps for the time between two events, divided by speed.
+func wait(ctx context.Context, last, next time.Time, speed float64) error {
+	if speed <= 0 || last.IsZero() || !next.After(last) {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(time.Duration(float64(next.Sub(last)) / speed))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
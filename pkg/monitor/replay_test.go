@@ -0,0 +1,115 @@
+package monitor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gptscript-ai/gptscript/pkg/runner"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	runs []*recordedRun
+}
+
+type recordedRun struct {
+	program *types.Program
+	events  []runner.Event
+	output  string
+	err     error
+	stopped bool
+}
+
+func (r *recorder) Start(_ context.Context, prg *types.Program, _ []string, _ string) (runner.Monitor, error) {
+	run := &recordedRun{program: prg}
+	r.runs = append(r.runs, run)
+	return run, nil
+}
+
+func (r *recorder) Pause() func() {
+	return func() {}
+}
+
+func (r *recordedRun) Event(event runner.Event) {
+	r.events = append(r.events, event)
+}
+
+func (r *recordedRun) Pause() func() {
+	return func() {}
+}
+
+func (r *recordedRun) Stop(_ context.Context, output string, err error) {
+	r.output = output
+	r.err = err
+	r.stopped = true
+}
+
+const replayEvents = `{"time":"2024-01-01T00:00:00Z","type":"runStart","program":{"name":"test.gpt"}}
+
+{"time":"2024-01-01T00:00:01Z","type":"callStart","callContext":{"id":"1","tool":{"name":"main"},"inputContext":null}}
+
+{"time":"2024-01-01T00:00:01Z","type":"callStart","callContext":{"id":"2","parentID":"1","tool":{"name":"sub"},"inputContext":null}}
+
+{"time":"2024-01-01T00:00:01Z","type":"callStart","callContext":{"id":"3","parentID":"2","tool":{"name":"subsub"},"inputContext":null}}
+
+{"time":"2024-01-01T00:00:01Z","type":"callFinish","callContext":{"id":"3","parentID":"2","tool":{"name":"subsub"},"inputContext":null}}
+
+{"time":"2024-01-01T00:00:01Z","type":"callFinish","callContext":{"id":"2","parentID":"1","tool":{"name":"sub"},"inputContext":null}}
+
+{"time":"2024-01-01T00:00:01Z","type":"callStart","callContext":{"id":"4","parentID":"1","tool":{"name":"other"},"inputContext":null}}
+
+{"time":"2024-01-01T00:00:01Z","type":"callFinish","callContext":{"id":"1","tool":{"name":"main"},"inputContext":null}}
+
+{"time":"2024-01-01T00:00:01Z","type":"runFinish","output":"done","err":"failed"}
+
+{"time":"2024-01-01T00:00:02Z","type":"runStart","program":{"name":"second.gpt"}}
+
+{"time":"2024-01-01T00:00:02Z","type":"callStart","callContext":{"id":"5","tool":{"name":"main"},"inputContext":null}}
+`
+
+func TestReplay(t *testing.T) {
+	rec := &recorder{}
+	require.NoError(t, Replay(context.Background(), strings.NewReader(replayEvents), rec, ReplayOptions{}))
+	require.Len(t, rec.runs, 2)
+
+	first := rec.runs[0]
+	require.Equal(t, "test.gpt", first.program.Name)
+	require.Len(t, first.events, 7)
+	require.Equal(t, time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC), first.events[0].Time)
+	require.True(t, first.stopped)
+	require.Equal(t, "done", first.output)
+	require.EqualError(t, first.err, "failed")
+
+	// Runs that don't finish in the file are stopped with an error.
+	second := rec.runs[1]
+	require.Len(t, second.events, 1)
+	require.True(t, second.stopped)
+	require.Error(t, second.err)
+}
+
+func TestReplayCallID(t *testing.T) {
+	rec := &recorder{}
+	require.NoError(t, Replay(context.Background(), strings.NewReader(replayEvents), rec, ReplayOptions{CallID: "2"}))
+	require.Len(t, rec.runs, 2)
+
+	var ids []string
+	for _, e := range rec.runs[0].events {
+		ids = append(ids, e.CallContext.ID)
+	}
+	require.Equal(t, []string{"2", "3", "3", "2"}, ids)
+	require.Empty(t, rec.runs[1].events)
+}
+
+func TestReplaySpeed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// At the recorded speed, the replay waits a second for the first call to start.
+	rec := &recorder{}
+	err := Replay(ctx, strings.NewReader(replayEvents), rec, ReplayOptions{Speed: 1})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Empty(t, rec.runs[0].events)
+}
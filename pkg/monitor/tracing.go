@@ -0,0 +1,300 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gptscript-ai/gptscript/pkg/runner"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/gptscript-ai/gptscript/pkg/version"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/gptscript-ai/gptscript/pkg/monitor"
+
+type tracingFactory struct {
+	tracer trace.Tracer
+}
+
+// NewTracingFactory creates a monitor factory that records each run as a trace of the provider. The run is the root
+// span, with a child span for each call of a tool under the span of the call that made it, and spans for the LLM
+// requests and commands of each call.
+func NewTracingFactory(provider trace.TracerProvider) runner.MonitorFactory {
+	return &tracingFactory{
+		tracer: provider.Tracer(tracerName, trace.WithInstrumentationVersion(version.Get().String())),
+	}
+}
+
+// NewOTLPTracingFactory creates a monitor factory that exports traces of runs to an OTLP/HTTP endpoint, such as
+// http://localhost:4318. The returned function flushes the spans that haven't been exported yet and stops exporting.
+func NewOTLPTracingFactory(ctx context.Context, endpoint string) (runner.MonitorFactory, func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", version.ProgramName),
+			attribute.String("service.version", version.Get().String()),
+		)),
+	)
+	return NewTracingFactory(provider), provider.Shutdown, nil
+}
+
+func (t *tracingFactory) Start(ctx context.Context, prg *types.Program, _ []string, _ string) (runner.Monitor, error) {
+	attrs := []attribute.KeyValue{
+		attribute.String("gptscript.program", prg.Name),
+	}
+	if tool, ok := prg.ToolSet[prg.EntryToolID]; ok {
+		attrs = append(attrs, attribute.String("gptscript.tool.name", tool.Name))
+	}
+
+	ctx, span := t.tracer.Start(ctx, "run", trace.WithAttributes(attrs...))
+	return &tracingMonitor{
+		tracer:      t.tracer,
+		ctx:         ctx,
+		root:        span,
+		calls:       map[string]*callSpan{},
+		completions: map[string]*completionSpan{},
+	}, nil
+}
+
+func (t *tracingFactory) Pause() func() {
+	return func() {}
+}
+
+type callSpan struct {
+	ctx  context.Context
+	span trace.Span
+}
+
+type completionSpan struct {
+	callID  string
+	command bool
+	span    trace.Span
+}
+
+type tracingMonitor struct {
+	tracer      trace.Tracer
+	ctx         context.Context
+	root        trace.Span
+	lock        sync.Mutex
+	calls       map[string]*callSpan
+	completions map[string]*completionSpan
+}
+
+func (t *tracingMonitor) Event(event runner.Event) {
+	if event.CallContext == nil {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	switch event.Type {
+	case runner.EventTypeCallStart:
+		t.startCall(event)
+	case runner.EventTypeChat:
+		t.chat(event)
+	case runner.EventTypeCallCacheHit:
+		if call, ok := t.calls[event.CallContext.ID]; ok {
+			call.span.SetAttributes(attribute.Bool("gptscript.cached", true))
+			call.span.AddEvent("cache hit", trace.WithTimestamp(event.Time))
+		}
+	case runner.EventTypeCallPolicy:
+		if call, ok := t.calls[event.CallContext.ID]; ok && event.Policy != nil {
+			call.span.AddEvent("policy", trace.WithTimestamp(event.Time), trace.WithAttributes(
+				attribute.String("gptscript.policy.decision", string(event.Policy.Decision)),
+				attribute.String("gptscript.policy.rule", event.Policy.RuleID),
+			))
+		}
+	case runner.EventTypeCallFinish:
+		t.finishCall(event.CallContext.ID, event.Time, "")
+	}
+}
+
+func (t *tracingMonitor) startCall(event runner.Event) {
+	callCtx := event.CallContext
+	if _, ok := t.calls[callCtx.ID]; ok {
+		return
+	}
+
+	ctx := t.ctx
+	if parent, ok := t.calls[callCtx.ParentID]; ok {
+		ctx = parent.ctx
+	}
+
+	name := callCtx.ToolName
+	if name == "" {
+		name = callCtx.Tool.Name
+	}
+
+	ctx, span := t.tracer.Start(ctx, "call "+name, trace.WithTimestamp(event.Time), trace.WithAttributes(
+		attribute.String("gptscript.call.id", callCtx.ID),
+		attribute.String("gptscript.call.parent_id", callCtx.ParentID),
+		attribute.String("gptscript.tool.name", name),
+		attribute.String("gptscript.tool.id", callCtx.Tool.ID),
+		attribute.String("gptscript.tool.category", string(callCtx.ToolCategory)),
+	))
+	t.calls[callCtx.ID] = &callSpan{
+		ctx:  ctx,
+		span: span,
+	}
+}
+
+func (t *tracingMonitor) chat(event runner.Event) {
+	completion, ok := t.completions[event.ChatCompletionID]
+	if !ok {
+		completion = t.startCompletion(event)
+		if completion == nil {
+			return
+		}
+	}
+
+	if event.ChatResponse == nil {
+		return
+	}
+
+	if completion.command {
+		recordCommandResponse(completion.span, event.ChatResponse)
+	} else {
+		completion.span.SetAttributes(
+			attribute.Int("gen_ai.usage.input_tokens", event.Usage.PromptTokens),
+			attribute.Int("gen_ai.usage.output_tokens", event.Usage.CompletionTokens),
+			attribute.Bool("gptscript.cached", event.ChatResponseCached),
+		)
+		if model := jsonString(event.ChatResponse, "model"); model != "" {
+			completion.span.SetAttributes(attribute.String("gen_ai.response.model", model))
+		}
+	}
+
+	completion.span.End(trace.WithTimestamp(event.Time))
+	delete(t.completions, event.ChatCompletionID)
+}
+
+func (t *tracingMonitor) startCompletion(event runner.Event) *completionSpan {
+	call, ok := t.calls[event.CallContext.ID]
+	if !ok {
+		return nil
+	}
+
+	completion := &completionSpan{
+		callID:  event.CallContext.ID,
+		command: event.CallContext.Tool.IsCommand(),
+	}
+
+	if completion.command {
+		var attrs []attribute.KeyValue
+		if request, ok := event.ChatRequest.(map[string]any); ok {
+			if command, ok := request["command"].([]string); ok && len(command) > 0 {
+				attrs = append(attrs,
+					attribute.String("gptscript.command.interpreter", command[0]),
+					attribute.StringSlice("gptscript.command.args", command[1:]),
+				)
+			}
+		}
+		_, completion.span = t.tracer.Start(call.ctx, "command", trace.WithTimestamp(event.Time), trace.WithAttributes(attrs...))
+	} else {
+		model := jsonString(event.ChatRequest, "model")
+		_, completion.span = t.tracer.Start(call.ctx, strings.TrimSpace("chat "+model), trace.WithTimestamp(event.Time), trace.WithAttributes(
+			attribute.String("gen_ai.operation.name", "chat"),
+			attribute.String("gen_ai.request.model", model),
+		))
+	}
+
+	t.completions[event.ChatCompletionID] = completion
+	return completion
+}
+
+// recordCommandResponse sets the exit status of a command, and marks its span as failed if it didn't succeed.
+func recordCommandResponse(span trace.Span, response any) {
+	resp, ok := response.(map[string]any)
+	if !ok {
+		return
+	}
+
+	exitCode, hasExitCode := resp["exitCode"].(int)
+	if hasExitCode {
+		span.SetAttributes(attribute.Int("gptscript.command.exit_code", exitCode))
+	}
+
+	if err, ok := resp["err"].(error); ok && err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	} else if output, _ := resp["output"].(string); strings.HasPrefix(output, "ERROR:") || (hasExitCode && exitCode != 0) {
+		span.SetStatus(codes.Error, "command failed")
+	}
+}
+
+// finishCall ends the span of the call, and the spans of its requests and commands that haven't finished.
+func (t *tracingMonitor) finishCall(id string, end time.Time, errMsg string) {
+	call, ok := t.calls[id]
+	if !ok {
+		return
+	}
+
+	for completionID, completion := range t.completions {
+		if completion.callID == id {
+			completion.span.End(trace.WithTimestamp(end))
+			delete(t.completions, completionID)
+		}
+	}
+
+	if errMsg != "" {
+		call.span.SetStatus(codes.Error, errMsg)
+	}
+	call.span.End(trace.WithTimestamp(end))
+	delete(t.calls, id)
+}
+
+func (t *tracingMonitor) Pause() func() {
+	return func() {}
+}
+
+func (t *tracingMonitor) Stop(_ context.Context, _ string, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var errMsg string
+	if err != nil {
+		errMsg = err.Error()
+	}
+
+	now := time.Now()
+	for id := range t.calls {
+		t.finishCall(id, now, errMsg)
+	}
+
+	if err != nil {
+		t.root.RecordError(err)
+		t.root.SetStatus(codes.Error, errMsg)
+	}
+	t.root.End(trace.WithTimestamp(now))
+}
+
+// jsonString returns the string field of the value when it is marshaled to JSON.
+func jsonString(v any, field string) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return ""
+	}
+	var result string
+	_ = json.Unmarshal(fields[field], &result)
+	return result
+}
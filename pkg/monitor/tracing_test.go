@@ -0,0 +1,101 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gptscript-ai/gptscript/pkg/engine"
+	"github.com/gptscript-ai/gptscript/pkg/runner"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingMonitor(t *testing.T) {
+	var (
+		exporter = tracetest.NewInMemoryExporter()
+		provider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+		ctx      = context.Background()
+		now      = time.Now()
+	)
+
+	agent := types.Tool{
+		ToolDef: types.ToolDef{Parameters: types.Parameters{Name: "agent"}},
+		ID:      "agent",
+	}
+	command := types.Tool{
+		ToolDef: types.ToolDef{Parameters: types.Parameters{Name: "ls"}, Instructions: "#!/bin/sh -c ls"},
+		ID:      "ls",
+	}
+	prg := &types.Program{
+		Name:        "test.gpt",
+		EntryToolID: "agent",
+		ToolSet: types.ToolSet{
+			"agent": agent,
+			"ls":    command,
+		},
+	}
+
+	callCtx := func(id, parentID string, tool types.Tool) *engine.CallContext {
+		c := &engine.CallContext{ToolName: tool.Name, ParentID: parentID}
+		c.ID = id
+		c.Tool = tool
+		return c
+	}
+	agentCall := callCtx("1", "", agent)
+	commandCall := callCtx("2", "1", command)
+
+	mon, err := NewMultiFactory(NewTracingFactory(provider)).Start(ctx, prg, nil, "input")
+	require.NoError(t, err)
+
+	for _, event := range []runner.Event{
+		{Time: now, Type: runner.EventTypeCallStart, CallContext: agentCall},
+		{Time: now, Type: runner.EventTypeChat, CallContext: agentCall, ChatCompletionID: "c1", ChatRequest: map[string]any{"model": "gpt-4o"}},
+		{Time: now, Type: runner.EventTypeChat, CallContext: agentCall, ChatCompletionID: "c1", ChatResponse: map[string]any{"model": "gpt-4o-2024"}, Usage: types.Usage{PromptTokens: 10, CompletionTokens: 5}, ChatResponseCached: true},
+		{Time: now, Type: runner.EventTypeCallStart, CallContext: commandCall},
+		{Time: now, Type: runner.EventTypeChat, CallContext: commandCall, ChatCompletionID: "c2", ChatRequest: map[string]any{"command": []string{"/bin/sh", "-c", "ls"}}},
+		{Time: now, Type: runner.EventTypeChat, CallContext: commandCall, ChatCompletionID: "c2", ChatResponse: map[string]any{"output": "ERROR: failed", "exitCode": 2}},
+		{Time: now, Type: runner.EventTypeCallFinish, CallContext: commandCall},
+	} {
+		mon.Event(event)
+	}
+	// The call of the agent isn't finished, so it is ended when the run stops.
+	mon.Stop(ctx, "", errors.New("failed"))
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	require.Len(t, spans, 5)
+
+	run, agentSpan, chat, commandSpan, cmd := spans["run"], spans["call agent"], spans["chat gpt-4o"], spans["call ls"], spans["command"]
+
+	require.Equal(t, codes.Error, run.Status.Code)
+	require.False(t, run.Parent.IsValid())
+	require.Equal(t, run.SpanContext.SpanID(), agentSpan.Parent.SpanID())
+	require.Equal(t, agentSpan.SpanContext.SpanID(), chat.Parent.SpanID())
+	require.Equal(t, agentSpan.SpanContext.SpanID(), commandSpan.Parent.SpanID())
+	require.Equal(t, commandSpan.SpanContext.SpanID(), cmd.Parent.SpanID())
+	require.Equal(t, codes.Error, agentSpan.Status.Code)
+	require.Equal(t, codes.Unset, commandSpan.Status.Code)
+
+	require.Subset(t, chat.Attributes, []attribute.KeyValue{
+		attribute.String("gen_ai.request.model", "gpt-4o"),
+		attribute.String("gen_ai.response.model", "gpt-4o-2024"),
+		attribute.Int("gen_ai.usage.input_tokens", 10),
+		attribute.Int("gen_ai.usage.output_tokens", 5),
+		attribute.Bool("gptscript.cached", true),
+	})
+
+	require.Equal(t, codes.Error, cmd.Status.Code)
+	require.Subset(t, cmd.Attributes, []attribute.KeyValue{
+		attribute.String("gptscript.command.interpreter", "/bin/sh"),
+		attribute.StringSlice("gptscript.command.args", []string{"-c", "ls"}),
+		attribute.Int("gptscript.command.exit_code", 2),
+	})
+}
@@ -118,8 +118,18 @@ func New(name string) Logger {
 	return NewWithFields(fields)
 }
 
+var wrapOutput = func(out io.Writer) io.Writer {
+	return out
+}
+
 func SetOutput(out io.Writer) {
-	logrus.SetOutput(out)
+	logrus.SetOutput(wrapOutput(out))
+}
+
+// WrapOutput wraps the current log output, and any output set later, with wrap.
+func WrapOutput(wrap func(io.Writer) io.Writer) {
+	wrapOutput = wrap
+	logrus.SetOutput(wrap(logrus.StandardLogger().Out))
 }
 
 type Logger struct {
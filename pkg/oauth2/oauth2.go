@@ -0,0 +1,364 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	FlowAuthorizationCode = "authorization_code"
+	FlowDeviceCode        = "device_code"
+	FlowClientCredentials = "client_credentials"
+
+	deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+// defaultPollInterval is how often the token endpoint is polled in the device code flow if the server doesn't say.
+var defaultPollInterval = 5 * time.Second
+
+// Config describes an OAuth2 client and the endpoints of its authorization server.
+type Config struct {
+	ClientID      string
+	ClientSecret  string
+	AuthURL       string
+	TokenURL      string
+	DeviceAuthURL string
+	Scopes        []string
+	// RedirectPort is the port of the loopback redirect URL of the authorization code flow. A random port is used if
+	// it is 0.
+	RedirectPort int
+	HTTPClient   *http.Client
+}
+
+// Token is the response of a token request.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresIn    int64     `json:"expires_in,omitempty"`
+	Expiry       time.Time `json:"-"`
+}
+
+// Error is an error response of the authorization server.
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return "oauth2: " + e.Code
+	}
+	return fmt.Sprintf("oauth2: %s: %s", e.Code, e.Description)
+}
+
+func (c Config) client() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c Config) postForm(ctx context.Context, endpoint string, form url.Values, out any) error {
+	form.Set("client_id", c.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+	}
+
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var oauthErr Error
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Code != "" {
+			return &oauthErr
+		}
+		return fmt.Errorf("oauth2: request to %s failed with status %s: %s", endpoint, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	// Some servers, such as GitHub, respond with a form unless JSON is requested, and some ignore the request.
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "application/x-www-form-urlencoded" || mediaType == "text/plain" {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return err
+		}
+		data := map[string]any{}
+		for k := range values {
+			data[k] = values.Get(k)
+		}
+		if v, ok := data["expires_in"].(string); ok {
+			var expiresIn int64
+			_, _ = fmt.Sscan(v, &expiresIn)
+			data["expires_in"] = expiresIn
+		}
+		if body, err = json.Marshal(data); err != nil {
+			return err
+		}
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("oauth2: invalid response from %s: %w", endpoint, err)
+	}
+	return nil
+}
+
+func (c Config) token(ctx context.Context, form url.Values) (*Token, error) {
+	var resp struct {
+		Token
+		Error
+	}
+	if err := c.postForm(ctx, c.TokenURL, form, &resp); err != nil {
+		return nil, err
+	}
+
+	// Servers that return errors with a 200 status code, such as GitHub, aren't following the spec, but they exist.
+	if resp.AccessToken == "" {
+		if resp.Code != "" {
+			return nil, &resp.Error
+		}
+		return nil, errors.New("oauth2: server response is missing access_token")
+	}
+
+	token := resp.Token
+	if token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return &token, nil
+}
+
+// Refresh uses a refresh token to get a new token. The refresh token is kept if the server doesn't return a new one.
+func (c Config) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	token, err := c.token(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// ClientCredentials gets a token for the client itself, without a user.
+func (c Config) ClientCredentials(ctx context.Context) (*Token, error) {
+	form := url.Values{
+		"grant_type": {"client_credentials"},
+	}
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	return c.token(ctx, form)
+}
+
+type deviceAuthResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	// Some servers, such as Google, use verification_url instead
+	VerificationURL string `json:"verification_url,omitempty"`
+	ExpiresIn       int64  `json:"expires_in"`
+	Interval        int64  `json:"interval,omitempty"`
+}
+
+// DeviceCode asks the user to enter a code on another device, with notify, and waits until they have done so.
+func (c Config) DeviceCode(ctx context.Context, notify func(verificationURI, userCode string) error) (*Token, error) {
+	form := url.Values{}
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+
+	var auth deviceAuthResponse
+	if err := c.postForm(ctx, c.DeviceAuthURL, form, &auth); err != nil {
+		return nil, err
+	}
+
+	verificationURI := auth.VerificationURIComplete
+	if verificationURI == "" {
+		verificationURI = auth.VerificationURI
+	}
+	if verificationURI == "" {
+		verificationURI = auth.VerificationURL
+	}
+	if err := notify(verificationURI, auth.UserCode); err != nil {
+		return nil, err
+	}
+
+	if auth.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(auth.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("oauth2: timed out waiting for the device to be authorized: %w", ctx.Err())
+		case <-time.After(interval):
+		}
+
+		token, err := c.token(ctx, url.Values{
+			"grant_type":  {deviceCodeGrantType},
+			"device_code": {auth.DeviceCode},
+		})
+		var oauthErr *Error
+		if errors.As(err, &oauthErr) {
+			switch oauthErr.Code {
+			case "authorization_pending":
+				continue
+			case "slow_down":
+				interval += 5 * time.Second
+				continue
+			}
+		}
+		return token, err
+	}
+}
+
+// AuthorizationCode asks the user to open the authorization URL, with notify, and receives the code on a loopback
+// redirect URL. PKCE is used, so the client secret is optional.
+func (c Config) AuthorizationCode(ctx context.Context, notify func(authURL string) error) (*Token, error) {
+	l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", c.RedirectPort))
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: failed to listen for the redirect: %w", err)
+	}
+	defer l.Close()
+
+	redirectURL := fmt.Sprintf("http://%s/callback", l.Addr().String())
+
+	state, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	authURL, err := url.Parse(c.AuthURL)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: invalid authorization URL: %w", err)
+	}
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", c.ClientID)
+	q.Set("redirect_uri", redirectURL)
+	q.Set("state", state)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	if len(c.Scopes) > 0 {
+		q.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	authURL.RawQuery = q.Encode()
+
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/callback" {
+				http.NotFound(w, r)
+				return
+			}
+
+			query := r.URL.Query()
+			if query.Get("state") != state {
+				// This redirect is not for this flow, so ignore it and keep waiting for the one that is.
+				http.Error(w, "oauth2: invalid state in the redirect", http.StatusBadRequest)
+				return
+			}
+
+			var res result
+			switch {
+			case query.Get("error") != "":
+				res.err = &Error{Code: query.Get("error"), Description: query.Get("error_description")}
+			case query.Get("code") == "":
+				res.err = errors.New("oauth2: the redirect is missing the code")
+			default:
+				res.code = query.Get("code")
+			}
+
+			if res.err != nil {
+				http.Error(w, res.err.Error(), http.StatusBadRequest)
+			} else {
+				_, _ = io.WriteString(w, "Authorization complete, you can close this window.")
+			}
+
+			select {
+			case results <- res:
+			default:
+			}
+		}),
+	}
+	go func() {
+		_ = server.Serve(l)
+	}()
+	defer server.Close()
+
+	if err := notify(authURL.String()); err != nil {
+		return nil, err
+	}
+
+	var res result
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("oauth2: timed out waiting for the authorization: %w", ctx.Err())
+	case res = <-results:
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+
+	return c.token(ctx, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {res.code},
+		"redirect_uri":  {redirectURL},
+		"code_verifier": {verifier},
+	})
+}
+
+func randomString() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
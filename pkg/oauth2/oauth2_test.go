@@ -0,0 +1,195 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testServer struct {
+	t *testing.T
+
+	lock          sync.Mutex
+	challenge     string
+	redirectURI   string
+	pendingChecks int
+}
+
+func (s *testServer) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	require.NoError(s.t, json.NewEncoder(w).Encode(v))
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch r.URL.Path {
+	case "/authorize":
+		q := r.URL.Query()
+		s.challenge = q.Get("code_challenge")
+		s.redirectURI = q.Get("redirect_uri")
+		http.Redirect(w, r, s.redirectURI+"?"+url.Values{"code": {"the-code"}, "state": {q.Get("state")}}.Encode(), http.StatusFound)
+	case "/device":
+		s.writeJSON(w, http.StatusOK, map[string]any{
+			"device_code":      "the-device-code",
+			"user_code":        "ABCD-1234",
+			"verification_uri": "https://example.com/device",
+			"interval":         0,
+		})
+	case "/token":
+		require.NoError(s.t, r.ParseForm())
+		user, pass, _ := r.BasicAuth()
+		if r.Form.Get("client_id") != "client" || (pass != "" && (user != "client" || pass != "secret")) {
+			s.writeJSON(w, http.StatusUnauthorized, Error{Code: "invalid_client"})
+			return
+		}
+
+		switch r.Form.Get("grant_type") {
+		case "client_credentials":
+			s.writeJSON(w, http.StatusOK, Token{AccessToken: "client-token", ExpiresIn: 3600})
+		case "refresh_token":
+			if r.Form.Get("refresh_token") != "the-refresh-token" {
+				s.writeJSON(w, http.StatusBadRequest, Error{Code: "invalid_grant"})
+				return
+			}
+			s.writeJSON(w, http.StatusOK, Token{AccessToken: "refreshed-token", ExpiresIn: 3600})
+		case deviceCodeGrantType:
+			if s.pendingChecks > 0 {
+				s.pendingChecks--
+				// Respond like GitHub does, with a 200 status code and a form.
+				w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+				_, _ = w.Write([]byte("error=authorization_pending"))
+				return
+			}
+			s.writeJSON(w, http.StatusOK, Token{AccessToken: "device-token", RefreshToken: "the-refresh-token"})
+		case "authorization_code":
+			verifier := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			if r.Form.Get("code") != "the-code" || r.Form.Get("redirect_uri") != s.redirectURI ||
+				base64.RawURLEncoding.EncodeToString(verifier[:]) != s.challenge {
+				s.writeJSON(w, http.StatusBadRequest, Error{Code: "invalid_grant"})
+				return
+			}
+			s.writeJSON(w, http.StatusOK, Token{AccessToken: "user-token", RefreshToken: "the-refresh-token", ExpiresIn: 60})
+		default:
+			s.writeJSON(w, http.StatusBadRequest, Error{Code: "unsupported_grant_type"})
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestConfig(t *testing.T) (Config, *testServer) {
+	s := &testServer{t: t, pendingChecks: 2}
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	return Config{
+		ClientID:      "client",
+		ClientSecret:  "secret",
+		AuthURL:       server.URL + "/authorize",
+		TokenURL:      server.URL + "/token",
+		DeviceAuthURL: server.URL + "/device",
+		Scopes:        []string{"read", "write"},
+	}, s
+}
+
+func TestClientCredentials(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+
+	token, err := cfg.ClientCredentials(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "client-token", token.AccessToken)
+	require.WithinDuration(t, time.Now().Add(time.Hour), token.Expiry, time.Minute)
+
+	cfg.ClientSecret = "wrong"
+	_, err = cfg.ClientCredentials(context.Background())
+	require.ErrorContains(t, err, "invalid_client")
+}
+
+func TestRefresh(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+
+	token, err := cfg.Refresh(context.Background(), "the-refresh-token")
+	require.NoError(t, err)
+	require.Equal(t, "refreshed-token", token.AccessToken)
+	// The server didn't return a new refresh token, so the old one is kept.
+	require.Equal(t, "the-refresh-token", token.RefreshToken)
+
+	_, err = cfg.Refresh(context.Background(), "revoked")
+	var oauthErr *Error
+	require.ErrorAs(t, err, &oauthErr)
+	require.Equal(t, "invalid_grant", oauthErr.Code)
+}
+
+func TestDeviceCode(t *testing.T) {
+	cfg, s := newTestConfig(t)
+	defaultPollInterval = 10 * time.Millisecond
+
+	var uri, code string
+	token, err := cfg.DeviceCode(context.Background(), func(verificationURI, userCode string) error {
+		uri, code = verificationURI, userCode
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/device", uri)
+	require.Equal(t, "ABCD-1234", code)
+	require.Equal(t, "device-token", token.AccessToken)
+	require.True(t, token.Expiry.IsZero())
+	require.Zero(t, s.pendingChecks)
+}
+
+func TestAuthorizationCode(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	cfg.ClientSecret = ""
+
+	token, err := cfg.AuthorizationCode(context.Background(), func(authURL string) error {
+		// Act like the user's browser, which follows the redirect to the loopback callback.
+		resp, err := http.Get(authURL)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	})
+	require.NoError(t, err)
+	require.Equal(t, "user-token", token.AccessToken)
+	require.Equal(t, "the-refresh-token", token.RefreshToken)
+}
+
+func TestAuthorizationCodeIgnoresInvalidState(t *testing.T) {
+	cfg, _ := newTestConfig(t)
+	cfg.ClientSecret = ""
+
+	token, err := cfg.AuthorizationCode(context.Background(), func(authURL string) error {
+		u, err := url.Parse(authURL)
+		if err != nil {
+			return err
+		}
+
+		// A redirect with another state is rejected, but doesn't end the flow.
+		resp, err := http.Get(u.Query().Get("redirect_uri") + "?" + url.Values{"code": {"other-code"}, "state": {"other-state"}}.Encode())
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, err = http.Get(authURL)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	})
+	require.NoError(t, err)
+	require.Equal(t, "user-token", token.AccessToken)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"net/http"
 	"os"
 	"slices"
 	"sort"
@@ -59,6 +60,7 @@ type Options struct {
 	SetSeed      bool   `usage:"-"`
 	CacheKey     string `usage:"-"`
 	Cache        *cache.Client
+	HTTPClient   *http.Client `usage:"-"`
 }
 
 func Complete(opts ...Options) (result Options) {
@@ -70,6 +72,7 @@ func Complete(opts ...Options) (result Options) {
 		result.DefaultModel = types.FirstSet(opt.DefaultModel, result.DefaultModel)
 		result.SetSeed = types.FirstSet(opt.SetSeed, result.SetSeed)
 		result.CacheKey = types.FirstSet(opt.CacheKey, result.CacheKey)
+		result.HTTPClient = types.FirstSet(opt.HTTPClient, result.HTTPClient)
 	}
 
 	return result
@@ -115,6 +118,10 @@ func NewClient(ctx context.Context, credStore credentials.CredentialStore, opts
 	cfg := openai.DefaultConfig(opt.APIKey)
 	cfg.BaseURL = types.FirstSet(opt.BaseURL, cfg.BaseURL)
 	cfg.OrgID = types.FirstSet(opt.OrgID, cfg.OrgID)
+	if opt.HTTPClient != nil {
+		cfg.HTTPClient = opt.HTTPClient
+	}
+	cfg.HTTPClient = withExtraFieldsTransport(cfg.HTTPClient)
 
 	cacheKeyBase := opt.CacheKey
 	if cacheKeyBase == "" {
@@ -186,11 +193,15 @@ func (c *Client) ListModels(ctx context.Context, providers ...string) (result []
 	return result, nil
 }
 
-func (c *Client) cacheKey(request openai.ChatCompletionRequest) any {
-	return map[string]any{
+func (c *Client) cacheKey(ctx context.Context, request openai.ChatCompletionRequest) any {
+	key := map[string]any{
 		"base":    c.cacheKeyBase,
 		"request": request,
 	}
+	if fields := extraFields(ctx); len(fields) > 0 {
+		key["extraFields"] = fields
+	}
+	return key
 }
 
 func (c *Client) seed(request openai.ChatCompletionRequest) int {
@@ -212,11 +223,38 @@ func (c *Client) seed(request openai.ChatCompletionRequest) int {
 	return hash.Seed(newRequest)
 }
 
+// requestExtraFields returns the parameters of the request that the client library doesn't have fields for.
+func requestExtraFields(messageRequest types.CompletionRequest, request openai.ChatCompletionRequest) map[string]any {
+	fields := map[string]any{}
+	// The client library leaves out parameters that are zero, so zeros that are set explicitly are sent as extra fields.
+	for name, value := range map[string]*float32{
+		"top_p":             messageRequest.TopP,
+		"presence_penalty":  messageRequest.PresencePenalty,
+		"frequency_penalty": messageRequest.FrequencyPenalty,
+	} {
+		if value != nil && *value == 0 {
+			fields[name] = 0
+		}
+	}
+	if messageRequest.ReasoningEffort != "" {
+		fields["reasoning_effort"] = messageRequest.ReasoningEffort
+	}
+	if messageRequest.ParallelToolCalls != nil {
+		// The API rejects parallel_tool_calls in requests without tools.
+		if len(request.Tools) > 0 {
+			fields["parallel_tool_calls"] = *messageRequest.ParallelToolCalls
+		} else {
+			log.Debugf("ignoring parallel tool calls for model %s, the request has no tools", request.Model)
+		}
+	}
+	return fields
+}
+
 func (c *Client) fromCache(ctx context.Context, messageRequest types.CompletionRequest, request openai.ChatCompletionRequest) (result []openai.ChatCompletionStreamResponse, _ bool, _ error) {
 	if !messageRequest.GetCache() {
 		return nil, false, nil
 	}
-	found, err := c.cache.Get(ctx, c.cacheKey(request), &result)
+	found, err := c.cache.Get(ctx, c.cacheKey(ctx, request), &result)
 	if err != nil {
 		return nil, false, err
 	} else if !found {
@@ -350,6 +388,17 @@ func (c *Client) Call(ctx context.Context, messageRequest types.CompletionReques
 		request.Temperature = messageRequest.Temperature
 	}
 
+	if messageRequest.TopP != nil {
+		request.TopP = *messageRequest.TopP
+	}
+	request.Stop = messageRequest.Stop
+	if messageRequest.PresencePenalty != nil {
+		request.PresencePenalty = *messageRequest.PresencePenalty
+	}
+	if messageRequest.FrequencyPenalty != nil {
+		request.FrequencyPenalty = *messageRequest.FrequencyPenalty
+	}
+
 	if messageRequest.JSONResponse {
 		request.ResponseFormat = &openai.ChatCompletionResponseFormat{
 			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
@@ -388,6 +437,11 @@ func (c *Client) Call(ctx context.Context, messageRequest types.CompletionReques
 			IncludeUsage: true,
 		}
 	}
+	if messageRequest.Seed != nil {
+		request.Seed = messageRequest.Seed
+	}
+
+	ctx = withExtraFields(ctx, requestExtraFields(messageRequest, request))
 	response, ok, err := c.fromCache(ctx, messageRequest, request)
 	if err != nil {
 		return nil, err
@@ -592,7 +646,7 @@ func (c *Client) call(ctx context.Context, request openai.ChatCompletionRequest,
 	for {
 		response, err := stream.Recv()
 		if err == io.EOF {
-			return responses, c.cache.Store(ctx, c.cacheKey(request), responses)
+			return responses, c.cache.Store(ctx, c.cacheKey(ctx, request), responses)
 		} else if err != nil {
 			return nil, err
 		}
@@ -1,12 +1,19 @@
 package openai
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	openai "github.com/gptscript-ai/chat-completion-client"
+	"github.com/gptscript-ai/gptscript/pkg/cache"
 	"github.com/gptscript-ai/gptscript/pkg/types"
 	"github.com/hexops/autogold/v2"
 	"github.com/hexops/valast"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_appendMessage(t *testing.T) {
@@ -48,3 +55,78 @@ func Test_appendMessage(t *testing.T) {
 		},
 	}))
 }
+
+func TestCallModelParameters(t *testing.T) {
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]any{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies = append(bodies, body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"id":"1","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+	t.Setenv("GPTSCRIPT_INTERNAL_OPENAI_STREAMING", "false")
+
+	cacheClient, err := cache.New(cache.Options{CacheDir: t.TempDir(), DisableCache: true})
+	require.NoError(t, err)
+	c, err := NewClient(context.Background(), nil, Options{
+		APIKey:  "key",
+		BaseURL: srv.URL,
+		Cache:   cacheClient,
+		SetSeed: true,
+	})
+	require.NoError(t, err)
+
+	call := func(request types.CompletionRequest) map[string]any {
+		t.Helper()
+		status := make(chan types.CompletionStatus, 10)
+		request.Model = "test"
+		request.Messages = []types.CompletionMessage{{Role: types.CompletionMessageRoleTypeUser, Content: types.Text("hi")}}
+		_, err := c.Call(context.Background(), request, status)
+		require.NoError(t, err)
+		return bodies[len(bodies)-1]
+	}
+
+	body := call(types.CompletionRequest{
+		TopP:              valast.Ptr(float32(0.5)),
+		Stop:              []string{"END"},
+		Seed:              valast.Ptr(42),
+		PresencePenalty:   valast.Ptr(float32(0.25)),
+		FrequencyPenalty:  valast.Ptr(float32(-0.5)),
+		ReasoningEffort:   "low",
+		ParallelToolCalls: valast.Ptr(false),
+		Tools: []types.ChatCompletionTool{{
+			Function: types.CompletionFunctionDefinition{Name: "tool"},
+		}},
+	})
+	require.Equal(t, 0.5, body["top_p"])
+	require.Equal(t, []any{"END"}, body["stop"])
+	require.Equal(t, float64(42), body["seed"])
+	require.Equal(t, 0.25, body["presence_penalty"])
+	require.Equal(t, -0.5, body["frequency_penalty"])
+	require.Equal(t, "low", body["reasoning_effort"])
+	require.Equal(t, false, body["parallel_tool_calls"])
+
+	// Without tools, parallel tool calls are ignored, and the seed is derived from the request.
+	body = call(types.CompletionRequest{
+		ParallelToolCalls: valast.Ptr(false),
+	})
+	require.NotContains(t, body, "parallel_tool_calls")
+	require.NotContains(t, body, "reasoning_effort")
+	require.NotContains(t, body, "top_p")
+	require.NotEqual(t, float64(42), body["seed"])
+	require.Contains(t, body, "seed")
+	require.NotContains(t, body, "presence_penalty")
+	require.NotContains(t, body, "frequency_penalty")
+
+	// Zeros that are set explicitly are sent.
+	body = call(types.CompletionRequest{
+		TopP:             valast.Ptr(float32(0)),
+		PresencePenalty:  valast.Ptr(float32(0)),
+		FrequencyPenalty: valast.Ptr(float32(0)),
+	})
+	require.Equal(t, float64(0), body["top_p"])
+	require.Equal(t, float64(0), body["presence_penalty"])
+	require.Equal(t, float64(0), body["frequency_penalty"])
+}
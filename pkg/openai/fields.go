@@ -0,0 +1,77 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+type extraFieldsKey struct{}
+
+// withExtraFields returns a context with fields that are added to the body of the requests that are sent with it.
+// They are for the parameters of the chat completion API that the client library doesn't have fields for.
+func withExtraFields(ctx context.Context, fields map[string]any) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, extraFieldsKey{}, fields)
+}
+
+func extraFields(ctx context.Context) map[string]any {
+	fields, _ := ctx.Value(extraFieldsKey{}).(map[string]any)
+	return fields
+}
+
+// extraFieldsTransport adds the extra fields of the context of a request to its JSON body.
+type extraFieldsTransport struct {
+	next http.RoundTripper
+}
+
+func (t *extraFieldsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fields := extraFields(req.Context())
+	if len(fields) == 0 || req.Body == nil {
+		return t.next.RoundTrip(req)
+	}
+
+	data, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, err
+	}
+	for key, value := range fields {
+		if body[key], err = json.Marshal(value); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err = json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.ContentLength = int64(len(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return t.next.RoundTrip(req)
+}
+
+// withExtraFieldsTransport returns a copy of the client that adds the extra fields of the context to requests.
+func withExtraFieldsTransport(client *http.Client) *http.Client {
+	cp := *client
+	next := cp.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	cp.Transport = &extraFieldsTransport{next: next}
+	return &cp
+}
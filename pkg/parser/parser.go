@@ -10,6 +10,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/gptscript-ai/gptscript/pkg/types"
@@ -51,6 +52,19 @@ func csv(line string) (result []string) {
 	return
 }
 
+// stopSequence is the value of a stop directive, which is a single sequence. Commas are part of the sequence, and a
+// sequence in double quotes can have escapes such as \n and leading or trailing spaces.
+func stopSequence(value string) (string, error) {
+	if !strings.HasPrefix(value, `"`) {
+		return value, nil
+	}
+	stop, err := strconv.Unquote(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid stop sequence %s: %w", value, err)
+	}
+	return stop, nil
+}
+
 func addArg(line string, tool *types.Tool) error {
 	if tool.Parameters.Arguments == nil {
 		tool.Parameters.Arguments = &openapi3.Schema{
@@ -138,6 +152,13 @@ func isParam(line string, tool *types.Tool) (_ bool, err error) {
 			return false, err
 		}
 		tool.Parameters.Cache = &b
+	case "cachettl":
+		tool.Parameters.CacheTTL, err = time.ParseDuration(value)
+		if err != nil {
+			return false, err
+		}
+	case "cacheenv", "cacheenvs":
+		tool.Parameters.CacheEnv = append(tool.Parameters.CacheEnv, csv(value)...)
 	case "jsonmode", "json", "jsonoutput", "jsonformat", "jsonresponse":
 		tool.Parameters.JSONResponse, err = toBool(value)
 		if err != nil {
@@ -148,6 +169,46 @@ func isParam(line string, tool *types.Tool) (_ bool, err error) {
 		if err != nil {
 			return false, err
 		}
+	case "topp":
+		tool.Parameters.TopP, err = toFloatPtr(value)
+		if err != nil {
+			return false, err
+		}
+	case "stop", "stops", "stopsequence", "stopsequences":
+		stop, err := stopSequence(value)
+		if err != nil {
+			return false, err
+		}
+		tool.Parameters.Stop = append(tool.Parameters.Stop, stop)
+	case "seed":
+		seed, err := strconv.Atoi(value)
+		if err != nil {
+			return false, err
+		}
+		tool.Parameters.Seed = &seed
+	case "presencepenalty":
+		tool.Parameters.PresencePenalty, err = toFloatPtr(value)
+		if err != nil {
+			return false, err
+		}
+	case "frequencypenalty":
+		tool.Parameters.FrequencyPenalty, err = toFloatPtr(value)
+		if err != nil {
+			return false, err
+		}
+	case "reasoningeffort":
+		switch effort := strings.ToLower(value); effort {
+		case "low", "medium", "high":
+			tool.Parameters.ReasoningEffort = effort
+		default:
+			return false, fmt.Errorf("invalid reasoning effort %q, use low, medium, or high", value)
+		}
+	case "paralleltoolcalls":
+		b, err := toBool(value)
+		if err != nil {
+			return false, err
+		}
+		tool.Parameters.ParallelToolCalls = &b
 	case "credentials", "creds", "credential", "cred":
 		tool.Parameters.Credentials = append(tool.Parameters.Credentials, value)
 	case "sharecredentials", "sharecreds", "sharecredential", "sharecred", "sharedcredentials", "sharedcreds", "sharedcredential", "sharedcred":
@@ -241,6 +241,50 @@ share output filters: shared
 	}}).Equal(t, out)
 }
 
+func TestParseModelParameters(t *testing.T) {
+	input := `
+name: sampler
+top p: 0.5
+stop: ###, END
+stop: "\n\n"
+stop: " Q:"
+seed: 42
+presence penalty: 0.25
+frequency penalty: -0.5
+reasoning effort: High
+parallel tool calls: false
+
+Say hi
+`
+	out, err := Parse(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, out.Nodes, 1)
+
+	params := out.Nodes[0].ToolNode.Tool.Parameters
+	require.Equal(t, float32(0.5), *params.TopP)
+	require.Equal(t, []string{"###, END", "\n\n", " Q:"}, params.Stop)
+	require.Equal(t, 42, *params.Seed)
+	require.Equal(t, float32(0.25), *params.PresencePenalty)
+	require.Equal(t, float32(-0.5), *params.FrequencyPenalty)
+	require.Equal(t, "high", params.ReasoningEffort)
+	require.False(t, *params.ParallelToolCalls)
+
+	// The parameters are kept when the tool is written out and parsed again.
+	again, err := Parse(strings.NewReader(out.Nodes[0].ToolNode.Tool.String()))
+	require.NoError(t, err)
+	require.Equal(t, params, again.Nodes[0].ToolNode.Tool.Parameters)
+}
+
+func TestParseInvalidReasoningEffort(t *testing.T) {
+	_, err := Parse(strings.NewReader("reasoning effort: extreme\n\nSay hi\n"))
+	require.ErrorContains(t, err, `invalid reasoning effort "extreme", use low, medium, or high`)
+}
+
+func TestParseInvalidStop(t *testing.T) {
+	_, err := Parse(strings.NewReader("stop: \"END\n\nSay hi\n"))
+	require.ErrorContains(t, err, `invalid stop sequence "END`)
+}
+
 func TestParseMetaData(t *testing.T) {
 	input := `
 name: first
@@ -0,0 +1,320 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+type Decision string
+
+const (
+	Allow Decision = "allow"
+	Deny  Decision = "deny"
+	Ask   Decision = "ask"
+)
+
+// restrictiveness orders the decisions, so that the most restrictive one wins when several policies are evaluated.
+var restrictiveness = map[Decision]int{
+	Allow: 0,
+	Ask:   1,
+	Deny:  2,
+}
+
+// Policy is a list of rules that decide whether a tool call is allowed. The first rule that matches a call decides,
+// and Default decides if none do.
+type Policy struct {
+	Default Decision `json:"default,omitempty"`
+	Rules   []Rule   `json:"rules,omitempty"`
+}
+
+// Rule matches a tool call if all of its criteria that are set match. Each criterion is a list of glob patterns, and
+// matches if any of them does. In the patterns, * and ? don't match /, and ** matches anything. Relative paths are
+// relative to the current directory.
+type Rule struct {
+	ID       string   `json:"id,omitempty"`
+	Decision Decision `json:"decision"`
+	// Message is returned to the LLM instead of the result of the call when the call is denied.
+	Message string `json:"message,omitempty"`
+
+	// Tool matches the name of the tool.
+	Tool []string `json:"tool,omitempty"`
+	// Source matches the location of the tool, such as a file path or github.com/org/repo/tool.gpt.
+	Source []string `json:"source,omitempty"`
+	// Interpreter matches the program that runs a command tool, such as python3 or bash.
+	Interpreter []string `json:"interpreter,omitempty"`
+	// Builtin matches the name of a builtin tool, such as sys.read.
+	Builtin []string `json:"builtin,omitempty"`
+	// Path matches the absolute paths of the files used by builtin tools, such as the filename of sys.read.
+	Path []string `json:"path,omitempty"`
+	// Host matches the host of the URL of sys.http.* and sys.download calls, HTTP tools, and OpenAPI tools.
+	Host []string `json:"host,omitempty"`
+	// Args matches the arguments of the call. The values are regular expressions.
+	Args map[string]string `json:"args,omitempty"`
+
+	compiled *compiledRule
+}
+
+type compiledRule struct {
+	tool, source, interpreter, builtin, path, host []*regexp.Regexp
+	args                                           map[string]*regexp.Regexp
+}
+
+// Result is the decision for a tool call, and the rule that made it, if any.
+type Result struct {
+	Decision Decision `json:"decision"`
+	RuleID   string   `json:"ruleID,omitempty"`
+	Message  string   `json:"message,omitempty"`
+}
+
+// Load reads a policy from a YAML or JSON file.
+func Load(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+	p, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", file, err)
+	}
+	return p, nil
+}
+
+// Parse parses a YAML or JSON policy.
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	if err := p.compile(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (p *Policy) UnmarshalJSON(data []byte) error {
+	type policy Policy
+	if err := json.Unmarshal(data, (*policy)(p)); err != nil {
+		return err
+	}
+	return p.compile()
+}
+
+func validDecision(d Decision) bool {
+	_, ok := restrictiveness[d]
+	return ok
+}
+
+func (p *Policy) compile() error {
+	if p.Default == "" {
+		p.Default = Allow
+	} else if !validDecision(p.Default) {
+		return fmt.Errorf("invalid default decision %q, use %s, %s, or %s", p.Default, Allow, Deny, Ask)
+	}
+
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("rule-%d", i+1)
+		}
+		if !validDecision(rule.Decision) {
+			return fmt.Errorf("invalid decision %q in rule %s, use %s, %s, or %s", rule.Decision, rule.ID, Allow, Deny, Ask)
+		}
+
+		var (
+			c   compiledRule
+			err error
+		)
+		for _, field := range []struct {
+			patterns []string
+			into     *[]*regexp.Regexp
+			isPath   bool
+		}{
+			{rule.Tool, &c.tool, false},
+			{rule.Source, &c.source, false},
+			{rule.Interpreter, &c.interpreter, false},
+			{rule.Builtin, &c.builtin, false},
+			{rule.Path, &c.path, true},
+			{rule.Host, &c.host, false},
+		} {
+			for _, pattern := range field.patterns {
+				if field.isPath {
+					if pattern, err = absPattern(pattern); err != nil {
+						return fmt.Errorf("invalid path %q in rule %s: %w", pattern, rule.ID, err)
+					}
+				}
+				re, err := globToRegexp(pattern)
+				if err != nil {
+					return fmt.Errorf("invalid pattern %q in rule %s: %w", pattern, rule.ID, err)
+				}
+				*field.into = append(*field.into, re)
+			}
+		}
+
+		if len(rule.Args) > 0 {
+			c.args = make(map[string]*regexp.Regexp, len(rule.Args))
+			for name, pattern := range rule.Args {
+				if c.args[name], err = regexp.Compile(pattern); err != nil {
+					return fmt.Errorf("invalid pattern for argument %s in rule %s: %w", name, rule.ID, err)
+				}
+			}
+		}
+
+		rule.compiled = &c
+	}
+
+	return nil
+}
+
+// absPattern expands ~ and makes a relative path pattern absolute, relative to the current directory. Patterns that
+// start with ** match anywhere, so they are not changed.
+func absPattern(pattern string) (string, error) {
+	if strings.HasPrefix(pattern, "**") {
+		return pattern, nil
+	}
+	if pattern == "~" || strings.HasPrefix(pattern, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		pattern = filepath.Join(home, pattern[1:])
+	}
+	abs, err := filepath.Abs(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	// The paths of calls have their symlinks resolved, so do the same for the directory before the first wildcard.
+	dir, rest := abs, ""
+	if i := strings.IndexAny(abs, "*?"); i >= 0 {
+		dir = filepath.Dir(abs[:i+1])
+		rest = abs[len(dir):]
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		abs = resolved + rest
+	}
+	return filepath.ToSlash(abs), nil
+}
+
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '/':
+			if pattern[i+1:] == "**" {
+				// A trailing /** also matches the directory itself.
+				sb.WriteString("(/.*)?")
+				i += 2
+			} else {
+				sb.WriteString("/")
+			}
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					// **/ matches zero or more directories.
+					i++
+					sb.WriteString("(.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+func matchAny(patterns []*regexp.Regexp, values ...string) bool {
+	for _, pattern := range patterns {
+		for _, value := range values {
+			if pattern.MatchString(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *Rule) matches(req Request) bool {
+	c := r.compiled
+	if c == nil {
+		return false
+	}
+
+	if len(c.tool) > 0 && !matchAny(c.tool, req.ToolName) {
+		return false
+	}
+	if len(c.source) > 0 && !matchAny(c.source, req.Source) {
+		return false
+	}
+	if len(c.interpreter) > 0 && !matchAny(c.interpreter, req.Interpreter) {
+		return false
+	}
+	if len(c.builtin) > 0 && (req.Builtin == "" || !matchAny(c.builtin, req.Builtin)) {
+		return false
+	}
+	if len(c.path) > 0 && !matchAny(c.path, req.Paths...) {
+		return false
+	}
+	if len(c.host) > 0 && !matchAny(c.host, req.Hosts...) {
+		return false
+	}
+	for name, pattern := range c.args {
+		value, ok := req.Args[name]
+		if !ok || !pattern.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Evaluate returns the decision of the first rule that matches the request, or the default decision.
+func (p *Policy) Evaluate(req Request) Result {
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.matches(req) {
+			return Result{
+				Decision: rule.Decision,
+				RuleID:   rule.ID,
+				Message:  rule.Message,
+			}
+		}
+	}
+
+	decision := p.Default
+	if decision == "" {
+		decision = Allow
+	}
+	return Result{
+		Decision: decision,
+	}
+}
+
+// Evaluate evaluates all the policies and returns the most restrictive result.
+func Evaluate(policies []*Policy, req Request) Result {
+	result := Result{
+		Decision: Allow,
+	}
+	for _, p := range policies {
+		if p == nil {
+			continue
+		}
+		if r := p.Evaluate(req); restrictiveness[r.Decision] > restrictiveness[result.Decision] {
+			result = r
+		}
+	}
+	return result
+}
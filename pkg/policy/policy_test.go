@@ -0,0 +1,114 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func builtin(name, input string) Request {
+	return NewRequest(types.Tool{
+		ToolDef: types.ToolDef{
+			Parameters: types.Parameters{
+				Name: name,
+			},
+			Instructions: types.CommandPrefix + name,
+		},
+	}, input)
+}
+
+func TestEvaluate(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+
+	p, err := Parse([]byte(`
+default: ask
+rules:
+- id: no-secrets
+  decision: deny
+  message: secrets are off limits
+  path: ["**/.env", "~/.ssh/**"]
+- id: workspace
+  decision: allow
+  builtin: ["sys.read", "sys.ls"]
+  path: ["./**"]
+- decision: allow
+  host: ["*.example.com"]
+- id: no-force-push
+  decision: deny
+  args:
+    command: "git push.*--force"
+- decision: allow
+  interpreter: [python3]
+  source: ["github.com/acme/**"]
+`))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name   string
+		req    Request
+		result Result
+	}{
+		{"read in workspace", builtin("sys.read", `{"filename":"main.go"}`), Result{Decision: Allow, RuleID: "workspace"}},
+		{"ls defaults to the current directory", builtin("sys.ls", `{}`), Result{Decision: Allow, RuleID: "workspace"}},
+		{"read env file", builtin("sys.read", `{"filename":"sub/.env"}`), Result{Decision: Deny, RuleID: "no-secrets", Message: "secrets are off limits"}},
+		{"read outside workspace", builtin("sys.read", `{"filename":"/etc/passwd"}`), Result{Decision: Ask}},
+		{"write in workspace", builtin("sys.write", `{"filename":"main.go"}`), Result{Decision: Ask}},
+		{"allowed host", builtin("sys.http.get", `{"url":"https://api.example.com/x"}`), Result{Decision: Allow, RuleID: "rule-3"}},
+		{"other host", builtin("sys.http.get", `{"url":"https://example.com/x"}`), Result{Decision: Ask}},
+		{"args", builtin("sys.exec", `{"command":"git push origin --force","directory":"/"}`), Result{Decision: Deny, RuleID: "no-force-push"}},
+		{"interpreter and source", Request{Interpreter: "python3", Source: "github.com/acme/tools/tool.gpt"}, Result{Decision: Allow, RuleID: "rule-5"}},
+		{"interpreter without source", Request{Interpreter: "python3", Source: "/tmp/tool.gpt"}, Result{Decision: Ask}},
+		{"builtin rule doesn't match command tools", Request{Interpreter: "sys.read", Paths: []string{dir + "/x"}}, Result{Decision: Ask}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.result, p.Evaluate(tc.req))
+		})
+	}
+}
+
+func TestSymlinkedPath(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "secret"), 0755))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "secret"), filepath.Join(dir, "link")))
+
+	p, err := Parse([]byte(`{"rules":[{"decision":"deny","path":["` + filepath.ToSlash(dir) + `/secret/**"]}]}`))
+	require.NoError(t, err)
+
+	result := p.Evaluate(builtin("sys.write", `{"filename":"`+filepath.ToSlash(filepath.Join(dir, "link", "new.txt"))+`"}`))
+	assert.Equal(t, Deny, result.Decision)
+}
+
+func TestMostRestrictive(t *testing.T) {
+	allow, err := Parse([]byte(`default: allow`))
+	require.NoError(t, err)
+	ask, err := Parse([]byte(`default: ask`))
+	require.NoError(t, err)
+	deny, err := Parse([]byte(`{"rules":[{"id":"no-ls","decision":"deny","builtin":["sys.ls"]}]}`))
+	require.NoError(t, err)
+
+	req := builtin("sys.ls", `{}`)
+	assert.Equal(t, Allow, Evaluate(nil, req).Decision)
+	assert.Equal(t, Ask, Evaluate([]*Policy{allow, ask}, req).Decision)
+	assert.Equal(t, Result{Decision: Deny, RuleID: "no-ls"}, Evaluate([]*Policy{ask, deny, allow}, req))
+}
+
+func TestInvalid(t *testing.T) {
+	_, err := Parse([]byte(`default: maybe`))
+	assert.ErrorContains(t, err, `invalid default decision "maybe"`)
+
+	_, err = Parse([]byte(`{"rules":[{"id":"x","decision":"allow","args":{"a":"("}}]}`))
+	assert.ErrorContains(t, err, "invalid pattern for argument a in rule x")
+
+	_, err = Parse([]byte(`rules: [{tool: [foo]}]`))
+	assert.ErrorContains(t, err, "invalid decision \"\" in rule rule-1")
+}
@@ -0,0 +1,131 @@
+package policy
+
+import (
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/gptscript-ai/gptscript/pkg/openapi"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+)
+
+// Request describes a tool call for the rules of a policy.
+type Request struct {
+	ToolName    string
+	Source      string
+	Interpreter string
+	Builtin     string
+	Paths       []string
+	Hosts       []string
+	Args        map[string]string
+}
+
+// pathArgs are the arguments of builtin tools that are file paths, and the default value of the argument, if any.
+var pathArgs = map[string]map[string]string{
+	"sys.ls":       {"dir": "."},
+	"sys.read":     {"filename": ""},
+	"sys.write":    {"filename": ""},
+	"sys.append":   {"filename": ""},
+	"sys.find":     {"directory": "."},
+	"sys.exec":     {"directory": "."},
+	"sys.download": {"location": ""},
+	"sys.remove":   {"location": ""},
+	"sys.stat":     {"filepath": ""},
+}
+
+// urlArgs are the arguments of builtin tools that are URLs.
+var urlArgs = map[string]string{
+	"sys.http.get":       "url",
+	"sys.http.html2text": "url",
+	"sys.http.post":      "url",
+	"sys.download":       "url",
+}
+
+// NewRequest describes a call of tool with the given input.
+func NewRequest(tool types.Tool, input string) Request {
+	req := Request{
+		ToolName:    tool.Name,
+		Source:      Source(tool),
+		Interpreter: tool.GetInterpreter(),
+		Args:        map[string]string{},
+	}
+	if req.ToolName == "" {
+		req.ToolName = tool.ID
+	}
+
+	var args map[string]any
+	if err := json.Unmarshal([]byte(input), &args); err == nil {
+		for k, v := range args {
+			if s, ok := v.(string); ok {
+				req.Args[k] = s
+			} else if data, err := json.Marshal(v); err == nil {
+				req.Args[k] = string(data)
+			}
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(tool.Instructions, types.CommandPrefix+"sys."):
+		req.Builtin = req.Interpreter
+		for arg, def := range pathArgs[req.Builtin] {
+			p := req.Args[arg]
+			if p == "" {
+				p = def
+			}
+			if p != "" {
+				req.Paths = append(req.Paths, resolvePath(p))
+			}
+		}
+		if arg, ok := urlArgs[req.Builtin]; ok {
+			req.Hosts = appendHost(req.Hosts, req.Args[arg])
+		}
+		if tool.IsOpenAPI() {
+			var info openapi.OperationInfo
+			inst := strings.Trim(strings.TrimSpace(strings.TrimPrefix(tool.Instructions, types.OpenAPIPrefix)), "'")
+			if err := json.Unmarshal([]byte(inst), &info); err == nil {
+				req.Hosts = appendHost(req.Hosts, info.Server)
+			}
+		}
+	case tool.IsHTTP():
+		req.Interpreter = "http"
+		req.Hosts = appendHost(req.Hosts, strings.Fields(strings.TrimPrefix(tool.Instructions, types.CommandPrefix))[0])
+	}
+
+	return req
+}
+
+// Source returns the location of a tool that rules match on.
+func Source(tool types.Tool) string {
+	if tool.Source.Repo != nil {
+		loc := tool.Source.Repo.Root
+		loc = strings.TrimPrefix(loc, "https://")
+		loc = strings.TrimSuffix(loc, ".git")
+		return filepath.ToSlash(filepath.Join(loc, tool.Source.Repo.Path, tool.Source.Repo.Name))
+	}
+	return tool.Source.Location
+}
+
+func appendHost(hosts []string, rawURL string) []string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return hosts
+	}
+	return append(hosts, u.Hostname())
+}
+
+// resolvePath returns the absolute path with symlinks resolved, so that a symlink can't be used to get around a rule.
+// If the file doesn't exist yet, the symlinks in its directory are resolved.
+func resolvePath(p string) string {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return filepath.ToSlash(p)
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return filepath.ToSlash(resolved)
+	}
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		return filepath.ToSlash(filepath.Join(dir, filepath.Base(abs)))
+	}
+	return filepath.ToSlash(abs)
+}
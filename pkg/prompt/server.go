@@ -10,10 +10,13 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/gptscript-ai/gptscript/pkg/redact"
 	"github.com/gptscript-ai/gptscript/pkg/types"
 )
 
-func NewServer(ctx context.Context, envs []string) ([]string, error) {
+// NewServer starts a server that prompts the user in the terminal. The answers to sensitive prompts are added to
+// redactor, if it is set.
+func NewServer(ctx context.Context, envs []string, redactor *redact.Redactor) ([]string, error) {
 	for _, env := range envs {
 		for _, k := range []string{types.PromptURLEnvVar, types.PromptTokenEnvVar} {
 			v, ok := strings.CutPrefix(env, k+"=")
@@ -54,6 +57,10 @@ func NewServer(ctx context.Context, envs []string) ([]string, error) {
 				return
 			}
 
+			if req.Sensitive {
+				redactResponse(redactor, resp)
+			}
+
 			rw.Header().Set("Content-Type", "application/json")
 			rw.WriteHeader(http.StatusOK)
 			_, _ = rw.Write([]byte(resp))
@@ -75,3 +82,14 @@ func NewServer(ctx context.Context, envs []string) ([]string, error) {
 		fmt.Sprintf("%s=%s", types.PromptTokenEnvVar, token),
 	}, nil
 }
+
+// redactResponse adds the values of a prompt response, which is a JSON object of field names to values, to redactor.
+func redactResponse(redactor *redact.Redactor, resp string) {
+	var values map[string]string
+	if err := json.Unmarshal([]byte(resp), &values); err != nil {
+		return
+	}
+	for _, v := range values {
+		redactor.Add(v)
+	}
+}
@@ -0,0 +1,170 @@
+package redact
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Placeholder replaces secret values.
+const Placeholder = "[REDACTED]"
+
+// minLength is the length of the shortest value that is treated as a secret. Shorter values, such as "1" or "true",
+// would replace too much unrelated text and don't reveal much on their own.
+const minLength = 4
+
+// Redactor replaces known secret values with Placeholder. A nil Redactor doesn't replace anything, so that redaction can
+// be disabled by not creating one.
+type Redactor struct {
+	lock     sync.RWMutex
+	secrets  map[string]struct{}
+	replacer *strings.Replacer
+}
+
+func New() *Redactor {
+	return &Redactor{
+		secrets: map[string]struct{}{},
+	}
+}
+
+// Add records values that must not be revealed.
+func (r *Redactor) Add(values ...string) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var changed bool
+	for _, value := range values {
+		if len(value) < minLength {
+			continue
+		}
+		if _, ok := r.secrets[value]; !ok {
+			r.secrets[value] = struct{}{}
+			changed = true
+		}
+	}
+
+	if changed {
+		r.replacer = newReplacer(r.secrets)
+	}
+}
+
+// AddEnv records the values of environment variables in the form "KEY=VALUE".
+func (r *Redactor) AddEnv(env ...string) {
+	if r == nil {
+		return
+	}
+	for _, e := range env {
+		_, v, _ := strings.Cut(e, "=")
+		r.Add(v)
+	}
+}
+
+func newReplacer(secrets map[string]struct{}) *strings.Replacer {
+	var values []string
+	for secret := range secrets {
+		values = append(values, secret)
+		// Secrets can also appear escaped in JSON, such as in chat requests.
+		if escaped, err := json.Marshal(secret); err == nil {
+			if escaped := string(escaped[1 : len(escaped)-1]); escaped != secret {
+				values = append(values, escaped)
+			}
+		}
+	}
+
+	// The replacer tries the values in order, so longer values go first in case a secret contains another.
+	sort.Slice(values, func(i, j int) bool {
+		if len(values[i]) == len(values[j]) {
+			return values[i] < values[j]
+		}
+		return len(values[i]) > len(values[j])
+	})
+
+	oldNew := make([]string, 0, len(values)*2)
+	for _, value := range values {
+		oldNew = append(oldNew, value, Placeholder)
+	}
+	return strings.NewReplacer(oldNew...)
+}
+
+func (r *Redactor) getReplacer() *strings.Replacer {
+	if r == nil {
+		return nil
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.replacer
+}
+
+// String returns s with all the known secrets replaced.
+func (r *Redactor) String(s string) string {
+	if replacer := r.getReplacer(); replacer != nil && s != "" {
+		return replacer.Replace(s)
+	}
+	return s
+}
+
+// Env returns a copy of env with all the known secrets in the values replaced.
+func (r *Redactor) Env(env []string) []string {
+	if r.getReplacer() == nil {
+		return env
+	}
+	result := make([]string, 0, len(env))
+	for _, e := range env {
+		result = append(result, r.String(e))
+	}
+	return result
+}
+
+// JSON returns v with all the known secrets replaced in its JSON form. The original v is returned if it doesn't contain
+// any secrets or can't be converted to and from JSON. The result of an interface value is decoded as generic JSON.
+func JSON[T any](r *Redactor, v T) T {
+	if r.getReplacer() == nil {
+		return v
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+
+	redacted := r.String(string(data))
+	if redacted == string(data) {
+		return v
+	}
+
+	var result T
+	if err := json.Unmarshal([]byte(redacted), &result); err != nil {
+		return v
+	}
+	return result
+}
+
+// Writer returns a writer that replaces the known secrets in each write before passing it to w. Secrets that are split
+// across writes are not replaced, so it should only be used with writers that receive whole messages, like loggers.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	if r == nil {
+		return w
+	}
+	return &writer{
+		redactor: r,
+		out:      w,
+	}
+}
+
+type writer struct {
+	redactor *Redactor
+	out      io.Writer
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.out, w.redactor.String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
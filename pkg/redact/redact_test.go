@@ -0,0 +1,54 @@
+package redact
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestString(t *testing.T) {
+	r := New()
+	require.Equal(t, "token abcd1234", r.String("token abcd1234"))
+
+	r.Add("abcd1234", "abcd", "x", "")
+	require.Equal(t, "token [REDACTED], short [REDACTED], x", r.String("token abcd1234, short abcd, x"))
+
+	r.AddEnv("PASSWORD=p\"ss\\word")
+	require.Equal(t, `{"input":"[REDACTED]"}`, r.String(`{"input":"p\"ss\\word"}`))
+
+	var nilRedactor *Redactor
+	nilRedactor.Add("abcd1234")
+	require.Equal(t, "abcd1234", nilRedactor.String("abcd1234"))
+}
+
+func TestJSON(t *testing.T) {
+	type request struct {
+		Messages []string `json:"messages"`
+	}
+
+	r := New()
+	r.Add("secret-value")
+
+	in := &request{Messages: []string{"the key is secret-value", "hi"}}
+	out := JSON(r, in)
+	require.Equal(t, []string{"the key is [REDACTED]", "hi"}, out.Messages)
+	require.Equal(t, "the key is secret-value", in.Messages[0])
+
+	clean := &request{Messages: []string{"hi"}}
+	require.Same(t, clean, JSON(r, clean))
+
+	var generic any = map[string]any{"content": "secret-value"}
+	require.Equal(t, map[string]any{"content": Placeholder}, JSON(r, generic))
+}
+
+func TestWriter(t *testing.T) {
+	r := New()
+	r.Add("secret-value")
+
+	var buf bytes.Buffer
+	n, err := r.Writer(&buf).Write([]byte("level=debug msg=\"env secret-value\"\n"))
+	require.NoError(t, err)
+	require.Equal(t, 35, n)
+	require.Equal(t, "level=debug msg=\"env [REDACTED]\"\n", buf.String())
+}
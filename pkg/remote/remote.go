@@ -168,9 +168,10 @@ func (c *Client) load(ctx context.Context, toolName string) (*openai.Client, err
 	}
 
 	oClient, err := openai.NewClient(ctx, c.credStore, openai.Options{
-		BaseURL:  strings.TrimSuffix(url, "/") + "/v1",
-		Cache:    c.cache,
-		CacheKey: prg.EntryToolID,
+		BaseURL:    strings.TrimSuffix(url, "/") + "/v1",
+		Cache:      c.cache,
+		CacheKey:   prg.EntryToolID,
+		HTTPClient: engine.DaemonClient,
 	})
 	if err != nil {
 		return nil, err
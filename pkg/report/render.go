@@ -0,0 +1,93 @@
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatHTML     Format = "html"
+	FormatMarkdown Format = "markdown"
+)
+
+var (
+	//go:embed report.html.tmpl
+	htmlTemplate string
+	//go:embed report.md.tmpl
+	markdownTemplate string
+
+	funcs = map[string]any{
+		"duration": formatDuration,
+		"since":    func(start, t time.Time) time.Duration { return t.Sub(start) },
+		"percent":  func(f float64) string { return fmt.Sprintf("%.2f%%", f) },
+		// Non-breaking spaces, because Markdown collapses other spaces at the start of table cells.
+		"indent":  func(depth int) string { return strings.Repeat("\u00a0\u00a0", depth) },
+		"add":     func(a, b int) int { return a + b },
+		"heading": func(depth int) string { return strings.Repeat("#", min(depth+4, 6)) },
+		"fence":   fence,
+	}
+)
+
+// ParseFormat returns the format with the name, which is html, markdown, or md.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "html":
+		return FormatHTML, nil
+	case "markdown", "md":
+		return FormatMarkdown, nil
+	default:
+		return "", fmt.Errorf("unknown report format %q, must be html or markdown", name)
+	}
+}
+
+// Render writes the report in the format. HTML reports are a single file without any external resources.
+func (r *Report) Render(w io.Writer, format Format, title string) error {
+	data := map[string]any{
+		"Title": title,
+		"Runs":  r.Runs,
+	}
+
+	switch format {
+	case FormatHTML:
+		t, err := htmltemplate.New("report").Funcs(funcs).Parse(htmlTemplate)
+		if err != nil {
+			return err
+		}
+		return t.Execute(w, data)
+	case FormatMarkdown:
+		t, err := template.New("report").Funcs(funcs).Parse(markdownTemplate)
+		if err != nil {
+			return err
+		}
+		return t.Execute(w, data)
+	default:
+		return fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d < time.Millisecond:
+		return d.String()
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	default:
+		return d.Round(10 * time.Millisecond).String()
+	}
+}
+
+// fence returns a fenced code block of the text, with a fence that is longer than any run of backticks in it.
+func fence(text string) string {
+	f := "```"
+	for strings.Contains(text, f) {
+		f += "`"
+	}
+	return f + "\n" + strings.TrimRight(text, "\n") + "\n" + f
+}
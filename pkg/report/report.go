@@ -0,0 +1,450 @@
+// Package report turns the events that are written with --events-stream-to into a report of the runs that is meant to
+// be read by people.
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gptscript-ai/gptscript/pkg/monitor"
+	"github.com/gptscript-ai/gptscript/pkg/runner"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+)
+
+// Report is the runs of an events file, in the order that they started.
+type Report struct {
+	Runs []*Run
+}
+
+type Run struct {
+	Program  string
+	Input    string
+	Output   string
+	Error    string
+	Start    time.Time
+	End      time.Time
+	Finished bool
+	Usage    types.Usage
+	// Calls are the calls that aren't made by another call, which is usually just the call of the entry tool.
+	Calls []*Call
+}
+
+type Call struct {
+	ID          string
+	ParentID    string
+	ToolName    string
+	DisplayText string
+	Category    string
+	Location    string
+	Input       string
+	Output      string
+	Start       time.Time
+	End         time.Time
+	Finished    bool
+	Cached      bool
+	Usage       types.Usage
+	Policies    []string
+	// Chats are the requests to the LLM, or the commands that were run, for the call.
+	Chats []*Chat
+	// Calls are the calls that were made by this call.
+	Calls []*Call
+}
+
+// Chat is a request to an LLM, or a command that was run.
+type Chat struct {
+	ID       string
+	Start    time.Time
+	End      time.Time
+	Finished bool
+	Model    string
+	Cached   bool
+	Usage    types.Usage
+	Request  []Message
+	Response []Message
+	// Command, Output and ExitCode are set for commands instead of Model, Request and Response.
+	Command  []string
+	Output   string
+	ExitCode *int
+	// Raw is the request and response as JSON, for requests and responses that aren't understood.
+	RawRequest  string
+	RawResponse string
+}
+
+type Message struct {
+	Role      string
+	Content   string
+	ToolCalls []ToolCall
+}
+
+type ToolCall struct {
+	Name      string
+	Arguments string
+}
+
+func (r *Run) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// Failed returns whether the run ended with an error.
+func (r *Run) Failed() bool {
+	return r.Error != ""
+}
+
+func (c *Call) Name() string {
+	if c.DisplayText != "" {
+		return c.DisplayText
+	}
+	if c.ToolName != "" {
+		return c.ToolName
+	}
+	return c.ID
+}
+
+func (c *Call) Duration() time.Duration {
+	return c.End.Sub(c.Start)
+}
+
+// Failed returns whether the call returned an error. Errors are returned to the LLM as results that start with
+// "ERROR:".
+func (c *Call) Failed() bool {
+	return strings.HasPrefix(c.Output, "ERROR:")
+}
+
+func (c *Chat) Duration() time.Duration {
+	return c.End.Sub(c.Start)
+}
+
+func (c *Chat) IsCommand() bool {
+	return len(c.Command) > 0
+}
+
+// Failed returns whether the command exited with an error.
+func (c *Chat) Failed() bool {
+	return c.ExitCode != nil && *c.ExitCode != 0
+}
+
+// Bar is a call in the timing waterfall of a run.
+type Bar struct {
+	Call  *Call
+	Depth int
+	// Offset and Width are percentages of the duration of the run.
+	Offset float64
+	Width  float64
+}
+
+// Waterfall returns the calls of the run in the order that they are shown in the call tree, with when they ran as
+// percentages of the duration of the run.
+func (r *Run) Waterfall() []Bar {
+	var (
+		result []Bar
+		total  = r.Duration()
+		walk   func(calls []*Call, depth int)
+	)
+	walk = func(calls []*Call, depth int) {
+		for _, c := range calls {
+			bar := Bar{Call: c, Depth: depth, Width: 100}
+			if total > 0 {
+				bar.Offset = 100 * float64(c.Start.Sub(r.Start)) / float64(total)
+				bar.Width = 100 * float64(c.Duration()) / float64(total)
+			}
+			result = append(result, bar)
+			walk(c.Calls, depth+1)
+		}
+	}
+	walk(r.Calls, 0)
+	return result
+}
+
+// Read reads the events of an events file and builds the report of the runs in it.
+func Read(r io.Reader) (*Report, error) {
+	var (
+		dec = json.NewDecoder(r)
+		b   = builder{
+			calls: map[string]*Call{},
+			chats: map[string]*Chat{},
+		}
+	)
+	for i := 1; ; i++ {
+		var e monitor.Event
+		if err := dec.Decode(&e); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read event %d: %w", i, err)
+		}
+		b.add(e)
+	}
+	return b.finish(), nil
+}
+
+type builder struct {
+	report Report
+	// open are the runs that have started but not finished. Runs can start while another is running, such as the runs
+	// of credential tools, so the calls without a parent are added to the run that started last.
+	open  []*Run
+	calls map[string]*Call
+	chats map[string]*Chat
+	last  time.Time
+}
+
+func (b *builder) add(e monitor.Event) {
+	if e.Time.After(b.last) {
+		b.last = e.Time
+	}
+
+	switch e.Type {
+	case runner.EventTypeRunStart:
+		run := &Run{
+			Input: e.Input,
+			Start: e.Time,
+		}
+		if e.Program != nil {
+			run.Program = e.Program.Name
+		}
+		b.report.Runs = append(b.report.Runs, run)
+		b.open = append(b.open, run)
+	case runner.EventTypeRunFinish:
+		run := b.run(e.Time)
+		run.End = e.Time
+		run.Finished = true
+		run.Output = e.Output
+		run.Error = e.Err
+		b.open = b.open[:len(b.open)-1]
+	case runner.EventTypeCallStart:
+		b.call(e).Input = e.Content
+	case runner.EventTypeChat:
+		b.chat(e)
+	case runner.EventTypeCallCacheHit:
+		b.call(e).Cached = true
+	case runner.EventTypeCallPolicy:
+		if e.Policy != nil {
+			call := b.call(e)
+			call.Policies = append(call.Policies, strings.TrimSpace(fmt.Sprintf("%s %s %s", e.Policy.Decision, e.Policy.RuleID, e.Policy.Message)))
+		}
+	case runner.EventTypeCallFinish:
+		call := b.call(e)
+		call.Output = e.Content
+		call.End = e.Time
+		call.Finished = true
+	}
+}
+
+// run returns the run that started last and hasn't finished. A run is added for events that come before the start of
+// a run, which happens when the events file doesn't start at the beginning of a run.
+func (b *builder) run(t time.Time) *Run {
+	if len(b.open) == 0 {
+		run := &Run{Start: t}
+		b.report.Runs = append(b.report.Runs, run)
+		b.open = append(b.open, run)
+	}
+	return b.open[len(b.open)-1]
+}
+
+func (b *builder) call(e monitor.Event) *Call {
+	if e.CallContext == nil {
+		return &Call{}
+	}
+	if call, ok := b.calls[e.CallContext.ID]; ok {
+		return call
+	}
+
+	call := &Call{
+		ID:          e.CallContext.ID,
+		ParentID:    e.CallContext.ParentID,
+		ToolName:    types.FirstSet(e.CallContext.ToolName, e.CallContext.Tool.Name),
+		DisplayText: e.CallContext.DisplayText,
+		Category:    string(e.CallContext.ToolCategory),
+		Start:       e.Time,
+	}
+	if source := e.CallContext.Tool.Source; source.Location != "" {
+		call.Location = fmt.Sprintf("%s:%d", source.Location, source.LineNo)
+	}
+
+	if parent, ok := b.calls[call.ParentID]; ok {
+		parent.Calls = append(parent.Calls, call)
+	} else {
+		run := b.run(e.Time)
+		run.Calls = append(run.Calls, call)
+	}
+	b.calls[call.ID] = call
+	return call
+}
+
+func (b *builder) chat(e monitor.Event) {
+	call := b.call(e)
+	chat, ok := b.chats[e.ChatCompletionID]
+	if !ok {
+		chat = &Chat{
+			ID:    e.ChatCompletionID,
+			Start: e.Time,
+		}
+		b.chats[e.ChatCompletionID] = chat
+		call.Chats = append(call.Chats, chat)
+	}
+
+	if e.ChatRequest != nil {
+		parseRequest(chat, e.ChatRequest)
+	}
+	if e.ChatResponse != nil {
+		parseResponse(chat, e.ChatResponse)
+		chat.End = e.Time
+		chat.Finished = true
+		chat.Cached = e.ChatResponseCached
+		chat.Usage = e.Usage
+		addUsage(&call.Usage, e.Usage)
+		addUsage(&b.run(e.Time).Usage, e.Usage)
+	}
+}
+
+func (b *builder) finish() *Report {
+	for _, run := range b.report.Runs {
+		if !run.Finished {
+			run.End = b.last
+		}
+	}
+	for _, call := range b.calls {
+		if !call.Finished {
+			call.End = b.last
+		}
+	}
+	for _, chat := range b.chats {
+		if !chat.Finished {
+			chat.End = b.last
+		}
+	}
+	return &b.report
+}
+
+func addUsage(total *types.Usage, usage types.Usage) {
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+}
+
+type request struct {
+	Model    string `json:"model"`
+	Messages []struct {
+		Role      string          `json:"role"`
+		Name      string          `json:"name"`
+		Content   json.RawMessage `json:"content"`
+		ToolCalls []struct {
+			Function struct {
+				Name      string `json:"name"`
+				Arguments string `json:"arguments"`
+			} `json:"function"`
+		} `json:"tool_calls"`
+	} `json:"messages"`
+	Command []string `json:"command"`
+}
+
+type commandResponse struct {
+	Output   string `json:"output"`
+	ExitCode *int   `json:"exitCode"`
+}
+
+func parseRequest(chat *Chat, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil || (len(req.Command) == 0 && len(req.Messages) == 0) {
+		chat.RawRequest = indent(data)
+		return
+	}
+
+	chat.Model = req.Model
+	chat.Command = req.Command
+	for _, m := range req.Messages {
+		msg := Message{
+			Role:    types.FirstSet(m.Name, m.Role),
+			Content: messageContent(m.Content),
+		}
+		for _, tc := range m.ToolCalls {
+			msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+				Name:      tc.Function.Name,
+				Arguments: tc.Function.Arguments,
+			})
+		}
+		chat.Request = append(chat.Request, msg)
+	}
+}
+
+func parseResponse(chat *Chat, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	if chat.IsCommand() {
+		var resp commandResponse
+		if err := json.Unmarshal(data, &resp); err == nil {
+			chat.Output = resp.Output
+			chat.ExitCode = resp.ExitCode
+			return
+		}
+	} else {
+		var resp types.CompletionMessage
+		if err := json.Unmarshal(data, &resp); err == nil && (resp.Role != "" || len(resp.Content) > 0) {
+			msg := Message{
+				Role: string(resp.Role),
+			}
+			for _, part := range resp.Content {
+				if part.ToolCall != nil {
+					msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+						Name:      part.ToolCall.Function.Name,
+						Arguments: part.ToolCall.Function.Arguments,
+					})
+				} else {
+					msg.Content += part.Text
+				}
+			}
+			chat.Response = append(chat.Response, msg)
+			return
+		}
+	}
+
+	chat.RawResponse = indent(data)
+}
+
+// messageContent returns the text of the content of a message of a request, which is either a string or a list of
+// parts.
+func messageContent(data json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		return text
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return string(data)
+	}
+
+	var result []string
+	for _, part := range parts {
+		if part.Type == "text" {
+			result = append(result, part.Text)
+		} else {
+			result = append(result, "["+part.Type+"]")
+		}
+	}
+	return strings.Join(result, "\n")
+}
+
+func indent(data []byte) string {
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil || enc.Encode(v) != nil {
+		return string(data)
+	}
+	return strings.TrimSpace(buf.String())
+}
@@ -0,0 +1,102 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const events = `{"time":"2024-01-01T00:00:00Z","type":"runStart","program":{"name":"test.gpt"}}
+
+{"time":"2024-01-01T00:00:01Z","type":"callStart","callContext":{"id":"1","tool":{"name":"main","source":{"location":"test.gpt","lineNo":1}},"inputContext":null,"displayText":"Running main"},"content":"hello"}
+
+{"time":"2024-01-01T00:00:01Z","type":"callChat","callContext":{"id":"1","tool":{"name":"main"},"inputContext":null},"chatCompletionId":"10","chatRequest":{"model":"gpt-4o","messages":[{"role":"system","content":"Be <nice>"},{"role":"user","content":[{"type":"text","text":"hello"}]}]}}
+
+{"time":"2024-01-01T00:00:03Z","type":"callChat","callContext":{"id":"1","tool":{"name":"main"},"inputContext":null},"chatCompletionId":"10","chatResponse":{"role":"assistant","content":[{"toolCall":{"id":"a","function":{"name":"ls","arguments":"{\"dir\":\".\"}"}}}]},"usage":{"promptTokens":10,"completionTokens":5,"totalTokens":15}}
+
+{"time":"2024-01-01T00:00:04Z","type":"callStart","callContext":{"id":"2","parentID":"1","tool":{"name":"ls"},"inputContext":null},"content":"{\"dir\":\".\"}"}
+
+{"time":"2024-01-01T00:00:04Z","type":"callChat","callContext":{"id":"2","parentID":"1","tool":{"name":"ls"},"inputContext":null},"chatCompletionId":"11","chatRequest":{"command":["/bin/ls","."],"input":"{}"}}
+
+{"time":"2024-01-01T00:00:05Z","type":"callChat","callContext":{"id":"2","parentID":"1","tool":{"name":"ls"},"inputContext":null},"chatCompletionId":"11","chatResponse":{"output":"no such file","err":{},"exitCode":2}}
+
+{"time":"2024-01-01T00:00:05Z","type":"callFinish","callContext":{"id":"2","parentID":"1","tool":{"name":"ls"},"inputContext":null},"content":"ERROR: no such file"}
+
+{"time":"2024-01-01T00:00:06Z","type":"callStart","callContext":{"id":"3","parentID":"1","tool":{"name":"slow"},"inputContext":null}}
+
+{"time":"2024-01-01T00:00:09Z","type":"callFinish","callContext":{"id":"1","tool":{"name":"main"},"inputContext":null},"content":"done"}
+
+{"time":"2024-01-01T00:00:10Z","type":"runFinish","output":"done","err":"failed"}
+`
+
+func TestRead(t *testing.T) {
+	rep, err := Read(strings.NewReader(events))
+	require.NoError(t, err)
+	require.Len(t, rep.Runs, 1)
+
+	run := rep.Runs[0]
+	require.Equal(t, "test.gpt", run.Program)
+	require.Equal(t, 10*time.Second, run.Duration())
+	require.True(t, run.Failed())
+	require.Equal(t, 15, run.Usage.TotalTokens)
+	require.Len(t, run.Calls, 1)
+
+	main := run.Calls[0]
+	require.Equal(t, "Running main", main.Name())
+	require.Equal(t, "test.gpt:1", main.Location)
+	require.Equal(t, "hello", main.Input)
+	require.Equal(t, "done", main.Output)
+	require.Equal(t, 8*time.Second, main.Duration())
+	require.Len(t, main.Chats, 1)
+
+	chat := main.Chats[0]
+	require.Equal(t, "gpt-4o", chat.Model)
+	require.Equal(t, 2*time.Second, chat.Duration())
+	require.Equal(t, []Message{
+		{Role: "system", Content: "Be <nice>"},
+		{Role: "user", Content: "hello"},
+	}, chat.Request)
+	require.Equal(t, []Message{
+		{Role: "assistant", ToolCalls: []ToolCall{{Name: "ls", Arguments: `{"dir":"."}`}}},
+	}, chat.Response)
+
+	require.Len(t, main.Calls, 2)
+	ls, slow := main.Calls[0], main.Calls[1]
+	require.True(t, ls.Failed())
+	require.Equal(t, []string{"/bin/ls", "."}, ls.Chats[0].Command)
+	require.Equal(t, "no such file", ls.Chats[0].Output)
+	require.True(t, ls.Chats[0].Failed())
+
+	// Calls that don't finish end with the last event.
+	require.False(t, slow.Finished)
+	require.Equal(t, 4*time.Second, slow.Duration())
+
+	bars := run.Waterfall()
+	require.Len(t, bars, 3)
+	require.Equal(t, 1, bars[1].Depth)
+	require.InDelta(t, 40.0, bars[1].Offset, 0.001)
+	require.InDelta(t, 10.0, bars[1].Width, 0.001)
+}
+
+func TestRender(t *testing.T) {
+	rep, err := Read(strings.NewReader(events))
+	require.NoError(t, err)
+
+	var html strings.Builder
+	require.NoError(t, rep.Render(&html, FormatHTML, "Report"))
+	require.Contains(t, html.String(), "Be &lt;nice&gt;")
+	require.Contains(t, html.String(), `<details class="failed">`)
+	require.Contains(t, html.String(), "left: 40.00%; width: 10.00%")
+	require.NotContains(t, html.String(), "ZgotmplZ")
+
+	var md strings.Builder
+	require.NoError(t, rep.Render(&md, FormatMarkdown, "Report"))
+	require.Contains(t, md.String(), "# Report\n")
+	require.Contains(t, md.String(), "Calls `ls` with:\n\n```\n{\"dir\":\".\"}\n```")
+	require.Contains(t, md.String(), "Command `/bin/ls .`, 1s, exit code 2")
+
+	_, err = ParseFormat("pdf")
+	require.Error(t, err)
+}
@@ -0,0 +1,199 @@
+// Package archive loads tools from archives and OCI images. They are extracted into the cache, in directories named
+// after the sha256 digests that they are verified with.
+package archive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// VCS is the VCS of the repos of tools that are loaded from archives. The root of such a repo is where the archive was
+// loaded from, and the revision is the digest of the archive, or of the manifest of the OCI image.
+const VCS = "archive"
+
+// DigestPrefix separates a reference from the sha256 digest that it is pinned to.
+const DigestPrefix = "@sha256:"
+
+var suffixes = []string{".tar.gz", ".tgz", ".zip"}
+
+// Dir returns the directory that archives are extracted into.
+func Dir(cacheDir string) string {
+	return filepath.Join(cacheDir, "repos", "archives")
+}
+
+// IsArchive returns whether the name is the name of an archive that can be extracted.
+func IsArchive(name string) bool {
+	name = strings.ToLower(name)
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitDigest splits the digest that a reference is pinned to from the reference.
+func SplitDigest(ref string) (string, string) {
+	i := strings.LastIndex(ref, DigestPrefix)
+	if i == -1 {
+		return ref, ""
+	}
+	digest := ref[i+len(DigestPrefix):]
+	if _, err := hex.DecodeString(digest); err != nil || len(digest) != sha256.Size*2 {
+		return ref, ""
+	}
+	return ref[:i], strings.ToLower(digest)
+}
+
+// FromFile extracts a local archive into dir, and returns the digest of the archive. If digest is set, the archive must
+// have that digest.
+func FromFile(dir, file, digest string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	actual, err := hashReader(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	if err := verify(file, digest, actual); err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	return actual, store(dir, actual, func(target string) error {
+		return extractArchive(f, file, target)
+	})
+}
+
+// FromURL downloads an archive and extracts it into dir, and returns the digest of the archive. If digest is set, the
+// archive must have that digest, and isn't downloaded again once it is extracted.
+func FromURL(ctx context.Context, dir, url, digest string) (string, error) {
+	if digest != "" && Extracted(dir, digest) {
+		return digest, nil
+	}
+	if digest == "" {
+		log.Warnf("Downloading %s without a digest, which can change, pin it with %s<digest> to verify it", url, DigestPrefix)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error loading %s: %s", url, resp.Status)
+	}
+
+	tmp, actual, err := download(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := verify(url, digest, actual); err != nil {
+		return "", err
+	}
+
+	return actual, store(dir, actual, func(target string) error {
+		return extractArchive(tmp, url, target)
+	})
+}
+
+// Checkout copies the extracted archive with the revision to target.
+func Checkout(dir, revision, target string) error {
+	src := filepath.Join(dir, revision)
+	if _, err := os.Stat(src); errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("archive %s is not extracted, load the tool again to extract it", revision)
+	} else if err != nil {
+		return err
+	}
+	return copyDir(src, target)
+}
+
+// Extracted returns whether the archive or OCI image with the digest is extracted in dir.
+func Extracted(dir, digest string) bool {
+	s, err := os.Stat(filepath.Join(dir, digest))
+	return err == nil && s.IsDir()
+}
+
+// store extracts into the directory of the digest, unless it is already extracted. The files are extracted into a
+// temporary directory first, so that the directory only exists once they all are.
+func store(dir, digest string, extract func(target string) error) error {
+	if Extracted(dir, digest) {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(dir, digest+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := extract(tmp); err != nil {
+		return err
+	}
+	if err := checkLinks(tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, digest)); err != nil && !Extracted(dir, digest) {
+		return err
+	}
+	return nil
+}
+
+// download writes the content to a temporary file, and returns the file and the digest of the content.
+func download(r io.Reader) (*os.File, string, error) {
+	tmp, err := os.CreateTemp("", "gptscript-archive")
+	if err != nil {
+		return nil, "", err
+	}
+
+	digester := sha256.New()
+	if _, err := io.Copy(tmp, io.TeeReader(r, digester)); err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, "", err
+	}
+
+	return tmp, hex.EncodeToString(digester.Sum(nil)), nil
+}
+
+func hashReader(r io.Reader) (string, error) {
+	digester := sha256.New()
+	if _, err := io.Copy(digester, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(digester.Sum(nil)), nil
+}
+
+func verify(name, expected, actual string) error {
+	if expected != "" && expected != actual {
+		return fmt.Errorf("integrity check of %s failed: expected digest sha256:%s but got sha256:%s", name, expected, actual)
+	}
+	return nil
+}
@@ -0,0 +1,384 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type entry struct {
+	name string
+	data string
+	link string
+}
+
+func tarGz(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.data)), Typeflag: tar.TypeReg}
+		if e.link != "" {
+			hdr = &tar.Header{Name: e.name, Mode: 0777, Linkname: e.link, Typeflag: tar.TypeSymlink}
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(e.data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func zipped(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.Create(e.name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(e.data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func writeTemp(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(file, data, 0644))
+	return file
+}
+
+func TestSplitDigest(t *testing.T) {
+	digest := strings.Repeat("ab", 32)
+
+	ref, d := SplitDigest("https://example.com/tool.tar.gz@sha256:" + digest)
+	require.Equal(t, "https://example.com/tool.tar.gz", ref)
+	require.Equal(t, digest, d)
+
+	ref, d = SplitDigest("oci://localhost:5000/tools:v1@sha256:" + strings.ToUpper(digest))
+	require.Equal(t, "oci://localhost:5000/tools:v1", ref)
+	require.Equal(t, digest, d)
+
+	ref, d = SplitDigest("./tool.tar.gz@sha256:abc")
+	require.Equal(t, "./tool.tar.gz@sha256:abc", ref)
+	require.Empty(t, d)
+}
+
+func TestFromFile(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, file := range []string{
+		writeTemp(t, "tool.tar.gz", tarGz(t, entry{name: "tool/tool.gpt", data: "echo hi"})),
+		writeTemp(t, "tool.zip", zipped(t, entry{name: "tool/tool.gpt", data: "echo hi"})),
+	} {
+		revision, err := FromFile(dir, file, "")
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(dir, revision, "tool", "tool.gpt"))
+		require.NoError(t, err)
+		require.Equal(t, "echo hi", string(data))
+
+		target := filepath.Join(t.TempDir(), "checkout")
+		require.NoError(t, Checkout(dir, revision, target))
+		data, err = os.ReadFile(filepath.Join(target, "tool", "tool.gpt"))
+		require.NoError(t, err)
+		require.Equal(t, "echo hi", string(data))
+	}
+}
+
+func TestFromFileDigestMismatch(t *testing.T) {
+	dir := t.TempDir()
+	data := tarGz(t, entry{name: "tool.gpt", data: "echo hi"})
+	file := writeTemp(t, "tool.tar.gz", data)
+
+	_, err := FromFile(dir, file, strings.Repeat("0", 64))
+	require.ErrorContains(t, err, "integrity check of "+file+" failed")
+
+	revision, err := FromFile(dir, file, digestOf(data))
+	require.NoError(t, err)
+	require.Equal(t, digestOf(data), revision)
+}
+
+func TestFromFileInvalidEntries(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"outside":         tarGz(t, entry{name: "../evil", data: "x"}),
+		"absolute link":   tarGz(t, entry{name: "link", link: "/etc/passwd"}),
+		"link outside":    tarGz(t, entry{name: "link", link: "../../evil"}),
+		"through link":    tarGz(t, entry{name: "d", link: "."}, entry{name: "d/x", data: "x"}),
+		"zip outside":     zipped(t, entry{name: "../evil", data: "x"}),
+		"zip absolute":    zipped(t, entry{name: "/evil", data: "x"}),
+		"not an archive":  []byte("not an archive"),
+		"nested outside":  tarGz(t, entry{name: "a/../../evil", data: "x"}),
+		"link then write": tarGz(t, entry{name: "up", link: "sub/.."}, entry{name: "up/x", data: "x"}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			suffix := ".tar.gz"
+			if strings.HasPrefix(name, "zip") {
+				suffix = ".zip"
+			}
+			_, err := FromFile(dir, writeTemp(t, "tool"+suffix, data), "")
+			require.Error(t, err)
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			require.Empty(t, entries, "nothing is extracted from an invalid archive")
+		})
+	}
+}
+
+func TestFromFileChainedLink(t *testing.T) {
+	// e1/e2/l is the root of the archive, so tool.gpt is in the archive by its path, but outside of it on disk.
+	base := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(base, "secret"), []byte("secret"), 0600))
+	dir := filepath.Join(base, "a", "b")
+
+	file := writeTemp(t, "tool.tar.gz", tarGz(t,
+		entry{name: "e1/e2/l", link: "../.."},
+		entry{name: "tool.gpt", link: "e1/e2/l/../../../secret"},
+	))
+	_, err := FromFile(dir, file, "")
+	require.ErrorContains(t, err, "invalid link tool.gpt in archive")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	// Links through links that stay in the archive are fine.
+	file = writeTemp(t, "tool.tar.gz", tarGz(t,
+		entry{name: "e1/e2/l", link: "../.."},
+		entry{name: "e1/tool.gpt", data: "echo hi"},
+		entry{name: "tool.gpt", link: "e1/e2/l/e1/tool.gpt"},
+	))
+	revision, err := FromFile(dir, file, "")
+	require.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(dir, revision, "tool.gpt"))
+	require.NoError(t, err)
+	require.Equal(t, "echo hi", string(data))
+}
+
+func setLimits(t *testing.T, bytes int64, entries int) {
+	t.Helper()
+	oldBytes, oldEntries := maxExtractedBytes, maxExtractedEntries
+	maxExtractedBytes, maxExtractedEntries = bytes, entries
+	t.Cleanup(func() {
+		maxExtractedBytes, maxExtractedEntries = oldBytes, oldEntries
+	})
+}
+
+func TestFromFileLimits(t *testing.T) {
+	setLimits(t, 10, 2)
+
+	for name, test := range map[string]struct {
+		file string
+		err  string
+	}{
+		"size":        {writeTemp(t, "tool.tar.gz", tarGz(t, entry{name: "a", data: "123456"}, entry{name: "b", data: "123456"})), "larger than 10 bytes"},
+		"entries":     {writeTemp(t, "tool.tar.gz", tarGz(t, entry{name: "a"}, entry{name: "b"}, entry{name: "c"})), "more than 2 entries"},
+		"zip size":    {writeTemp(t, "tool.zip", zipped(t, entry{name: "a", data: strings.Repeat("x", 11)})), "larger than 10 bytes"},
+		"zip entries": {writeTemp(t, "tool.zip", zipped(t, entry{name: "a"}, entry{name: "b"}, entry{name: "c"})), "more than 2 entries"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			_, err := FromFile(dir, test.file, "")
+			require.ErrorContains(t, err, test.err)
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			require.Empty(t, entries, "nothing is extracted from an archive that is too large")
+		})
+	}
+
+	_, err := FromFile(t.TempDir(), writeTemp(t, "tool.tar.gz", tarGz(t, entry{name: "a", data: "12345"}, entry{name: "b", data: "12345"})), "")
+	require.NoError(t, err)
+}
+
+func TestFromURL(t *testing.T) {
+	data := tarGz(t, entry{name: "tool.gpt", data: "echo hi"})
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/tool.tar.gz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer s.Close()
+
+	dir := t.TempDir()
+	_, err := FromURL(context.Background(), dir, s.URL+"/tool.tar.gz", strings.Repeat("0", 64))
+	require.ErrorContains(t, err, "integrity check")
+
+	revision, err := FromURL(context.Background(), dir, s.URL+"/tool.tar.gz", digestOf(data))
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(dir, revision, "tool.gpt"))
+
+	// A pinned archive isn't downloaded again once it is extracted.
+	_, err = FromURL(context.Background(), dir, s.URL+"/tool.tar.gz", digestOf(data))
+	require.NoError(t, err)
+	require.Equal(t, 2, requests)
+
+	_, err = FromURL(context.Background(), dir, s.URL+"/missing.tar.gz", "")
+	require.ErrorContains(t, err, "404 Not Found")
+}
+
+// image is an OCI image with a manifest and its layers, and an index that refers to the manifest.
+type image struct {
+	blobs    map[string][]byte
+	manifest string
+	index    []byte
+}
+
+func newImage(t *testing.T, tag string, layers ...[]byte) image {
+	t.Helper()
+	img := image{blobs: map[string][]byte{}}
+
+	m := manifest{MediaType: mediaTypeManifest}
+	for i, layer := range layers {
+		digest := "sha256:" + digestOf(layer)
+		img.blobs[digest] = layer
+		desc := descriptor{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: digest, Size: int64(len(layer))}
+		if i == len(layers)-1 && !bytes.HasPrefix(layer, []byte{0x1f, 0x8b}) {
+			desc = descriptor{MediaType: "application/vnd.gptscript.file", Digest: digest, Size: int64(len(layer)), Annotations: map[string]string{titleAnnotation: "README.md"}}
+		}
+		m.Layers = append(m.Layers, desc)
+	}
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	img.manifest = "sha256:" + digestOf(data)
+	img.blobs[img.manifest] = data
+
+	img.index, err = json.Marshal(manifest{
+		MediaType: mediaTypeIndex,
+		Manifests: []descriptor{{
+			MediaType:   mediaTypeManifest,
+			Digest:      img.manifest,
+			Size:        int64(len(data)),
+			Annotations: map[string]string{refNameAnnotation: tag},
+		}},
+	})
+	require.NoError(t, err)
+	return img
+}
+
+func (i image) layout(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.json"), i.index, 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "blobs", "sha256"), 0755))
+	for digest, data := range i.blobs {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:")), data, 0644))
+	}
+	return dir
+}
+
+func TestFromLayout(t *testing.T) {
+	img := newImage(t, "v1",
+		tarGz(t, entry{name: "tool.gpt", data: "old"}, entry{name: "removed.txt", data: "x"}),
+		tarGz(t, entry{name: "tool.gpt", data: "new"}, entry{name: ".wh.removed.txt"}),
+		[]byte("# Tool"),
+	)
+	layout := img.layout(t)
+	require.True(t, IsLayout(layout))
+
+	dir := t.TempDir()
+	revision, err := FromLayout(context.Background(), dir, layout, "v1", "")
+	require.NoError(t, err)
+	require.Equal(t, strings.TrimPrefix(img.manifest, "sha256:"), revision)
+
+	data, err := os.ReadFile(filepath.Join(dir, revision, "tool.gpt"))
+	require.NoError(t, err)
+	require.Equal(t, "new", string(data))
+	require.NoFileExists(t, filepath.Join(dir, revision, "removed.txt"))
+	require.FileExists(t, filepath.Join(dir, revision, "README.md"))
+
+	_, err = FromLayout(context.Background(), t.TempDir(), layout, "v2", "")
+	require.ErrorContains(t, err, "has no image v2")
+
+	// A tampered layer fails the integrity check.
+	for digest, data := range img.blobs {
+		if digest != img.manifest {
+			require.NoError(t, os.WriteFile(filepath.Join(layout, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:")), append(data, 'x'), 0644))
+		}
+	}
+	_, err = FromLayout(context.Background(), t.TempDir(), layout, "", "")
+	require.ErrorContains(t, err, "integrity check")
+}
+
+func TestFromLayoutLimits(t *testing.T) {
+	setLimits(t, 10, 10)
+
+	// Each layer is within the limit, but the image isn't.
+	img := newImage(t, "v1",
+		tarGz(t, entry{name: "a", data: "123456"}),
+		tarGz(t, entry{name: "b", data: "123456"}),
+	)
+	_, err := FromLayout(context.Background(), t.TempDir(), img.layout(t), "v1", "")
+	require.ErrorContains(t, err, "larger than 10 bytes")
+}
+
+func TestFromRegistry(t *testing.T) {
+	img := newImage(t, "v1", tarGz(t, entry{name: "tool.gpt", data: "echo hi"}))
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch p := r.URL.Path; {
+		case p == "/v2/team/tools/manifests/v1":
+			w.Header().Set("Content-Type", mediaTypeIndex)
+			_, _ = w.Write(img.index)
+		case strings.HasPrefix(p, "/v2/team/tools/manifests/"), strings.HasPrefix(p, "/v2/team/tools/blobs/"):
+			data, ok := img.blobs[p[strings.LastIndex(p, "/")+1:]]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(data)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	ref := OCIScheme + strings.TrimPrefix(s.URL, "http://") + "/team/tools"
+	dir := t.TempDir()
+
+	revision, err := FromRegistry(context.Background(), dir, ref+":v1", "")
+	require.NoError(t, err)
+	require.Equal(t, strings.TrimPrefix(img.manifest, "sha256:"), revision)
+	require.FileExists(t, filepath.Join(dir, revision, "tool.gpt"))
+
+	revision, err = FromRegistry(context.Background(), t.TempDir(), ref, strings.TrimPrefix(img.manifest, "sha256:"))
+	require.NoError(t, err)
+	require.Equal(t, strings.TrimPrefix(img.manifest, "sha256:"), revision)
+
+	_, err = FromRegistry(context.Background(), t.TempDir(), ref, strings.Repeat("0", 64))
+	require.ErrorContains(t, err, "404 Not Found")
+
+	_, err = FromRegistry(context.Background(), t.TempDir(), ref+":v2", "")
+	require.ErrorContains(t, err, "404 Not Found")
+
+	_, err = FromRegistry(context.Background(), t.TempDir(), OCIScheme+"localhost", "")
+	require.ErrorContains(t, err, "invalid OCI reference")
+}
@@ -0,0 +1,343 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// whiteoutPrefix starts the names of the files that delete files of lower layers of OCI images, and opaqueWhiteout
+// deletes all the files of lower layers in its directory.
+const (
+	whiteoutPrefix = ".wh."
+	opaqueWhiteout = ".wh..wh..opq"
+)
+
+// maxExtractedBytes and maxExtractedEntries limit what an archive, or all the layers of an OCI image, can extract, so
+// that a small compressed archive can't fill the disk.
+var (
+	maxExtractedBytes   int64 = 4 << 30
+	maxExtractedEntries       = 250_000
+)
+
+// limits is what is left to extract of an archive or of an OCI image.
+type limits struct {
+	bytes   int64
+	entries int
+}
+
+func newLimits() *limits {
+	return &limits{
+		bytes:   maxExtractedBytes,
+		entries: maxExtractedEntries,
+	}
+}
+
+// entry counts an entry of the archive, which fails once the archive has too many entries.
+func (l *limits) entry() error {
+	if l.entries <= 0 {
+		return fmt.Errorf("archive has more than %d entries", maxExtractedEntries)
+	}
+	l.entries--
+	return nil
+}
+
+func extractArchive(f *os.File, name, target string) error {
+	if strings.HasSuffix(strings.ToLower(name), ".zip") {
+		s, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		zr, err := zip.NewReader(f, s.Size())
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if err := unzip(zr, target, newLimits()); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+		return nil
+	}
+
+	if err := untarGzip(f, target, newLimits()); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", name, err)
+	}
+	return nil
+}
+
+func untarGzip(r io.Reader, target string, l *limits) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	return untar(gz, target, l)
+}
+
+// untar extracts a tar archive into target. It applies the whiteout files of OCI layers.
+func untar(r io.Reader, target string, l *limits) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := l.entry(); err != nil {
+			return err
+		}
+
+		file, err := join(target, hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		switch base := path.Base(hdr.Name); {
+		case base == opaqueWhiteout:
+			entries, err := os.ReadDir(filepath.Dir(file))
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			for _, entry := range entries {
+				if err := os.RemoveAll(filepath.Join(filepath.Dir(file), entry.Name())); err != nil {
+					return err
+				}
+			}
+			continue
+		case strings.HasPrefix(base, whiteoutPrefix):
+			if err := os.RemoveAll(filepath.Join(filepath.Dir(file), strings.TrimPrefix(base, whiteoutPrefix))); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(file, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(file, tr, hdr.FileInfo().Mode(), l); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := symlink(target, file, hdr.Linkname); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			src, err := join(target, hdr.Linkname)
+			if err != nil {
+				return err
+			}
+			if err := replace(file); err != nil {
+				return err
+			}
+			if err := os.Link(src, file); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func unzip(zr *zip.Reader, target string, l *limits) error {
+	for _, f := range zr.File {
+		if err := l.entry(); err != nil {
+			return err
+		}
+		file, err := join(target, f.Name)
+		if err != nil {
+			return err
+		}
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(file, 0755); err != nil {
+				return err
+			}
+		case mode&fs.ModeSymlink != 0:
+			link, err := readZipFile(f, l)
+			if err != nil {
+				return err
+			}
+			if err := symlink(target, file, string(link)); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			r, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = writeFile(file, r, mode, l)
+			r.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func readZipFile(f *zip.File, l *limits) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var buf bytes.Buffer
+	if err := l.copy(&buf, r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// join returns the path of an entry of an archive in target. The entry can't be outside of target.
+func join(target, name string) (string, error) {
+	name = filepath.FromSlash(strings.TrimPrefix(name, "./"))
+	if name == "" {
+		return target, nil
+	}
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("invalid entry %s in archive: it is outside of the archive", name)
+	}
+
+	// Links are checked when they are extracted, but only by their paths, so entries can't be extracted through them.
+	dir := target
+	for _, part := range strings.Split(filepath.Dir(name), string(filepath.Separator)) {
+		dir = filepath.Join(dir, part)
+		if s, err := os.Lstat(dir); err == nil && s.Mode()&fs.ModeSymlink != 0 {
+			return "", fmt.Errorf("invalid entry %s in archive: it is inside of a link", name)
+		}
+	}
+	return filepath.Join(target, name), nil
+}
+
+// replace removes a file of a lower layer that an entry replaces, and creates the directory of the entry.
+func replace(file string) error {
+	if err := os.RemoveAll(file); err != nil {
+		return err
+	}
+	return os.MkdirAll(filepath.Dir(file), 0755)
+}
+
+// writeFile writes the content of an entry to file. Without limits, the content can be of any size.
+func writeFile(file string, r io.Reader, mode fs.FileMode, l *limits) error {
+	if err := replace(file); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	if err := l.copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// copy copies the content of an entry, which fails once the archive is too large.
+func (l *limits) copy(w io.Writer, r io.Reader) error {
+	if l == nil {
+		_, err := io.Copy(w, r)
+		return err
+	}
+	n, err := io.Copy(w, io.LimitReader(r, l.bytes+1))
+	l.bytes -= n
+	if err != nil {
+		return err
+	}
+	if l.bytes < 0 {
+		return fmt.Errorf("archive is larger than %d bytes", maxExtractedBytes)
+	}
+	return nil
+}
+
+// symlink creates a link, which must point to a file in target.
+func symlink(target, file, link string) error {
+	if filepath.IsAbs(link) {
+		return fmt.Errorf("invalid link %s in archive: it is absolute", link)
+	}
+	rel, err := filepath.Rel(target, filepath.Join(filepath.Dir(file), link))
+	if err != nil || !filepath.IsLocal(rel) {
+		return fmt.Errorf("invalid link %s in archive: it points outside of the archive", link)
+	}
+	if err := replace(file); err != nil {
+		return err
+	}
+	return os.Symlink(link, file)
+}
+
+// checkLinks fails if a link in target resolves to a file that isn't in target. Links are checked by their paths when
+// they are extracted, but a link can point through other links, which only resolve once they all are extracted.
+func checkLinks(target string) error {
+	root, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(target, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return err
+		}
+		name, err := filepath.Rel(target, p)
+		if err != nil {
+			return err
+		}
+		resolved, err := filepath.EvalSymlinks(p)
+		if err != nil {
+			return fmt.Errorf("invalid link %s in archive: it points to a file that isn't in the archive", filepath.ToSlash(name))
+		}
+		if rel, err := filepath.Rel(root, resolved); err != nil || !filepath.IsLocal(rel) {
+			return fmt.Errorf("invalid link %s in archive: it points outside of the archive", filepath.ToSlash(name))
+		}
+		return nil
+	})
+}
+
+// copyDir copies the files, directories and links of src to dst.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, 0755)
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return writeFile(target, f, info.Mode(), nil)
+		}
+		return nil
+	})
+}
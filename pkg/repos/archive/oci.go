@@ -0,0 +1,352 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// OCIScheme starts the references of images in OCI registries, such as oci://localhost:5000/tools:v1.
+const OCIScheme = "oci://"
+
+const (
+	mediaTypeIndex          = "application/vnd.oci.image.index.v1+json"
+	mediaTypeManifest       = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+
+	// refNameAnnotation is the tag of an image in an image layout.
+	refNameAnnotation = "org.opencontainers.image.ref.name"
+	// titleAnnotation is the name of a file that is a layer by itself, as pushed by tools such as ORAS.
+	titleAnnotation = "org.opencontainers.image.title"
+)
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType,omitempty"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    *platform         `json:"platform,omitempty"`
+}
+
+type platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+}
+
+// manifest is an image index or an image manifest.
+type manifest struct {
+	MediaType string       `json:"mediaType,omitempty"`
+	Manifests []descriptor `json:"manifests,omitempty"`
+	Layers    []descriptor `json:"layers,omitempty"`
+}
+
+func (m manifest) isIndex() bool {
+	return m.MediaType == mediaTypeIndex || m.MediaType == mediaTypeDockerList || (len(m.Manifests) > 0 && len(m.Layers) == 0)
+}
+
+// blobStore is where the manifests and layers of images are.
+type blobStore interface {
+	manifest(ctx context.Context, digest string) ([]byte, error)
+	blob(ctx context.Context, digest string) (io.ReadCloser, error)
+}
+
+// IsLayout returns whether the directory is an OCI image layout.
+func IsLayout(dir string) bool {
+	s, err := os.Stat(filepath.Join(dir, "oci-layout"))
+	return err == nil && !s.IsDir()
+}
+
+// FromLayout extracts the layers of an image of an OCI image layout into dir, and returns the digest of the manifest of
+// the image. The image is the one with the tag, or the one with the digest, or the only image of the layout.
+func FromLayout(ctx context.Context, dir, layout, tag, digest string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(layout, "index.json"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read OCI image layout %s: %w", layout, err)
+	}
+
+	var index manifest
+	if err := json.Unmarshal(data, &index); err != nil {
+		return "", fmt.Errorf("failed to parse the index of OCI image layout %s: %w", layout, err)
+	}
+
+	var image *descriptor
+	for _, desc := range index.Manifests {
+		if (digest != "" && desc.Digest == "sha256:"+digest) || (tag != "" && desc.Annotations[refNameAnnotation] == tag) {
+			image = &desc
+			break
+		}
+	}
+	switch {
+	case image == nil && (digest != "" || tag != ""):
+		return "", fmt.Errorf("OCI image layout %s has no image %s", layout, tagOrDigest(tag, digest))
+	case image == nil && len(index.Manifests) == 1:
+		image = &index.Manifests[0]
+	case image == nil:
+		return "", fmt.Errorf("OCI image layout %s has %d images, choose one with %s:<tag>", layout, len(index.Manifests), layout)
+	}
+
+	return pull(ctx, layoutStore(layout), dir, layout, image.Digest)
+}
+
+// FromRegistry pulls an image from an OCI registry and extracts its layers into dir, and returns the digest of the
+// manifest of the image. The reference is like host[:port]/repository[:tag]. If digest is set, the image is pulled by
+// digest instead of by tag. Registries on localhost are used over HTTP, and others over HTTPS.
+func FromRegistry(ctx context.Context, dir, ref, digest string) (string, error) {
+	host, repository, ok := strings.Cut(strings.TrimPrefix(ref, OCIScheme), "/")
+	if !ok || host == "" || repository == "" {
+		return "", fmt.Errorf("invalid OCI reference %s, expected %shost/repository[:tag]", ref, OCIScheme)
+	}
+
+	tag := "latest"
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository, tag = repository[:i], repository[i+1:]
+	}
+
+	scheme := "https"
+	if hostname, _, err := net.SplitHostPort(host); (err == nil && isLocalhost(hostname)) || isLocalhost(host) {
+		scheme = "http"
+	}
+
+	registry := &registryStore{
+		url: fmt.Sprintf("%s://%s/v2/%s", scheme, host, repository),
+	}
+
+	if digest != "" {
+		return pull(ctx, registry, dir, ref, "sha256:"+digest)
+	}
+
+	// A tag can't be verified, so the digest of the manifest that it refers to is what the image is verified with.
+	log.Warnf("Pulling %s by tag, which can change, pin it with %s<digest> to verify it", ref, DigestPrefix)
+	data, err := registry.get(ctx, "/manifests/"+tag, manifestMediaTypes)
+	if err != nil {
+		return "", err
+	}
+	actual, err := hashReader(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	return pullManifest(ctx, registry, dir, ref, data, actual)
+}
+
+func isLocalhost(host string) bool {
+	return host == "localhost" || net.ParseIP(host).IsLoopback()
+}
+
+func tagOrDigest(tag, digest string) string {
+	if digest != "" {
+		return DigestPrefix[1:] + digest
+	}
+	return tag
+}
+
+// pull pulls the manifest with the digest and extracts the layers of its image.
+func pull(ctx context.Context, blobs blobStore, dir, name, digest string) (string, error) {
+	hexDigest, err := sha256Hex(digest)
+	if err != nil {
+		return "", err
+	}
+	data, err := blobs.manifest(ctx, digest)
+	if err != nil {
+		return "", err
+	}
+	actual, err := hashReader(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	if err := verify(name, hexDigest, actual); err != nil {
+		return "", err
+	}
+	return pullManifest(ctx, blobs, dir, name, data, actual)
+}
+
+// pullManifest extracts the layers of the image of a manifest with the digest. If the manifest is an index, the image
+// is the one for the platform of this machine, or the first image.
+func pullManifest(ctx context.Context, blobs blobStore, dir, name string, data []byte, digest string) (string, error) {
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return "", fmt.Errorf("failed to parse the manifest of %s: %w", name, err)
+	}
+
+	if m.isIndex() {
+		image := choose(m.Manifests)
+		if image == nil {
+			return "", fmt.Errorf("the index of %s has no images", name)
+		}
+		return pull(ctx, blobs, dir, name, image.Digest)
+	}
+
+	return digest, store(dir, digest, func(target string) error {
+		l := newLimits()
+		for _, layer := range m.Layers {
+			if err := extractLayer(ctx, blobs, name, layer, target, l); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// choose returns the image of the platform of this machine, or the first image that is for any platform.
+func choose(images []descriptor) *descriptor {
+	var first *descriptor
+	for i, image := range images {
+		if image.Platform == nil {
+			if first == nil {
+				first = &images[i]
+			}
+			continue
+		}
+		if image.Platform.OS == runtime.GOOS && image.Platform.Architecture == runtime.GOARCH {
+			return &images[i]
+		}
+		if first == nil && image.Platform.OS != "unknown" {
+			first = &images[i]
+		}
+	}
+	return first
+}
+
+func extractLayer(ctx context.Context, blobs blobStore, name string, layer descriptor, target string, l *limits) error {
+	hexDigest, err := sha256Hex(layer.Digest)
+	if err != nil {
+		return err
+	}
+
+	body, err := blobs.blob(ctx, layer.Digest)
+	if err != nil {
+		return err
+	}
+	tmp, actual, err := download(body)
+	body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to download layer %s of %s: %w", layer.Digest, name, err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := verify(fmt.Sprintf("layer %s of %s", layer.Digest, name), hexDigest, actual); err != nil {
+		return err
+	}
+
+	switch mediaType := layer.MediaType; {
+	case strings.HasSuffix(mediaType, "tar+gzip") || strings.HasSuffix(mediaType, "tar.gzip"):
+		err = untarGzip(tmp, target, l)
+	case strings.HasSuffix(mediaType, ".tar"):
+		err = untar(tmp, target, l)
+	case layer.Annotations[titleAnnotation] != "":
+		var file string
+		if file, err = join(target, layer.Annotations[titleAnnotation]); err == nil {
+			if err = l.entry(); err == nil {
+				err = writeFile(file, tmp, 0644, l)
+			}
+		}
+	default:
+		return fmt.Errorf("layer %s of %s has unsupported media type %q", layer.Digest, name, mediaType)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to extract layer %s of %s: %w", layer.Digest, name, err)
+	}
+	return nil
+}
+
+func sha256Hex(digest string) (string, error) {
+	hexDigest, ok := strings.CutPrefix(digest, "sha256:")
+	if !ok {
+		return "", fmt.Errorf("unsupported digest %s, only sha256 digests are supported", digest)
+	}
+	return hexDigest, nil
+}
+
+// layoutStore is an OCI image layout directory.
+type layoutStore string
+
+func (l layoutStore) path(digest string) (string, error) {
+	hexDigest, err := sha256Hex(digest)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(string(l), "blobs", "sha256", hexDigest), nil
+}
+
+func (l layoutStore) manifest(_ context.Context, digest string) ([]byte, error) {
+	file, err := l.path(digest)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("OCI image layout %s has no manifest %s", string(l), digest)
+	}
+	return data, err
+}
+
+func (l layoutStore) blob(_ context.Context, digest string) (io.ReadCloser, error) {
+	file, err := l.path(digest)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("OCI image layout %s has no blob %s", string(l), digest)
+	}
+	return f, err
+}
+
+var manifestMediaTypes = strings.Join([]string{mediaTypeIndex, mediaTypeManifest, mediaTypeDockerList, mediaTypeDockerManifest}, ", ")
+
+// registryStore is a repository of an OCI registry.
+type registryStore struct {
+	url string
+}
+
+func (r *registryStore) manifest(ctx context.Context, digest string) ([]byte, error) {
+	return r.get(ctx, "/manifests/"+digest, manifestMediaTypes)
+}
+
+func (r *registryStore) blob(ctx context.Context, digest string) (io.ReadCloser, error) {
+	resp, err := r.do(ctx, "/blobs/"+digest, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (r *registryStore) get(ctx context.Context, path, accept string) ([]byte, error) {
+	resp, err := r.do(ctx, path, accept)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func (r *registryStore) do(ctx context.Context, path, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("error loading %s: %s", req.URL, resp.Status)
+	}
+	return resp, nil
+}
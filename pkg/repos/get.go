@@ -18,6 +18,7 @@ import (
 	"github.com/gptscript-ai/gptscript/pkg/credentials"
 	runtimeEnv "github.com/gptscript-ai/gptscript/pkg/env"
 	"github.com/gptscript-ai/gptscript/pkg/hash"
+	"github.com/gptscript-ai/gptscript/pkg/repos/archive"
 	"github.com/gptscript-ai/gptscript/pkg/repos/git"
 	"github.com/gptscript-ai/gptscript/pkg/repos/runtimes/golang"
 	"github.com/gptscript-ai/gptscript/pkg/types"
@@ -57,6 +58,7 @@ func (n noopRuntime) Setup(_ context.Context, _ types.Tool, _, _ string, _ []str
 type Manager struct {
 	storageDir       string
 	gitDir           string
+	archiveDir       string
 	runtimeDir       string
 	credHelperDirs   credentials.CredentialHelperDirs
 	runtimes         []Runtime
@@ -74,6 +76,7 @@ func New(cacheDir string, runtimes ...Runtime) *Manager {
 	return &Manager{
 		storageDir:     root,
 		gitDir:         filepath.Join(root, "git"),
+		archiveDir:     archive.Dir(cacheDir),
 		runtimeDir:     filepath.Join(root, "runtimes"),
 		credHelperDirs: credentials.GetCredentialHelperDirs(cacheDir),
 		runtimes:       runtimes,
@@ -109,8 +112,8 @@ func (m *Manager) deferredSetUpCredentialHelpers(ctx context.Context, cliCfg *co
 		helperName       = cliCfg.CredentialsStore
 		distInfo, suffix string
 	)
-	// The file helper is built-in and does not need to be downloaded.
-	if helperName == "file" {
+	// The file and encrypted helpers are built-in and do not need to be downloaded.
+	if helperName == "file" || helperName == "encrypted" {
 		return nil
 	}
 	switch helperName {
@@ -233,6 +236,10 @@ func (m *Manager) setup(ctx context.Context, runtime Runtime, tool types.Tool, e
 			if err := git.Checkout(ctx, m.gitDir, tool.Source.Repo.Root, tool.Source.Repo.Revision, target); err != nil {
 				return "", nil, err
 			}
+		} else if tool.Source.Repo.VCS == archive.VCS {
+			if err := archive.Checkout(m.archiveDir, tool.Source.Repo.Revision, target); err != nil {
+				return "", nil, err
+			}
 		} else {
 			if err := os.MkdirAll(target, 0755); err != nil {
 				return "", nil, err
@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"fmt"
+
+	context2 "github.com/gptscript-ai/gptscript/pkg/context"
+	"github.com/gptscript-ai/gptscript/pkg/engine"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+)
+
+// DebugStepType is what is about to happen when a run is paused by a debugger.
+type DebugStepType string
+
+const (
+	DebugStepCall       DebugStepType = "call"
+	DebugStepCompletion DebugStepType = "completion"
+)
+
+type DebugStep struct {
+	Type DebugStepType `json:"type"`
+	// Input is the input of the call, for call steps.
+	Input string `json:"input,omitempty"`
+	// Pending are the calls that the LLM asked for along with this one, by call ID, for call steps.
+	Pending map[string]engine.Call `json:"pending,omitempty"`
+	// Completion is the request that is about to be sent to the LLM, for completion steps.
+	Completion *types.CompletionRequest `json:"completion,omitempty"`
+}
+
+// DebugAction is what the debugger wants to happen for a step.
+type DebugAction string
+
+const (
+	DebugContinue DebugAction = "continue"
+	DebugSkip     DebugAction = "skip"
+	DebugResult   DebugAction = "result"
+)
+
+type DebugResponse struct {
+	// Action is continue if it isn't set. Calls can be skipped, or return Result without running, but requests to the
+	// LLM can only continue.
+	Action DebugAction `json:"action,omitempty"`
+	// Input, if set, replaces the input of a call that continues.
+	Input *string `json:"input,omitempty"`
+	// Result is the result of the call for the result action.
+	Result string `json:"result,omitempty"`
+}
+
+// DebuggerFunc is called before each tool call and each request to the LLM. Returning an error stops the run.
+type DebuggerFunc func(ctx engine.Context, step DebugStep) (DebugResponse, error)
+
+func (r *Runner) debug(callCtx engine.Context, monitor Monitor, step DebugStep) (DebugResponse, error) {
+	callCtx.Ctx = context2.AddPauseFuncToCtx(callCtx.Ctx, monitor.Pause)
+	resp, err := r.debugger(callCtx, step)
+	if err != nil {
+		return DebugResponse{}, err
+	}
+
+	switch resp.Action {
+	case "", DebugContinue:
+	case DebugSkip, DebugResult:
+		if step.Type != DebugStepCall {
+			return DebugResponse{}, fmt.Errorf("invalid debugger action %q for a request to the LLM", resp.Action)
+		}
+	default:
+		return DebugResponse{}, fmt.Errorf("invalid debugger action %q", resp.Action)
+	}
+	return resp, nil
+}
+
+// debugCall lets the debugger change or replace a call before it is made. A result is returned if the call should
+// not run.
+func (r *Runner) debugCall(callCtx engine.Context, monitor Monitor, input *string) (*string, error) {
+	if r.debugger == nil {
+		return nil, nil
+	}
+
+	var pending map[string]engine.Call
+	if callCtx.Parent != nil && callCtx.Parent.LastReturn != nil {
+		pending = callCtx.Parent.LastReturn.Calls
+	}
+
+	resp, err := r.debug(callCtx, monitor, DebugStep{
+		Type:    DebugStepCall,
+		Input:   *input,
+		Pending: pending,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.Action {
+	case DebugSkip:
+		return &[]string{"Tool call was skipped"}[0], nil
+	case DebugResult:
+		return &resp.Result, nil
+	}
+	if resp.Input != nil {
+		*input = *resp.Input
+	}
+	return nil, nil
+}
+
+// debugCompletion returns the function that lets the debugger see the requests of a call to the LLM before they are
+// sent. The context is a pointer so that the debugger sees the input context of the current request.
+func (r *Runner) debugCompletion(callCtx *engine.Context, monitor Monitor) func(types.CompletionRequest) error {
+	if r.debugger == nil {
+		return nil
+	}
+	return func(completion types.CompletionRequest) error {
+		_, err := r.debug(*callCtx, monitor, DebugStep{
+			Type:       DebugStepCompletion,
+			Completion: &completion,
+		})
+		return err
+	}
+}
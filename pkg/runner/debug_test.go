@@ -0,0 +1,178 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gptscript-ai/gptscript/pkg/credentials"
+	"github.com/gptscript-ai/gptscript/pkg/engine"
+	"github.com/gptscript-ai/gptscript/pkg/loader"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+// toolCallModel asks for a call of the search tool, and then returns the result of the call.
+type toolCallModel struct{}
+
+func (toolCallModel) Call(_ context.Context, req types.CompletionRequest, _ chan<- types.CompletionStatus) (*types.CompletionMessage, error) {
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role == types.CompletionMessageRoleTypeTool {
+		return &types.CompletionMessage{
+			Role:    types.CompletionMessageRoleTypeAssistant,
+			Content: types.Text("search returned " + last.ChatText()),
+		}, nil
+	}
+	return &types.CompletionMessage{
+		Role: types.CompletionMessageRoleTypeAssistant,
+		Content: []types.ContentPart{{
+			ToolCall: &types.CompletionToolCall{
+				Index: new(int),
+				ID:    "call1",
+				Function: types.CompletionFunctionCall{
+					Name:      "search",
+					Arguments: `{"query":"cats"}`,
+				},
+			},
+		}},
+	}, nil
+}
+
+func (toolCallModel) ProxyInfo() (string, string, error) {
+	return "", "", nil
+}
+
+const debugProgram = `
+tools: search
+
+Search for cats
+
+---
+name: search
+param: query: what to search for
+
+#!/bin/sh
+exit 1
+`
+
+func TestDebugger(t *testing.T) {
+	ctx := context.Background()
+	prg, err := loader.ProgramFromSource(ctx, debugProgram, "")
+	require.NoError(t, err)
+
+	var steps []string
+	r, err := New(toolCallModel{}, credentials.NoopStore{}, Options{
+		Debugger: func(ctx engine.Context, step DebugStep) (DebugResponse, error) {
+			steps = append(steps, string(step.Type)+" "+ctx.Tool.Name)
+			if step.Type == DebugStepCall && ctx.Tool.Name == "search" {
+				require.Equal(t, `{"query":"cats"}`, step.Input)
+				require.Contains(t, step.Pending, "call1")
+				return DebugResponse{Action: DebugResult, Result: "three cats"}, nil
+			}
+			if step.Type == DebugStepCompletion {
+				require.NotNil(t, step.Completion)
+			}
+			return DebugResponse{}, nil
+		},
+	})
+	require.NoError(t, err)
+
+	out, err := r.Run(ctx, prg, nil, "")
+	require.NoError(t, err)
+	require.Equal(t, "search returned three cats", out)
+	require.Equal(t, []string{
+		"call ",
+		"completion ",
+		"call search",
+		"completion ",
+	}, steps)
+}
+
+func TestDebuggerStop(t *testing.T) {
+	ctx := context.Background()
+	prg, err := loader.ProgramFromSource(ctx, debugProgram, "")
+	require.NoError(t, err)
+
+	stopped := errors.New("stopped")
+	r, err := New(toolCallModel{}, credentials.NoopStore{}, Options{
+		Debugger: func(_ engine.Context, step DebugStep) (DebugResponse, error) {
+			if step.Type == DebugStepCompletion {
+				return DebugResponse{}, stopped
+			}
+			return DebugResponse{}, nil
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = r.Run(ctx, prg, nil, "")
+	require.ErrorIs(t, err, stopped)
+}
+
+func TestDebuggerInvalidAction(t *testing.T) {
+	ctx := context.Background()
+	prg, err := loader.ProgramFromSource(ctx, debugProgram, "")
+	require.NoError(t, err)
+
+	r, err := New(toolCallModel{}, credentials.NoopStore{}, Options{
+		Debugger: func(_ engine.Context, step DebugStep) (DebugResponse, error) {
+			if step.Type == DebugStepCompletion {
+				return DebugResponse{Action: DebugSkip}, nil
+			}
+			return DebugResponse{}, nil
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = r.Run(ctx, prg, nil, "")
+	require.ErrorContains(t, err, "invalid debugger action")
+}
+
+func TestDebuggerSkipBeforeCredentials(t *testing.T) {
+	ctx := context.Background()
+	prg, err := loader.ProgramFromSource(ctx, `
+tools: search
+
+Search for cats
+
+---
+name: search
+param: query: what to search for
+credential: cred
+context: background
+
+#!/bin/sh
+exit 1
+
+---
+name: cred
+
+#!/bin/sh
+exit 1
+
+---
+name: background
+
+#!/bin/sh
+exit 1
+`, "")
+	require.NoError(t, err)
+
+	var steps []string
+	r, err := New(toolCallModel{}, credentials.NoopStore{}, Options{
+		Debugger: func(ctx engine.Context, step DebugStep) (DebugResponse, error) {
+			if step.Type == DebugStepCall {
+				steps = append(steps, ctx.Tool.Name)
+			}
+			if ctx.Tool.Name == "search" {
+				return DebugResponse{Action: DebugSkip}, nil
+			}
+			return DebugResponse{}, nil
+		},
+	})
+	require.NoError(t, err)
+
+	out, err := r.Run(ctx, prg, nil, "")
+	require.NoError(t, err)
+	require.Equal(t, "search returned Tool call was skipped", out)
+	require.Equal(t, []string{"", "search"}, steps)
+}
@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"maps"
+
+	"github.com/gptscript-ai/gptscript/pkg/credentials"
+	"github.com/gptscript-ai/gptscript/pkg/redact"
+)
+
+// redactingMonitor replaces the values of credentials and sensitive prompts in events before they are passed to the
+// monitor, so that they don't end up in logs or event streams.
+type redactingMonitor struct {
+	Monitor
+	redactor *redact.Redactor
+}
+
+func newRedactingMonitor(monitor Monitor, redactor *redact.Redactor) Monitor {
+	if redactor == nil {
+		return monitor
+	}
+	return &redactingMonitor{
+		Monitor:  monitor,
+		redactor: redactor,
+	}
+}
+
+func (r *redactingMonitor) Event(event Event) {
+	event.Content = r.redactor.String(event.Content)
+	event.ChatRequest = redact.JSON(r.redactor, event.ChatRequest)
+	event.ChatResponse = redact.JSON(r.redactor, event.ChatResponse)
+
+	if event.CallContext != nil {
+		callCtx := *event.CallContext
+		callCtx.DisplayText = r.redactor.String(callCtx.DisplayText)
+		callCtx.InputContext = redact.JSON(r.redactor, callCtx.InputContext)
+		event.CallContext = &callCtx
+	}
+
+	if len(event.ToolSubCalls) > 0 {
+		subCalls := maps.Clone(event.ToolSubCalls)
+		for id, call := range subCalls {
+			call.Input = r.redactor.String(call.Input)
+			subCalls[id] = call
+		}
+		event.ToolSubCalls = subCalls
+	}
+
+	r.Monitor.Event(event)
+}
+
+func (r *redactingMonitor) Stop(ctx context.Context, output string, err error) {
+	if err != nil {
+		if redacted := r.redactor.String(err.Error()); redacted != err.Error() {
+			err = errors.New(redacted)
+		}
+	}
+	r.Monitor.Stop(ctx, r.redactor.String(output), err)
+}
+
+// redactCredential records the values of c so that they are replaced in events and chat state.
+func (r *Runner) redactCredential(c *credentials.Credential) {
+	for _, v := range c.Env {
+		r.redactor.Add(v)
+	}
+	r.redactor.Add(c.RefreshToken)
+}
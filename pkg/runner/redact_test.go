@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/gptscript-ai/gptscript/pkg/credentials"
+	"github.com/gptscript-ai/gptscript/pkg/engine"
+	"github.com/gptscript-ai/gptscript/pkg/loader"
+	"github.com/gptscript-ai/gptscript/pkg/redact"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMonitor struct {
+	noopMonitor
+	events []Event
+	output string
+	err    error
+}
+
+func (r *recordingMonitor) Event(event Event) {
+	r.events = append(r.events, event)
+}
+
+func (r *recordingMonitor) Stop(_ context.Context, output string, err error) {
+	r.output = output
+	r.err = err
+}
+
+func TestRedactingMonitor(t *testing.T) {
+	redactor := redact.New()
+	redactor.Add("s3cr3t-token")
+
+	recorder := &recordingMonitor{}
+	monitor := newRedactingMonitor(recorder, redactor)
+
+	subCalls := map[string]engine.Call{
+		"call1": {ToolID: "tool", Input: `{"token":"s3cr3t-token"}`},
+	}
+	monitor.Event(Event{
+		Type:         EventTypeCallSubCalls,
+		Content:      "using s3cr3t-token",
+		ChatRequest:  map[string]any{"messages": []any{"s3cr3t-token"}},
+		ToolSubCalls: subCalls,
+	})
+	monitor.Stop(context.Background(), "output s3cr3t-token", errors.New("failed with s3cr3t-token"))
+
+	require.Len(t, recorder.events, 1)
+	event := recorder.events[0]
+	require.Equal(t, "using [REDACTED]", event.Content)
+	require.Equal(t, map[string]any{"messages": []any{"[REDACTED]"}}, event.ChatRequest)
+	require.Equal(t, `{"token":"[REDACTED]"}`, event.ToolSubCalls["call1"].Input)
+	// The events must not change the values used by the runner.
+	require.Equal(t, `{"token":"s3cr3t-token"}`, subCalls["call1"].Input)
+
+	require.Equal(t, "output [REDACTED]", recorder.output)
+	require.EqualError(t, recorder.err, "failed with [REDACTED]")
+
+	require.Same(t, recorder, newRedactingMonitor(recorder, nil))
+}
+
+type recordingFactory struct {
+	monitor *recordingMonitor
+}
+
+func (f recordingFactory) Start(context.Context, *types.Program, []string, string) (Monitor, error) {
+	return f.monitor, nil
+}
+
+func (f recordingFactory) Pause() func() {
+	return func() {}
+}
+
+// replyModel answers every request with the same message.
+type replyModel struct{}
+
+func (replyModel) Call(context.Context, types.CompletionRequest, chan<- types.CompletionStatus) (*types.CompletionMessage, error) {
+	return &types.CompletionMessage{
+		Role:    types.CompletionMessageRoleTypeAssistant,
+		Content: types.Text("noted"),
+	}, nil
+}
+
+func (replyModel) ProxyInfo() (string, string, error) {
+	return "", "", nil
+}
+
+func TestChatStateNotRedacted(t *testing.T) {
+	ctx := context.Background()
+	prg, err := loader.ProgramFromSource(ctx, "chat: true\n\nRemember what the user says", "")
+	require.NoError(t, err)
+
+	redactor := redact.New()
+	redactor.Add("s3cr3t-token")
+
+	recorder := &recordingMonitor{}
+	r, err := New(replyModel{}, credentials.NoopStore{}, Options{
+		MonitorFactory: recordingFactory{monitor: recorder},
+		Redactor:       redactor,
+	})
+	require.NoError(t, err)
+
+	resp, err := r.Chat(ctx, nil, prg, nil, "my token is s3cr3t-token")
+	require.NoError(t, err)
+	require.Equal(t, "noted", resp.Content)
+
+	// The state is passed back in the next turn, so it must have the real values
+	state, err := json.Marshal(resp.State)
+	require.NoError(t, err)
+	require.Contains(t, string(state), "my token is s3cr3t-token")
+
+	require.NotEmpty(t, recorder.events)
+	for _, event := range recorder.events {
+		data, err := json.Marshal(event)
+		require.NoError(t, err)
+		require.NotContains(t, string(data), "s3cr3t-token")
+	}
+
+	resp, err = r.Chat(ctx, resp.State, prg, nil, "what is my token?")
+	require.NoError(t, err)
+	state, err = json.Marshal(resp.State)
+	require.NoError(t, err)
+	require.Contains(t, string(state), "my token is s3cr3t-token")
+}
@@ -10,10 +10,15 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gptscript-ai/gptscript/pkg/approvals"
+	"github.com/gptscript-ai/gptscript/pkg/audit"
 	"github.com/gptscript-ai/gptscript/pkg/builtin"
+	"github.com/gptscript-ai/gptscript/pkg/cache"
 	context2 "github.com/gptscript-ai/gptscript/pkg/context"
 	"github.com/gptscript-ai/gptscript/pkg/credentials"
 	"github.com/gptscript-ai/gptscript/pkg/engine"
+	"github.com/gptscript-ai/gptscript/pkg/policy"
+	"github.com/gptscript-ai/gptscript/pkg/redact"
 	"github.com/gptscript-ai/gptscript/pkg/types"
 	"golang.org/x/exp/maps"
 )
@@ -34,18 +39,37 @@ type Options struct {
 	RuntimeManager      engine.RuntimeManager `usage:"-"`
 	StartPort           int64                 `usage:"-"`
 	EndPort             int64                 `usage:"-"`
+	DaemonDir           string                `usage:"-"`
 	CredentialOverrides []string              `usage:"-"`
 	Sequential          bool                  `usage:"-"`
 	Authorizer          AuthorizerFunc        `usage:"-"`
+	Redactor            *redact.Redactor      `usage:"-"`
+	// Policies decide whether tool calls are allowed. Authorizer is only used for the calls that they say to ask about.
+	Policies []*policy.Policy `usage:"-"`
+	// Audit is the log that the calls of command tools and the credentials that are used are written to.
+	Audit *audit.Log `usage:"-"`
+	// Cache stores the results of the tools that ask for their results to be cached.
+	Cache *cache.Client `usage:"-"`
+	// Debugger, if set, is called before each tool call and each request to the LLM so that they can be inspected
+	// and changed.
+	Debugger DebuggerFunc `usage:"-"`
+	// Mock, if set, is called before each tool call, and its result is used instead of running the tool.
+	Mock MockFunc `usage:"-"`
 }
 
 type AuthorizerResponse struct {
 	Accept  bool
 	Message string
+	// Remember is how long the answer is remembered for. It is only remembered for this call by default.
+	Remember approvals.Scope
 }
 
 type AuthorizerFunc func(ctx engine.Context, input string) (AuthorizerResponse, error)
 
+// MockFunc returns the result of a call if the tool is mocked. Nothing of a mocked call runs, not even its credential
+// tools.
+type MockFunc func(ctx engine.Context, input string) (result string, mocked bool)
+
 func DefaultAuthorizer(engine.Context, string) (AuthorizerResponse, error) {
 	return AuthorizerResponse{
 		Accept: true,
@@ -58,13 +82,26 @@ func Complete(opts ...Options) (result Options) {
 		result.RuntimeManager = types.FirstSet(opt.RuntimeManager, result.RuntimeManager)
 		result.StartPort = types.FirstSet(opt.StartPort, result.StartPort)
 		result.EndPort = types.FirstSet(opt.EndPort, result.EndPort)
+		result.DaemonDir = types.FirstSet(opt.DaemonDir, result.DaemonDir)
 		result.Sequential = types.FirstSet(opt.Sequential, result.Sequential)
+		result.Redactor = types.FirstSet(opt.Redactor, result.Redactor)
+		result.Audit = types.FirstSet(opt.Audit, result.Audit)
+		result.Cache = types.FirstSet(opt.Cache, result.Cache)
 		if opt.Authorizer != nil {
 			result.Authorizer = opt.Authorizer
 		}
+		if opt.Debugger != nil {
+			result.Debugger = opt.Debugger
+		}
+		if opt.Mock != nil {
+			result.Mock = opt.Mock
+		}
 		if opt.CredentialOverrides != nil {
 			result.CredentialOverrides = append(result.CredentialOverrides, opt.CredentialOverrides...)
 		}
+		if opt.Policies != nil {
+			result.Policies = append(result.Policies, opt.Policies...)
+		}
 	}
 	return
 }
@@ -89,12 +126,19 @@ func complete(opts ...Options) Options {
 type Runner struct {
 	c              engine.Model
 	auth           AuthorizerFunc
+	canAsk         bool
+	policies       []*policy.Policy
+	audit          *audit.Log
+	cache          *cache.Client
+	debugger       DebuggerFunc
+	mock           MockFunc
 	factory        MonitorFactory
 	runtimeManager engine.RuntimeManager
 	credMutex      sync.Mutex
 	credOverrides  []string
 	credStore      credentials.CredentialStore
 	sequential     bool
+	redactor       *redact.Redactor
 }
 
 func New(client engine.Model, credStore credentials.CredentialStore, opts ...Options) (*Runner, error) {
@@ -109,6 +153,13 @@ func New(client engine.Model, credStore credentials.CredentialStore, opts ...Opt
 		credStore:      credStore,
 		sequential:     opt.Sequential,
 		auth:           opt.Authorizer,
+		canAsk:         Complete(opts...).Authorizer != nil,
+		policies:       opt.Policies,
+		audit:          opt.Audit,
+		cache:          opt.Cache,
+		debugger:       opt.Debugger,
+		mock:           opt.Mock,
+		redactor:       opt.Redactor,
 	}
 
 	if opt.StartPort != 0 {
@@ -118,6 +169,10 @@ func New(client engine.Model, credStore credentials.CredentialStore, opts ...Opt
 		engine.SetPorts(opt.StartPort, opt.EndPort)
 	}
 
+	if opt.DaemonDir != "" {
+		engine.SetDaemonDir(opt.DaemonDir)
+	}
+
 	return runner, nil
 }
 
@@ -159,10 +214,15 @@ func (r *Runner) Chat(ctx context.Context, prevState ChatState, prg types.Progra
 		}
 	}
 
-	monitor, err := r.factory.Start(ctx, &prg, env, input)
+	if r.audit != nil {
+		ctx = audit.EnsureRunID(ctx)
+	}
+
+	monitor, err := r.factory.Start(ctx, &prg, r.redactor.Env(env), r.redactor.String(input))
 	if err != nil {
 		return resp, err
 	}
+	monitor = newRedactingMonitor(monitor, r.redactor)
 	defer func() {
 		monitor.Stop(ctx, resp.Content, err)
 	}()
@@ -231,6 +291,7 @@ type Event struct {
 	Usage              types.Usage            `json:"usage,omitempty"`
 	ChatResponseCached bool                   `json:"chatResponseCached,omitempty"`
 	Content            string                 `json:"content,omitempty"`
+	Policy             *policy.Result         `json:"policy,omitempty"`
 }
 
 type EventType string
@@ -243,6 +304,8 @@ var (
 	EventTypeCallProgress EventType = "callProgress"
 	EventTypeChat         EventType = "callChat"
 	EventTypeCallFinish   EventType = "callFinish"
+	EventTypeCallPolicy   EventType = "callPolicy"
+	EventTypeCallCacheHit EventType = "callCacheHit"
 	EventTypeRunFinish    EventType = "runFinish"
 )
 
@@ -364,6 +427,10 @@ func (r *Runner) getContext(callCtx engine.Context, state *State, monitor Monito
 }
 
 func (r *Runner) call(callCtx engine.Context, monitor Monitor, env []string, input string) (*State, error) {
+	if result, ok := r.mocked(callCtx, monitor, input); ok {
+		return result, nil
+	}
+
 	result, err := r.start(callCtx, nil, monitor, env, input)
 	if err != nil {
 		return nil, err
@@ -371,6 +438,34 @@ func (r *Runner) call(callCtx engine.Context, monitor Monitor, env []string, inp
 	return r.resume(callCtx, monitor, env, result)
 }
 
+// mocked returns the result of the call if the tool is mocked. The call is only reported to the monitor, nothing of it
+// runs, so a mocked tool doesn't need its credentials, input or output filters, or an approval.
+func (r *Runner) mocked(callCtx engine.Context, monitor Monitor, input string) (*State, bool) {
+	if r.mock == nil {
+		return nil, false
+	}
+	result, ok := r.mock(callCtx, input)
+	if !ok {
+		return nil, false
+	}
+
+	monitor.Event(Event{
+		Time:        time.Now(),
+		CallContext: callCtx.GetCallContext(),
+		Type:        EventTypeCallStart,
+		Content:     input,
+	})
+	monitor.Event(Event{
+		Time:        time.Now(),
+		CallContext: callCtx.GetCallContext(),
+		Type:        EventTypeCallFinish,
+		Content:     getEventContent(result, callCtx),
+	})
+	return &State{
+		Result: &result,
+	}, true
+}
+
 func (r *Runner) start(callCtx engine.Context, state *State, monitor Monitor, env []string, input string) (*State, error) {
 	progress, progressClose := streamProgress(&callCtx, monitor)
 	defer progressClose()
@@ -387,13 +482,25 @@ func (r *Runner) start(callCtx engine.Context, state *State, monitor Monitor, en
 		return nil, err
 	}
 
+	// The debugger comes before the credentials and the context of the call, so that a call it skips doesn't run them.
+	if result, err := r.debugCall(callCtx, monitor, &input); err != nil {
+		return nil, err
+	} else if result != nil {
+		return &State{
+			Continuation: &engine.Return{
+				Result: result,
+			},
+			debugged: true,
+		}, nil
+	}
+
 	credTools, err := callCtx.Tool.GetToolsByType(callCtx.Program, types.ToolTypeCredential)
 	if err != nil {
 		return nil, err
 	}
 	if len(credTools) > 0 {
 		var err error
-		env, err = r.handleCredentials(callCtx, monitor, env, credTools)
+		env, err = r.handleCredentials(callCtx, monitor, env, credTools, true)
 		if err != nil {
 			return nil, err
 		}
@@ -409,13 +516,22 @@ func (r *Runner) start(callCtx engine.Context, state *State, monitor Monitor, en
 		RuntimeManager: runtimeWithLogger(callCtx, monitor, r.runtimeManager),
 		Progress:       progress,
 		Env:            env,
+		Cache:          r.cache,
+		Redactor:       r.redactor,
+		ResultCached: func() {
+			monitor.Event(Event{
+				Time:        time.Now(),
+				CallContext: callCtx.GetCallContext(),
+				Type:        EventTypeCallCacheHit,
+			})
+		},
+		BeforeCompletion: r.debugCompletion(&callCtx, monitor),
 	}
 
 	callCtx.Ctx = context2.AddPauseFuncToCtx(callCtx.Ctx, monitor.Pause)
 
-	_, safe := builtin.SafeTools[callCtx.Tool.ID]
-	if callCtx.Tool.IsCommand() && !safe {
-		authResp, err := r.auth(callCtx, input)
+	if callCtx.Tool.IsCommand() {
+		authResp, err := r.authorize(callCtx, monitor, input)
 		if err != nil {
 			return nil, err
 		}
@@ -425,6 +541,9 @@ func (r *Runner) start(callCtx engine.Context, state *State, monitor Monitor, en
 			if msg == "" {
 				msg = "Tool call request has been denied"
 			}
+			if err := r.auditCall(callCtx, input, audit.StatusDenied, nil, nil, nil); err != nil {
+				return nil, err
+			}
 			return &State{
 				Continuation: &engine.Return{
 					Result: &msg,
@@ -433,7 +552,25 @@ func (r *Runner) start(callCtx engine.Context, state *State, monitor Monitor, en
 		}
 	}
 
+	var exitCode *int
+	e.CommandExited = func(code int) {
+		exitCode = &code
+	}
+
 	ret, err := e.Start(callCtx, input)
+	if callCtx.Tool.IsCommand() {
+		status := audit.StatusOK
+		if err != nil || (exitCode != nil && *exitCode != 0) {
+			status = audit.StatusError
+		}
+		var output *string
+		if ret != nil {
+			output = ret.Result
+		}
+		if err := r.auditCall(callCtx, input, status, exitCode, output, err); err != nil {
+			return nil, err
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -443,6 +580,121 @@ func (r *Runner) start(callCtx engine.Context, state *State, monitor Monitor, en
 	}, nil
 }
 
+// auditCall writes the call of a command tool to the audit log, if there is one.
+func (r *Runner) auditCall(callCtx engine.Context, input, status string, exitCode *int, output *string, callErr error) error {
+	if r.audit == nil {
+		return nil
+	}
+
+	tool := callCtx.Tool
+	entry := audit.Entry{
+		RunID:       audit.RunID(callCtx.Ctx),
+		CallID:      callCtx.ID,
+		Action:      audit.ActionCommand,
+		ToolID:      tool.ID,
+		ToolName:    tool.Name,
+		Source:      policy.Source(tool),
+		Interpreter: tool.GetInterpreter(),
+		Input:       r.redactor.String(input),
+		Status:      status,
+		ExitCode:    exitCode,
+	}
+	switch {
+	case tool.IsOpenAPI():
+		entry.Action = audit.ActionOpenAPI
+	case tool.IsHTTP():
+		entry.Action = audit.ActionHTTP
+	case tool.BuiltinFunc != nil || strings.HasPrefix(tool.Instructions, types.CommandPrefix+"sys."):
+		entry.Action = audit.ActionBuiltin
+	}
+	if callErr != nil {
+		entry.Error = r.redactor.String(callErr.Error())
+	}
+	if output != nil {
+		entry.OutputHash = audit.HashOutput(*output)
+	}
+
+	if err := r.audit.Append(entry); err != nil {
+		return fmt.Errorf("failed to write to the audit log: %w", err)
+	}
+	return nil
+}
+
+// auditCredential writes the use of a credential by a tool to the audit log, if there is one.
+func (r *Runner) auditCredential(callCtx engine.Context, name, credCtx, source string, env map[string]string) error {
+	if r.audit == nil {
+		return nil
+	}
+
+	envVars := maps.Keys(env)
+	sort.Strings(envVars)
+	if err := r.audit.Append(audit.Entry{
+		RunID:             audit.RunID(callCtx.Ctx),
+		CallID:            callCtx.ID,
+		Action:            audit.ActionCredential,
+		ToolID:            callCtx.Tool.ID,
+		ToolName:          callCtx.Tool.Name,
+		Source:            policy.Source(callCtx.Tool),
+		Status:            audit.StatusOK,
+		Credential:        name,
+		CredentialContext: credCtx,
+		CredentialSource:  source,
+		EnvVars:           envVars,
+	}); err != nil {
+		return fmt.Errorf("failed to write to the audit log: %w", err)
+	}
+	return nil
+}
+
+// authorize decides whether a command tool can be called. Without policies, the authorizer decides for every tool that
+// isn't safe. With policies, the authorizer only decides for the calls that the policies say to ask about.
+func (r *Runner) authorize(callCtx engine.Context, monitor Monitor, input string) (AuthorizerResponse, error) {
+	_, safe := builtin.SafeTools[callCtx.Tool.ID]
+	if len(r.policies) == 0 {
+		if safe {
+			return AuthorizerResponse{
+				Accept: true,
+			}, nil
+		}
+		return r.auth(callCtx, input)
+	}
+
+	result := policy.Evaluate(r.policies, policy.NewRequest(callCtx.Tool, input))
+	monitor.Event(Event{
+		Time:        time.Now(),
+		CallContext: callCtx.GetCallContext(),
+		Type:        EventTypeCallPolicy,
+		Policy:      &result,
+	})
+
+	reason := "policy rule " + result.RuleID
+	if result.RuleID == "" {
+		reason = "the default policy decision"
+	}
+
+	switch result.Decision {
+	case policy.Allow:
+		return AuthorizerResponse{
+			Accept: true,
+		}, nil
+	case policy.Ask:
+		if r.canAsk {
+			return r.auth(callCtx, input)
+		}
+		return AuthorizerResponse{
+			Message: fmt.Sprintf("Tool call request requires confirmation because of %s, but confirmation is not enabled", reason),
+		}, nil
+	default:
+		msg := result.Message
+		if msg == "" {
+			msg = fmt.Sprintf("Tool call request has been denied because of %s", reason)
+		}
+		return AuthorizerResponse{
+			Message: msg,
+		}, nil
+	}
+}
+
 type State struct {
 	Continuation       *engine.Return `json:"continuation,omitempty"`
 	ContinuationToolID string         `json:"continuationToolID,omitempty"`
@@ -453,6 +705,9 @@ type State struct {
 	SubCallID   string          `json:"subCallID,omitempty"`
 
 	InputContexts []engine.InputContext `json:"inputContexts,omitempty"`
+
+	// debugged is set when the debugger skipped the call or gave its result, so it has no credentials to resolve.
+	debugged bool
 }
 
 func (s State) WithResumeInput(input *string) *State {
@@ -507,9 +762,9 @@ func (r *Runner) resume(callCtx engine.Context, monitor Monitor, env []string, s
 	if err != nil {
 		return nil, err
 	}
-	if len(credTools) > 0 {
+	if len(credTools) > 0 && !state.debugged {
 		var err error
-		env, err = r.handleCredentials(callCtx, monitor, env, credTools)
+		env, err = r.handleCredentials(callCtx, monitor, env, credTools, false)
 		if err != nil {
 			return nil, err
 		}
@@ -588,10 +843,11 @@ func (r *Runner) resume(callCtx engine.Context, monitor Monitor, env []string, s
 		})
 
 		e := engine.Engine{
-			Model:          r.c,
-			RuntimeManager: runtimeWithLogger(callCtx, monitor, r.runtimeManager),
-			Progress:       progress,
-			Env:            env,
+			Model:            r.c,
+			RuntimeManager:   runtimeWithLogger(callCtx, monitor, r.runtimeManager),
+			Progress:         progress,
+			Env:              env,
+			BeforeCompletion: r.debugCompletion(&callCtx, monitor),
 		}
 
 		var contentInput string
@@ -800,7 +1056,9 @@ func getEventContent(content string, callCtx engine.Context) string {
 	return content
 }
 
-func (r *Runner) handleCredentials(callCtx engine.Context, monitor Monitor, env []string, credToolRefs []types.ToolReference) ([]string, error) {
+// handleCredentials adds the environment variables of the credentials of the tool to env. The credentials are written
+// to the audit log if auditUse is set, which is only the case when the call starts, so that they are logged once per call.
+func (r *Runner) handleCredentials(callCtx engine.Context, monitor Monitor, env []string, credToolRefs []types.ToolReference, auditUse bool) ([]string, error) {
 	// Since credential tools (usually) prompt the user, we want to only run one at a time.
 	r.credMutex.Lock()
 	defer r.credMutex.Unlock()
@@ -837,8 +1095,14 @@ func (r *Runner) handleCredentials(callCtx engine.Context, monitor Monitor, env
 		// Check whether the credential was overridden before we attempt to find it in the store or run the tool.
 		if override, exists := credOverrides[credName]; exists {
 			for k, v := range override {
+				r.redactor.Add(v)
 				env = append(env, fmt.Sprintf("%s=%s", k, v))
 			}
+			if auditUse {
+				if err := r.auditCredential(callCtx, credName, "", audit.CredentialSourceOverride, override); err != nil {
+					return nil, err
+				}
+			}
 			continue
 		}
 
@@ -870,11 +1134,15 @@ func (r *Runner) handleCredentials(callCtx engine.Context, monitor Monitor, env
 			c = &credentials.Credential{}
 		}
 
+		credSource := audit.CredentialSourceStore
+
 		// If the credential doesn't already exist in the store, run the credential tool in order to get the value,
 		// and save it in the store.
-		if !exists || c.IsExpired() {
-			// If the existing credential is expired, we need to provide it to the cred tool through the environment.
-			if exists && c.IsExpired() {
+		if !exists || c.ShouldRefresh() {
+			credSource = audit.CredentialSourceTool
+			// If the existing credential is expired, or about to be, we need to provide it to the cred tool through the environment.
+			if exists && c.ShouldRefresh() {
+				r.redactCredential(c)
 				credJSON, err := json.Marshal(c)
 				if err != nil {
 					return nil, fmt.Errorf("failed to marshal credential: %w", err)
@@ -937,9 +1205,15 @@ func (r *Runner) handleCredentials(callCtx engine.Context, monitor Monitor, env
 			nearestExpiration = c.ExpiresAt
 		}
 
+		r.redactCredential(c)
 		for k, v := range c.Env {
 			env = append(env, fmt.Sprintf("%s=%s", k, v))
 		}
+		if auditUse {
+			if err := r.auditCredential(callCtx, credName, c.Context, credSource, c.Env); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	if nearestExpiration != nil {
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/gptscript-ai/gptscript/pkg/approvals"
 	"github.com/gptscript-ai/gptscript/pkg/auth"
 	gcontext "github.com/gptscript-ai/gptscript/pkg/context"
 	"github.com/gptscript-ai/gptscript/pkg/engine"
@@ -13,14 +14,24 @@ import (
 	gserver "github.com/gptscript-ai/gptscript/pkg/server"
 )
 
-func (s *server) authorize(ctx engine.Context, input string) (runner.AuthorizerResponse, error) {
-	defer gcontext.GetPauseFuncFromCtx(ctx.Ctx)()()
+// authorizer returns an authorizer for a run. The answers that are remembered for the run are only used by it.
+func (s *server) authorizer() runner.AuthorizerFunc {
+	session := approvals.NewSession(s.approvals)
+	return func(ctx engine.Context, input string) (runner.AuthorizerResponse, error) {
+		if auth.IsSafe(ctx) {
+			return runner.AuthorizerResponse{
+				Accept: true,
+			}, nil
+		}
 
-	if auth.IsSafe(ctx) {
-		return runner.AuthorizerResponse{
-			Accept: true,
-		}, nil
+		return auth.Remembered(session, ctx, func() (runner.AuthorizerResponse, error) {
+			return s.authorize(ctx, input)
+		})
 	}
+}
+
+func (s *server) authorize(ctx engine.Context, input string) (runner.AuthorizerResponse, error) {
+	defer gcontext.GetPauseFuncFromCtx(ctx.Ctx)()()
 
 	s.lock.RLock()
 	authChan := s.waitingToConfirm[ctx.ID]
@@ -54,6 +65,7 @@ func (s *server) authorize(ctx engine.Context, input string) (runner.AuthorizerR
 	}
 
 	// Wait for the confirmation to come through.
+	defer s.metrics.observeWait("confirm", time.Now())
 	select {
 	case <-ctx.Ctx.Done():
 		return runner.AuthorizerResponse{}, ctx.Ctx.Err()
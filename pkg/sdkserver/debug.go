@@ -0,0 +1,82 @@
+package sdkserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	gcontext "github.com/gptscript-ai/gptscript/pkg/context"
+	"github.com/gptscript-ai/gptscript/pkg/engine"
+	"github.com/gptscript-ai/gptscript/pkg/runner"
+	gserver "github.com/gptscript-ai/gptscript/pkg/server"
+)
+
+// debug sends an event for a step of a run that is being debugged, and waits for the response to it. The steps of a
+// call happen one after another, so the call ID is used to respond to a step.
+func (s *server) debug(ctx engine.Context, step runner.DebugStep) (runner.DebugResponse, error) {
+	defer gcontext.GetPauseFuncFromCtx(ctx.Ctx)()()
+
+	s.lock.Lock()
+	if s.waitingToDebug[ctx.ID] != nil {
+		s.lock.Unlock()
+		return runner.DebugResponse{}, fmt.Errorf("debug called multiple times for same ID: %s", ctx.ID)
+	}
+	debugChan := make(chan runner.DebugResponse)
+	s.waitingToDebug[ctx.ID] = debugChan
+	s.lock.Unlock()
+	defer func(id string) {
+		s.lock.Lock()
+		delete(s.waitingToDebug, id)
+		s.lock.Unlock()
+	}(ctx.ID)
+
+	s.events.C <- event{
+		Event: gserver.Event{
+			Event: runner.Event{
+				Time:        time.Now(),
+				CallContext: ctx.GetCallContext(),
+				Type:        CallDebug,
+			},
+			RunID: gserver.RunIDFromContext(ctx.Ctx),
+		},
+		DebugStep: &step,
+	}
+
+	// Wait for the response to come through.
+	defer s.metrics.observeWait("debug", time.Now())
+	select {
+	case <-ctx.Ctx.Done():
+		return runner.DebugResponse{}, ctx.Ctx.Err()
+	case debugResponse := <-debugChan:
+		return debugResponse, nil
+	}
+}
+
+func (s *server) debugResponse(w http.ResponseWriter, r *http.Request) {
+	logger := gcontext.GetLogger(r.Context())
+	id := r.PathValue("id")
+
+	s.lock.RLock()
+	debugChan := s.waitingToDebug[id]
+	s.lock.RUnlock()
+
+	if debugChan == nil {
+		writeError(logger, w, http.StatusNotFound, fmt.Errorf("no debug step found with id %q", id))
+		return
+	}
+
+	var debugResponse runner.DebugResponse
+	if err := json.NewDecoder(r.Body).Decode(&debugResponse); err != nil {
+		writeError(logger, w, http.StatusBadRequest, fmt.Errorf("failed to decode request body: %w", err))
+		return
+	}
+
+	// Don't block here because, if the debugger is no longer waiting on this then it will never unblock.
+	select {
+	case debugChan <- debugResponse:
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusConflict)
+	}
+}
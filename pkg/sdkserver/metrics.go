@@ -0,0 +1,241 @@
+package sdkserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gptscript-ai/gptscript/pkg/daemon"
+	"github.com/gptscript-ai/gptscript/pkg/engine"
+	"github.com/gptscript-ai/gptscript/pkg/runner"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics are the Prometheus metrics of the server. Apart from the time spent waiting for confirmations and prompts,
+// which is measured by the handlers that wait, they are collected from the events of the runs.
+type metrics struct {
+	registry *prometheus.Registry
+
+	runsStarted   prometheus.Counter
+	runsFinished  prometheus.Counter
+	runsFailed    prometheus.Counter
+	toolCalls     *prometheus.CounterVec
+	llmDuration   *prometheus.HistogramVec
+	llmTokens     *prometheus.CounterVec
+	cacheRequests *prometheus.CounterVec
+	waitDuration  *prometheus.HistogramVec
+
+	lock        sync.Mutex
+	completions map[string]completion
+	cacheHits   map[string]bool
+}
+
+type completion struct {
+	start time.Time
+	model string
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		runsStarted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "gptscript_runs_started_total",
+			Help: "The number of runs that were started.",
+		}),
+		runsFinished: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "gptscript_runs_finished_total",
+			Help: "The number of runs that finished, including those that failed.",
+		}),
+		runsFailed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "gptscript_runs_failed_total",
+			Help: "The number of runs that finished with an error.",
+		}),
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gptscript_tool_calls_total",
+			Help: "The number of calls of tools, by how the tool runs and the category of the call.",
+		}, []string{"type", "category"}),
+		llmDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gptscript_llm_request_duration_seconds",
+			Help:    "The time that requests to LLMs take, by model.",
+			Buckets: prometheus.ExponentialBuckets(0.25, 2, 10),
+		}, []string{"model"}),
+		llmTokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gptscript_llm_tokens_total",
+			Help: "The number of tokens used by requests to LLMs, by model and whether they are input or output tokens.",
+		}, []string{"model", "type"}),
+		cacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gptscript_cache_requests_total",
+			Help: "The number of LLM responses and tool results that could come from the cache, by whether they did.",
+		}, []string{"kind", "result"}),
+		waitDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gptscript_wait_duration_seconds",
+			Help:    "The time that runs wait for confirmations and prompts to be answered.",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+		}, []string{"type"}),
+		completions: map[string]completion{},
+		cacheHits:   map[string]bool{},
+	}
+
+	m.registry.MustRegister(
+		m.runsStarted,
+		m.runsFinished,
+		m.runsFailed,
+		m.toolCalls,
+		m.llmDuration,
+		m.llmTokens,
+		m.cacheRequests,
+		m.waitDuration,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "gptscript_daemons_running",
+			Help: "The number of daemons that are running.",
+		}, runningDaemons),
+	)
+	return m
+}
+
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// collect updates the metrics from the events until the channel is closed.
+func (m *metrics) collect(events <-chan event) {
+	for e := range events {
+		m.observe(e)
+	}
+}
+
+func (m *metrics) observe(e event) {
+	switch e.Type {
+	case runner.EventTypeRunStart:
+		m.runsStarted.Inc()
+	case runner.EventTypeRunFinish:
+		m.runsFinished.Inc()
+		if e.Err != "" {
+			m.runsFailed.Inc()
+		}
+	case runner.EventTypeCallStart:
+		if e.CallContext != nil {
+			m.toolCalls.WithLabelValues(toolType(e.CallContext.Tool), string(e.CallContext.ToolCategory)).Inc()
+		}
+	case runner.EventTypeChat:
+		m.observeChat(e)
+	case runner.EventTypeCallCacheHit:
+		if e.CallContext != nil {
+			m.lock.Lock()
+			m.cacheHits[e.CallContext.ID] = true
+			m.lock.Unlock()
+			m.cacheRequests.WithLabelValues("tool", "hit").Inc()
+		}
+	case runner.EventTypeCallFinish:
+		if e.CallContext != nil {
+			m.lock.Lock()
+			hit := m.cacheHits[e.CallContext.ID]
+			delete(m.cacheHits, e.CallContext.ID)
+			m.lock.Unlock()
+			if !hit && cachesResults(e.CallContext.Tool) {
+				m.cacheRequests.WithLabelValues("tool", "miss").Inc()
+			}
+		}
+	}
+}
+
+func (m *metrics) observeChat(e event) {
+	if e.CallContext == nil || e.CallContext.Tool.IsCommand() {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if e.ChatResponse == nil {
+		if e.ChatRequest != nil {
+			model := requestModel(e.ChatRequest)
+			if model == "" {
+				model = e.CallContext.Tool.Parameters.ModelName
+			}
+			m.completions[e.ChatCompletionID] = completion{
+				start: e.Time,
+				model: model,
+			}
+		}
+		return
+	}
+
+	c, ok := m.completions[e.ChatCompletionID]
+	if !ok {
+		return
+	}
+	delete(m.completions, e.ChatCompletionID)
+
+	m.llmDuration.WithLabelValues(c.model).Observe(e.Time.Sub(c.start).Seconds())
+	m.llmTokens.WithLabelValues(c.model, "input").Add(float64(e.Usage.PromptTokens))
+	m.llmTokens.WithLabelValues(c.model, "output").Add(float64(e.Usage.CompletionTokens))
+	if e.ChatResponseCached {
+		m.cacheRequests.WithLabelValues("llm", "hit").Inc()
+	} else {
+		m.cacheRequests.WithLabelValues("llm", "miss").Inc()
+	}
+}
+
+// observeWait records the time that was spent waiting for a confirmation or prompt to be answered.
+func (m *metrics) observeWait(waitType string, start time.Time) {
+	m.waitDuration.WithLabelValues(waitType).Observe(time.Since(start).Seconds())
+}
+
+// toolType returns how the tool runs, which is the same as how the engine decides to run it.
+func toolType(tool types.Tool) string {
+	switch {
+	case tool.BuiltinFunc != nil:
+		return "builtin"
+	case !tool.IsCommand():
+		return "llm"
+	case tool.IsHTTP():
+		return "http"
+	case tool.IsDaemon():
+		return "daemon"
+	case tool.IsOpenAPI():
+		return "openapi"
+	case tool.IsEcho():
+		return "echo"
+	case tool.IsMCP(), tool.IsMCPInvoke():
+		return "mcp"
+	default:
+		return "command"
+	}
+}
+
+// cachesResults returns whether the result of a call of the tool can come from the cache. Only the results of
+// commands, HTTP tools, and OpenAPI tools are cached.
+func cachesResults(tool types.Tool) bool {
+	switch toolType(tool) {
+	case "command", "http", "openapi":
+		return tool.CachesResults()
+	default:
+		return false
+	}
+}
+
+func requestModel(request any) string {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return ""
+	}
+	var req struct {
+		Model string `json:"model"`
+	}
+	_ = json.Unmarshal(data, &req)
+	return req.Model
+}
+
+func runningDaemons() float64 {
+	var count int
+	for _, d := range engine.ListDaemons() {
+		if d.Status == daemon.StatusRunning {
+			count++
+		}
+	}
+	return float64(count)
+}
@@ -0,0 +1,59 @@
+package sdkserver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gptscript-ai/gptscript/pkg/engine"
+	"github.com/gptscript-ai/gptscript/pkg/runner"
+	gserver "github.com/gptscript-ai/gptscript/pkg/server"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	m := newMetrics()
+
+	cached := true
+	var (
+		now     = time.Now()
+		llmCall = &engine.CallContext{}
+		cmdCall = &engine.CallContext{}
+	)
+	llmCall.ID = "1"
+	llmCall.Tool = types.Tool{ToolDef: types.ToolDef{Parameters: types.Parameters{ModelName: "gpt-4o"}}}
+	cmdCall.ID = "2"
+	cmdCall.ToolCategory = engine.ContextToolCategory
+	cmdCall.Tool = types.Tool{ToolDef: types.ToolDef{Parameters: types.Parameters{Cache: &cached}, Instructions: "#!/bin/sh"}}
+
+	for _, e := range []runner.Event{
+		{Type: runner.EventTypeRunStart},
+		{Type: runner.EventTypeCallStart, CallContext: llmCall},
+		{Type: runner.EventTypeChat, CallContext: llmCall, ChatCompletionID: "a", Time: now, ChatRequest: map[string]any{}},
+		{Type: runner.EventTypeChat, CallContext: llmCall, ChatCompletionID: "a", Time: now.Add(time.Second), ChatResponse: map[string]any{}, Usage: types.Usage{PromptTokens: 10, CompletionTokens: 3}},
+		{Type: runner.EventTypeCallStart, CallContext: cmdCall},
+		{Type: runner.EventTypeCallFinish, CallContext: cmdCall},
+		{Type: runner.EventTypeCallStart, CallContext: cmdCall},
+		{Type: runner.EventTypeCallCacheHit, CallContext: cmdCall},
+		{Type: runner.EventTypeCallFinish, CallContext: cmdCall},
+		{Type: runner.EventTypeCallFinish, CallContext: llmCall},
+	} {
+		m.observe(event{Event: gserver.Event{Event: e}})
+	}
+	m.observe(event{Event: gserver.Event{Event: runner.Event{Type: runner.EventTypeRunFinish}, Err: "failed"}})
+
+	require.Equal(t, 1.0, testutil.ToFloat64(m.runsStarted))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.runsFinished))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.runsFailed))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.toolCalls.WithLabelValues("llm", "")))
+	require.Equal(t, 2.0, testutil.ToFloat64(m.toolCalls.WithLabelValues("command", "context")))
+	require.Equal(t, 10.0, testutil.ToFloat64(m.llmTokens.WithLabelValues("gpt-4o", "input")))
+	require.Equal(t, 3.0, testutil.ToFloat64(m.llmTokens.WithLabelValues("gpt-4o", "output")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.cacheRequests.WithLabelValues("llm", "miss")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.cacheRequests.WithLabelValues("tool", "miss")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.cacheRequests.WithLabelValues("tool", "hit")))
+	require.Equal(t, 1, testutil.CollectAndCount(m.llmDuration))
+	require.Empty(t, m.completions)
+	require.Empty(t, m.cacheHits)
+}
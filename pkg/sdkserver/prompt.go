@@ -87,11 +87,20 @@ func (s *server) prompt(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Wait for the prompt response to come through.
+	defer s.metrics.observeWait("prompt", time.Now())
 	select {
 	case <-r.Context().Done():
 		writeError(logger, w, http.StatusInternalServerError, fmt.Errorf("context canceled: %v", r.Context().Err()))
 		return
 	case promptResponse := <-promptChan:
+		if prompt.Sensitive {
+			s.lock.RLock()
+			redactor := s.redactors[id]
+			s.lock.RUnlock()
+			for _, v := range promptResponse {
+				redactor.Add(v)
+			}
+		}
 		writePromptResponse(logger, w, http.StatusOK, promptResponse)
 	}
 }
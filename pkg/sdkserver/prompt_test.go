@@ -0,0 +1,64 @@
+package sdkserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gptscript-ai/broadcaster"
+	"github.com/gptscript-ai/gptscript/pkg/redact"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSensitivePromptRedactedInItsRunOnly(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	run1, run2 := redact.New(), redact.New()
+	s := &server{
+		token:           "token",
+		events:          broadcaster.New[event](),
+		metrics:         newMetrics(),
+		waitingToPrompt: map[string]chan map[string]string{},
+		redactors: map[string]*redact.Redactor{
+			"run1": run1,
+			"run2": run2,
+		},
+	}
+	go s.events.Start(ctx)
+	events := s.events.Subscribe()
+	defer events.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /prompt/{id}", s.prompt)
+	mux.HandleFunc("POST /prompt-response/{id}", s.promptResponse)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	done := make(chan int)
+	go func() {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/prompt/run1", strings.NewReader(`{"message":"Password?","fields":["password"],"sensitive":true}`))
+		req.Header.Set("Authorization", "Bearer token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			done <- 0
+			return
+		}
+		_ = resp.Body.Close()
+		done <- resp.StatusCode
+	}()
+
+	e := <-events.C
+	require.Equal(t, "run1", e.RunID)
+
+	resp, err := http.Post(srv.URL+"/prompt-response/run1", "application/json", strings.NewReader(`{"password":"hunter22"}`))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.Equal(t, http.StatusOK, <-done)
+
+	require.Equal(t, "[REDACTED]", run1.String("hunter22"))
+	require.Equal(t, "hunter22", run2.String("hunter22"))
+}
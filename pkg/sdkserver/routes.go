@@ -11,13 +11,17 @@ import (
 	"sync"
 
 	"github.com/gptscript-ai/broadcaster"
+	"github.com/gptscript-ai/gptscript/pkg/approvals"
 	"github.com/gptscript-ai/gptscript/pkg/cache"
 	gcontext "github.com/gptscript-ai/gptscript/pkg/context"
+	"github.com/gptscript-ai/gptscript/pkg/engine"
 	"github.com/gptscript-ai/gptscript/pkg/gptscript"
 	"github.com/gptscript-ai/gptscript/pkg/input"
 	"github.com/gptscript-ai/gptscript/pkg/loader"
 	"github.com/gptscript-ai/gptscript/pkg/openai"
 	"github.com/gptscript-ai/gptscript/pkg/parser"
+	"github.com/gptscript-ai/gptscript/pkg/policy"
+	"github.com/gptscript-ai/gptscript/pkg/redact"
 	"github.com/gptscript-ai/gptscript/pkg/runner"
 	gserver "github.com/gptscript-ai/gptscript/pkg/server"
 	"github.com/gptscript-ai/gptscript/pkg/types"
@@ -29,10 +33,16 @@ type server struct {
 	address, token string
 	client         *gptscript.GPTScript
 	events         *broadcaster.Broadcaster[event]
+	approvals      *approvals.Store
+	metrics        *metrics
 
 	lock             sync.RWMutex
 	waitingToConfirm map[string]chan runner.AuthorizerResponse
 	waitingToPrompt  map[string]chan map[string]string
+	waitingToDebug   map[string]chan runner.DebugResponse
+	// redactors are the redactors of the runs in progress by run ID, so that the answers to sensitive prompts are
+	// only redacted in the run that asked for them.
+	redactors map[string]*redact.Redactor
 }
 
 func (s *server) addRoutes(mux *http.ServeMux) {
@@ -40,6 +50,8 @@ func (s *server) addRoutes(mux *http.ServeMux) {
 
 	mux.HandleFunc("GET /version", s.version)
 
+	mux.Handle("GET /metrics", s.metrics.handler())
+
 	// Listing tools supports listing system tools (GET) or listing tools in a gptscript (POST).
 	mux.HandleFunc("POST /list-tools", s.listTools)
 	mux.HandleFunc("GET /list-tools", s.listTools)
@@ -55,9 +67,12 @@ func (s *server) addRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /parse", s.parse)
 	mux.HandleFunc("POST /fmt", s.fmtDocument)
 
+	mux.HandleFunc("GET /daemons", s.listDaemons)
+
 	mux.HandleFunc("POST /confirm/{id}", s.confirm)
 	mux.HandleFunc("POST /prompt/{id}", s.prompt)
 	mux.HandleFunc("POST /prompt-response/{id}", s.promptResponse)
+	mux.HandleFunc("POST /debug/{id}", s.debugResponse)
 }
 
 // health just provides an endpoint for checking whether the server is running and accessible.
@@ -125,6 +140,11 @@ func (s *server) listModels(w http.ResponseWriter, r *http.Request) {
 	writeResponse(logger, w, map[string]any{"stdout": strings.Join(out, "\n")})
 }
 
+// listDaemons will return the daemon tools started by this server.
+func (s *server) listDaemons(w http.ResponseWriter, r *http.Request) {
+	writeResponse(gcontext.GetLogger(r.Context()), w, map[string]any{"stdout": engine.ListDaemons()})
+}
+
 // execHandler is a general handler for executing tools with gptscript. This is mainly responsible for parsing the request body.
 // Then the options and tool are passed to the process function.
 func (s *server) execHandler(w http.ResponseWriter, r *http.Request) {
@@ -175,12 +195,19 @@ func (s *server) execHandler(w http.ResponseWriter, r *http.Request) {
 		programLoader = loader.Program
 	}
 
+	// Older clients only send a single credential context.
+	credCtxs := reqObject.CredentialContexts
+	if len(credCtxs) == 0 && reqObject.CredentialContext != "" {
+		credCtxs = []string{reqObject.CredentialContext}
+	}
+
 	opts := gptscript.Options{
-		Cache:             cache.Options(reqObject.cacheOptions),
-		OpenAI:            openai.Options(reqObject.openAIOptions),
-		Env:               reqObject.Env,
-		Workspace:         reqObject.Workspace,
-		CredentialContext: reqObject.CredentialContext,
+		Cache:                  cache.Options(reqObject.cacheOptions),
+		OpenAI:                 openai.Options(reqObject.openAIOptions),
+		Env:                    reqObject.Env,
+		Workspace:              reqObject.Workspace,
+		CredentialContexts:     credCtxs,
+		CredentialWriteContext: reqObject.CredentialWriteContext,
 		Runner: runner.Options{
 			// Set the monitor factory so that we can get events from the server.
 			MonitorFactory:      NewSessionFactory(s.events),
@@ -190,8 +217,26 @@ func (s *server) execHandler(w http.ResponseWriter, r *http.Request) {
 		DefaultModelProvider: reqObject.DefaultModelProvider,
 	}
 
+	if !s.gptscriptOpts.DisableRedaction {
+		opts.Runner.Redactor = redact.New()
+		s.lock.Lock()
+		s.redactors[runID] = opts.Runner.Redactor
+		s.lock.Unlock()
+		defer func() {
+			s.lock.Lock()
+			delete(s.redactors, runID)
+			s.lock.Unlock()
+		}()
+	}
+
 	if reqObject.Confirm {
-		opts.Runner.Authorizer = s.authorize
+		opts.Runner.Authorizer = s.authorizer()
+	}
+	if reqObject.DebugStep {
+		opts.Runner.Debugger = s.debug
+	}
+	if reqObject.Policy != nil {
+		opts.Runner.Policies = []*policy.Policy{reqObject.Policy}
 	}
 
 	s.execAndStream(ctx, programLoader, logger, w, opts, reqObject.ChatState, reqObject.Input, reqObject.SubTool, def)
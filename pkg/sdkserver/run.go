@@ -32,7 +32,7 @@ func (s *server) execAndStream(ctx context.Context, programLoader loaderFunc, lo
 	}
 	defer g.Close(false)
 
-	prg, err := programLoader(ctx, toolDef.String(), subTool, loader.Options{Cache: g.Cache})
+	prg, err := programLoader(ctx, toolDef.String(), subTool, loader.Options{Cache: g.Cache, Env: opts.Env})
 	if err != nil {
 		writeError(logger, w, http.StatusInternalServerError, fmt.Errorf("failed to load program: %w", err))
 		return
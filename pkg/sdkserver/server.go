@@ -15,8 +15,10 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gptscript-ai/broadcaster"
+	"github.com/gptscript-ai/gptscript/pkg/approvals"
 	"github.com/gptscript-ai/gptscript/pkg/gptscript"
 	"github.com/gptscript-ai/gptscript/pkg/mvl"
+	"github.com/gptscript-ai/gptscript/pkg/redact"
 	"github.com/gptscript-ai/gptscript/pkg/runner"
 	"github.com/gptscript-ai/gptscript/pkg/types"
 	"github.com/rs/cors"
@@ -28,6 +30,9 @@ type Options struct {
 	ListenAddress             string
 	Debug                     bool
 	DisableServerErrorLogging bool
+	// Approvals stores the answers to confirmations that are always remembered. The approvals file next to the CLI
+	// config is used if it is not set.
+	Approvals *approvals.Store
 }
 
 // Run will start the server and block until the server is shut down.
@@ -92,6 +97,9 @@ func run(ctx context.Context, listener net.Listener, opts Options) error {
 	opts.Options.Runner.MonitorFactory = NewSessionFactory(events)
 	go events.Start(ctx)
 
+	metrics := newMetrics()
+	go metrics.collect(events.Subscribe().C)
+
 	token := uuid.NewString()
 	// Add the prompt token env var so that gptscript doesn't start its own server. We never want this client to start the
 	// prompt server because it is only used for fmt, parse, etc.
@@ -102,14 +110,26 @@ func run(ctx context.Context, listener net.Listener, opts Options) error {
 		return err
 	}
 
+	if opts.Approvals == nil {
+		file, err := approvals.DefaultFile(opts.OpenAI.ConfigFile)
+		if err != nil {
+			return err
+		}
+		opts.Approvals = approvals.NewStore(file)
+	}
+
 	s := &server{
 		gptscriptOpts:    opts.Options,
 		address:          listener.Addr().String(),
 		token:            token,
 		client:           g,
 		events:           events,
+		approvals:        opts.Approvals,
+		metrics:          metrics,
 		waitingToConfirm: make(map[string]chan runner.AuthorizerResponse),
 		waitingToPrompt:  make(map[string]chan map[string]string),
+		waitingToDebug:   make(map[string]chan runner.DebugResponse),
+		redactors:        make(map[string]*redact.Redactor),
 	}
 	defer s.close()
 
@@ -157,6 +177,7 @@ func complete(opts ...Options) Options {
 		result.ListenAddress = types.FirstSet(opt.ListenAddress, result.ListenAddress)
 		result.Debug = types.FirstSet(opt.Debug, result.Debug)
 		result.DisableServerErrorLogging = types.FirstSet(opt.DisableServerErrorLogging, result.DisableServerErrorLogging)
+		result.Approvals = types.FirstSet(opt.Approvals, result.Approvals)
 	}
 
 	if result.ListenAddress == "" {
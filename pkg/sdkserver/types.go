@@ -9,6 +9,7 @@ import (
 	"github.com/gptscript-ai/gptscript/pkg/engine"
 	"github.com/gptscript-ai/gptscript/pkg/openai"
 	"github.com/gptscript-ai/gptscript/pkg/parser"
+	"github.com/gptscript-ai/gptscript/pkg/policy"
 	"github.com/gptscript-ai/gptscript/pkg/runner"
 	gserver "github.com/gptscript-ai/gptscript/pkg/server"
 	"github.com/gptscript-ai/gptscript/pkg/types"
@@ -25,6 +26,7 @@ const (
 
 	CallConfirm runner.EventType = "callConfirm"
 	Prompt      runner.EventType = "prompt"
+	CallDebug   runner.EventType = "callDebug"
 )
 
 type toolDefs []types.ToolDef
@@ -52,18 +54,24 @@ type toolOrFileRequest struct {
 	cacheOptions  `json:",inline"`
 	openAIOptions `json:",inline"`
 
-	ToolDefs             toolDefs `json:"toolDefs,inline"`
-	SubTool              string   `json:"subTool"`
-	Input                string   `json:"input"`
-	ChatState            string   `json:"chatState"`
-	Workspace            string   `json:"workspace"`
-	Env                  []string `json:"env"`
-	CredentialContext    string   `json:"credentialContext"`
-	CredentialOverrides  []string `json:"credentialOverrides"`
-	Confirm              bool     `json:"confirm"`
-	Location             string   `json:"location,omitempty"`
-	ForceSequential      bool     `json:"forceSequential"`
-	DefaultModelProvider string   `json:"DefaultModelProvider,omitempty"`
+	ToolDefs               toolDefs `json:"toolDefs,inline"`
+	SubTool                string   `json:"subTool"`
+	Input                  string   `json:"input"`
+	ChatState              string   `json:"chatState"`
+	Workspace              string   `json:"workspace"`
+	Env                    []string `json:"env"`
+	CredentialContext      string   `json:"credentialContext"`
+	CredentialContexts     []string `json:"credentialContexts"`
+	CredentialWriteContext string   `json:"credentialWriteContext"`
+	CredentialOverrides    []string `json:"credentialOverrides"`
+	Confirm                bool     `json:"confirm"`
+	Location               string   `json:"location,omitempty"`
+	ForceSequential        bool     `json:"forceSequential"`
+	DefaultModelProvider   string   `json:"DefaultModelProvider,omitempty"`
+	// Policy is used in addition to the policy of the server, if it has one.
+	Policy *policy.Policy `json:"policy,omitempty"`
+	// DebugStep pauses the run before each tool call and request to the LLM, until it is answered with /debug/{id}.
+	DebugStep bool `json:"debugStep"`
 }
 
 type content struct {
@@ -184,6 +192,12 @@ func (r *runInfo) process(e event) map[string]any {
 		call.End = e.Time
 		call.setOutput(e.Content)
 
+	case runner.EventTypeCallPolicy:
+		call.Policy = e.Policy
+
+	case runner.EventTypeCallCacheHit:
+		call.Cached = true
+
 	case runner.EventTypeChat:
 		if e.ChatRequest != nil {
 			call.LLMRequest = e.ChatRequest
@@ -218,6 +232,9 @@ type call struct {
 	Usage       types.Usage      `json:"usage"`
 	LLMRequest  any              `json:"llmRequest"`
 	LLMResponse any              `json:"llmResponse"`
+	Policy      *policy.Result   `json:"policy,omitempty"`
+	// Cached is set when the result of the call comes from the cache instead of running the tool.
+	Cached bool `json:"cached,omitempty"`
 }
 
 func (c *call) setSubCalls(subCalls map[string]engine.Call) {
@@ -244,6 +261,8 @@ type output struct {
 type event struct {
 	gserver.Event `json:",inline"`
 	types.Prompt  `json:",inline"`
+	// DebugStep is the step that is waiting for a response, for callDebug events.
+	DebugStep *runner.DebugStep `json:"debugStep,omitempty"`
 }
 
 type prompt struct {
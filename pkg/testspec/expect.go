@@ -0,0 +1,101 @@
+package testspec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Judge decides whether the output of a test is equivalent to what is expected, using the criteria.
+type Judge interface {
+	Equal(ctx context.Context, expected, actual, criteria string) (equal bool, reasoning string, err error)
+}
+
+// defaultCriteria are the criteria of the judge when a test doesn't have any.
+const defaultCriteria = "The actual output must have the same meaning as the expected output, but can be worded differently."
+
+// check returns why the output or error of a run doesn't meet the expectations, if it doesn't.
+func (e Expect) check(ctx context.Context, judge Judge, output string, runErr error) (failures []string) {
+	errMsg := runError(output, runErr)
+	if e.Error != "" {
+		if errMsg == "" {
+			return []string{fmt.Sprintf("expected the run to fail with an error containing %q, but it succeeded", e.Error)}
+		}
+		if !strings.Contains(errMsg, e.Error) {
+			return []string{fmt.Sprintf("expected the run to fail with an error containing %q, but it failed with: %s", e.Error, errMsg)}
+		}
+		return nil
+	}
+	if errMsg != "" {
+		return []string{fmt.Sprintf("the run failed: %s", errMsg)}
+	}
+
+	if e.Output != nil && strings.TrimSpace(*e.Output) != strings.TrimSpace(output) {
+		failures = append(failures, fmt.Sprintf("expected the output to be %q, but it was %q", strings.TrimSpace(*e.Output), strings.TrimSpace(output)))
+	}
+
+	for _, s := range e.Contains {
+		if !strings.Contains(output, s) {
+			failures = append(failures, fmt.Sprintf("expected the output to contain %q", s))
+		}
+	}
+
+	if e.Regex != "" {
+		re, err := regexp.Compile(e.Regex)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("invalid regex %q: %v", e.Regex, err))
+		} else if !re.MatchString(output) {
+			failures = append(failures, fmt.Sprintf("expected the output to match %q", e.Regex))
+		}
+	}
+
+	if e.JSONSchema != nil {
+		if !json.Valid([]byte(output)) {
+			failures = append(failures, "expected the output to be JSON")
+		} else if result, err := gojsonschema.Validate(gojsonschema.NewGoLoader(e.JSONSchema), gojsonschema.NewStringLoader(output)); err != nil {
+			failures = append(failures, fmt.Sprintf("failed to validate the output with the JSON schema: %v", err))
+		} else if !result.Valid() {
+			for _, resultErr := range result.Errors() {
+				failures = append(failures, fmt.Sprintf("the output doesn't match the JSON schema: %s", resultErr))
+			}
+		}
+	}
+
+	if e.Judge != nil {
+		if judge == nil {
+			failures = append(failures, "the test has a judge expectation, but there is no judge, which needs an OpenAI API key")
+		} else {
+			equal, reasoning, err := judge.Equal(ctx, e.Judge.Expected, output, e.Judge.criteria())
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("failed to ask the judge: %v", err))
+			} else if !equal {
+				failures = append(failures, fmt.Sprintf("the judge ruled that the output is not equivalent to %q: %s", e.Judge.Expected, reasoning))
+			}
+		}
+	}
+
+	return failures
+}
+
+// runError returns the error of a run, if it failed. A tool that fails returns its error as output, so that the LLM
+// that called it can see it, which is also how the error of the tool of the test is returned.
+func runError(output string, runErr error) string {
+	if runErr != nil {
+		return runErr.Error()
+	}
+	if strings.HasPrefix(output, "ERROR:") {
+		return output
+	}
+	return ""
+}
+
+func (j JudgeExpectation) criteria() string {
+	if j.Criteria == "" {
+		return defaultCriteria
+	}
+	return j.Criteria
+}
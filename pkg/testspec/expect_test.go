@@ -0,0 +1,126 @@
+package testspec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gptscript-ai/gptscript/pkg/mock"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeJudge struct {
+	equal    bool
+	criteria string
+}
+
+func (f *fakeJudge) Equal(_ context.Context, _, _, criteria string) (bool, string, error) {
+	f.criteria = criteria
+	return f.equal, "because", nil
+}
+
+func TestCheck(t *testing.T) {
+	output := "yes"
+	expect := Expect{
+		Output:   &output,
+		Contains: []string{"animals"},
+		Regex:    `"cat"`,
+		JSONSchema: map[string]any{
+			"type":     "object",
+			"required": []any{"animals"},
+		},
+	}
+
+	require.Empty(t, Expect{Contains: []string{"cat"}}.check(context.Background(), nil, "a cat", nil))
+	require.Empty(t, Expect{Regex: "^a"}.check(context.Background(), nil, "a cat", nil))
+
+	failures := expect.check(context.Background(), nil, `{"dogs": ["cat"]}`, nil)
+	require.Len(t, failures, 3)
+	require.Contains(t, failures[0], `expected the output to be "yes"`)
+	require.Equal(t, `expected the output to contain "animals"`, failures[1])
+	require.Contains(t, failures[2], "the output doesn't match the JSON schema")
+
+	failures = Expect{JSONSchema: map[string]any{"type": "object"}}.check(context.Background(), nil, "not json", nil)
+	require.Equal(t, []string{"expected the output to be JSON"}, failures)
+
+	failures = Expect{Contains: []string{"cat"}}.check(context.Background(), nil, "", errors.New("boom"))
+	require.Equal(t, []string{"the run failed: boom"}, failures)
+}
+
+func TestCheckError(t *testing.T) {
+	expect := Expect{Error: "exit status 1"}
+
+	require.Empty(t, expect.check(context.Background(), nil, "", errors.New("got exit status 1")))
+	require.Empty(t, expect.check(context.Background(), nil, "ERROR: got (exit status 1) while running tool", nil))
+	require.Contains(t, expect.check(context.Background(), nil, "fine", nil)[0], "but it succeeded")
+	require.Contains(t, expect.check(context.Background(), nil, "", errors.New("boom"))[0], "but it failed with: boom")
+}
+
+func TestCheckJudge(t *testing.T) {
+	expect := Expect{Judge: &JudgeExpectation{Expected: "a cat"}}
+
+	require.Contains(t, expect.check(context.Background(), nil, "cat", nil)[0], "there is no judge")
+
+	judge := &fakeJudge{equal: true}
+	require.Empty(t, expect.check(context.Background(), judge, "cat", nil))
+	require.Equal(t, defaultCriteria, judge.criteria)
+
+	judge.equal = false
+	expect.Judge.Criteria = "be strict"
+	require.Equal(t, []string{`the judge ruled that the output is not equivalent to "a cat": because`}, expect.check(context.Background(), judge, "dog", nil))
+	require.Equal(t, "be strict", judge.criteria)
+}
+
+func TestModel(t *testing.T) {
+	m := &model{}
+	m.script([]mock.Turn{
+		{ToolCalls: []mock.ToolCall{{Name: "SEARCH", Arguments: map[string]any{"query": "cats"}}}},
+		{Text: "done"},
+	})
+
+	req := types.CompletionRequest{
+		Tools: []types.ChatCompletionTool{
+			{Function: types.CompletionFunctionDefinition{Name: "other"}},
+			{Function: types.CompletionFunctionDefinition{Name: "search"}},
+		},
+	}
+	msg, err := m.Call(context.Background(), req, nil)
+	require.NoError(t, err)
+	require.Len(t, msg.Content, 1)
+	call := msg.Content[0].ToolCall
+	require.Equal(t, "search", call.Function.Name)
+	require.Equal(t, `{"query":"cats"}`, call.Function.Arguments)
+	require.Equal(t, 1, *call.Index)
+	require.Equal(t, 1, m.remaining())
+
+	msg, err = m.Call(context.Background(), req, nil)
+	require.NoError(t, err)
+	require.Equal(t, "done", msg.String())
+	require.Equal(t, 0, m.remaining())
+
+	_, err = m.Call(context.Background(), req, nil)
+	require.ErrorContains(t, err, "request 3 to the model has no response")
+
+	m.script(nil)
+	_, err = m.Call(context.Background(), req, nil)
+	require.ErrorContains(t, err, "there is no model to use")
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteJUnit(&buf, []Result{
+		{Spec: "a.test.yaml", Name: "passes", Duration: time.Second, Output: "ok"},
+		{Spec: "a.test.yaml", Name: "fails", Duration: 2 * time.Second, Failures: []string{"first", "second"}},
+		{Spec: "b.test.yaml", Name: "<b>", Duration: time.Second},
+	}))
+
+	out := buf.String()
+	require.Contains(t, out, `<testsuites tests="3" failures="1" time="4.000">`)
+	require.Contains(t, out, `<testsuite name="a.test.yaml" tests="2" failures="1" time="3.000">`)
+	require.Contains(t, out, `<system-out>ok</system-out>`)
+	require.Contains(t, out, `<failure message="first">first&#xA;second</failure>`)
+	require.Contains(t, out, `<testcase name="&lt;b&gt;" classname="b.test.yaml" time="1.000">`)
+}
@@ -0,0 +1,98 @@
+package testspec
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results as JUnit XML, with a test suite for each spec file.
+func WriteJUnit(w io.Writer, results []Result) error {
+	var (
+		report    junitSuites
+		total     time.Duration
+		durations []time.Duration
+		suites    = map[string]int{}
+	)
+	for _, result := range results {
+		i, ok := suites[result.Spec]
+		if !ok {
+			i = len(report.Suites)
+			suites[result.Spec] = i
+			report.Suites = append(report.Suites, junitSuite{Name: result.Spec})
+			durations = append(durations, 0)
+		}
+
+		suite := &report.Suites[i]
+		c := junitCase{
+			Name:      result.Name,
+			ClassName: result.Spec,
+			Time:      seconds(result.Duration),
+			SystemOut: result.Output,
+		}
+		if !result.Passed() {
+			c.Failure = &junitFailure{
+				Message: result.Failures[0],
+				Text:    strings.Join(result.Failures, "\n"),
+			}
+			suite.Failures++
+			report.Failures++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, c)
+		report.Tests++
+		durations[i] += result.Duration
+		total += result.Duration
+	}
+
+	for i, d := range durations {
+		report.Suites[i].Time = seconds(d)
+	}
+	report.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
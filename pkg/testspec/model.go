@@ -0,0 +1,68 @@
+package testspec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gptscript-ai/gptscript/pkg/engine"
+	"github.com/gptscript-ai/gptscript/pkg/mock"
+	"github.com/gptscript-ai/gptscript/pkg/types"
+)
+
+// model responds with the responses of the test that is running, or uses the real model if the test doesn't have any.
+type model struct {
+	real engine.Model
+
+	lock      sync.Mutex
+	responses *mock.Turns
+}
+
+// script sets the responses of the next test.
+func (m *model) script(responses []mock.Turn) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if len(responses) == 0 {
+		m.responses = nil
+	} else {
+		m.responses = mock.NewTurns(responses)
+	}
+}
+
+// remaining returns the number of responses that the run didn't use.
+func (m *model) remaining() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.responses == nil {
+		return 0
+	}
+	return m.responses.Remaining()
+}
+
+func (m *model) Call(ctx context.Context, req types.CompletionRequest, status chan<- types.CompletionStatus) (*types.CompletionMessage, error) {
+	m.lock.Lock()
+	responses := m.responses
+	m.lock.Unlock()
+
+	if responses == nil {
+		if m.real == nil {
+			return nil, errors.New("the test has no responses and there is no model to use")
+		}
+		return m.real.Call(ctx, req, status)
+	}
+
+	msg, err := responses.Call(ctx, req, status)
+	var outOfTurns *mock.OutOfTurnsError
+	if errors.As(err, &outOfTurns) {
+		return nil, fmt.Errorf("request %d to the model has no response, the test only has %d", outOfTurns.Request, outOfTurns.Turns)
+	}
+	return msg, err
+}
+
+func (m *model) ProxyInfo() (string, string, error) {
+	if m.real == nil {
+		return "", "", nil
+	}
+	return m.real.ProxyInfo()
+}
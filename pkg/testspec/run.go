@@ -0,0 +1,118 @@
+package testspec
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gptscript-ai/gptscript/pkg/engine"
+	"github.com/gptscript-ai/gptscript/pkg/gptscript"
+	"github.com/gptscript-ai/gptscript/pkg/loader"
+)
+
+// Result is the result of a test.
+type Result struct {
+	Spec     string
+	Name     string
+	Duration time.Duration
+	Output   string
+	// Failures are why the test failed. The test passed if there aren't any.
+	Failures []string
+}
+
+func (r Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Runner runs the tests of specs, one at a time.
+type Runner struct {
+	gptscript *gptscript.GPTScript
+	model     *model
+	judge     Judge
+	env       []string
+
+	lock  sync.Mutex
+	mocks map[string]string
+}
+
+// NewRunner returns a runner that runs tests with the options. The judge is only needed for the tests that have a
+// judge expectation.
+func NewRunner(ctx context.Context, judge Judge, opts ...gptscript.Options) (*Runner, error) {
+	opt := gptscript.Complete(opts...)
+	r := &Runner{
+		model: &model{},
+		judge: judge,
+		env:   opt.Env,
+	}
+
+	opt.Model = r.model
+	opt.Runner.Mock = r.mock
+
+	g, err := gptscript.New(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+	r.model.real = g.Registry
+	r.gptscript = g
+	return r, nil
+}
+
+func (r *Runner) Close() {
+	r.gptscript.Close(true)
+}
+
+// Run runs the tests of the spec in order.
+func (r *Runner) Run(ctx context.Context, spec *Spec) []Result {
+	var results []Result
+	for _, test := range spec.Tests {
+		results = append(results, r.runTest(ctx, spec, test))
+	}
+	return results
+}
+
+func (r *Runner) runTest(ctx context.Context, spec *Spec, test Test) Result {
+	var (
+		result = Result{
+			Spec: spec.File,
+			Name: test.Name,
+		}
+		start = time.Now()
+	)
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	prg, err := loader.Program(ctx, spec.ScriptPath(), test.SubTool, loader.Options{
+		Cache: r.gptscript.Cache,
+	})
+	if err != nil {
+		result.Failures = []string{fmt.Sprintf("failed to load %s: %v", spec.ScriptPath(), err)}
+		return result
+	}
+
+	r.model.script(test.Responses)
+	r.lock.Lock()
+	r.mocks = map[string]string{}
+	for name, output := range test.Mocks {
+		r.mocks[strings.ToLower(name)] = output
+	}
+	r.lock.Unlock()
+
+	output, runErr := r.gptscript.Run(ctx, prg, r.env, test.Input)
+	result.Output = output
+	result.Failures = test.Expect.check(ctx, r.judge, output, runErr)
+	if remaining := r.model.remaining(); remaining > 0 && runErr == nil {
+		result.Failures = append(result.Failures, fmt.Sprintf("%d of the responses of the test were not used", remaining))
+	}
+	return result
+}
+
+// mock returns the mocked result of a call, if the tool of the call is mocked.
+func (r *Runner) mock(ctx engine.Context, _ string) (string, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	output, ok := r.mocks[strings.ToLower(ctx.Tool.Name)]
+	return output, ok
+}
@@ -0,0 +1,61 @@
+package testspec
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/gptscript-ai/gptscript/pkg/cache"
+	"github.com/gptscript-ai/gptscript/pkg/gptscript"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunMockedToolWithCredential(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GPTSCRIPT_CONFIG_FILE", filepath.Join(dir, "config.json"))
+
+	write(t, dir, "deploy.gpt", `
+tools: deploy
+
+Deploy the app
+
+---
+name: deploy
+credentials: login
+
+#!/bin/bash
+echo deployed
+
+---
+name: login
+
+#!/bin/bash
+echo the credential tool must not run >&2
+exit 1
+`)
+	spec, err := Load(write(t, dir, "deploy.test.yaml", `
+tests:
+  - name: mocked
+    responses:
+      - toolCalls:
+          - name: deploy
+      - text: done
+    mocks:
+      deploy: mocked deploy
+    expect:
+      output: done
+`))
+	require.NoError(t, err)
+
+	r, err := NewRunner(context.Background(), nil, gptscript.Options{
+		Cache:                cache.Options{CacheDir: filepath.Join(dir, "cache")},
+		DefaultModelProvider: "none",
+		DisablePromptServer:  true,
+	})
+	require.NoError(t, err)
+	defer r.Close()
+
+	results := r.Run(context.Background(), spec)
+	require.Len(t, results, 1)
+	require.Empty(t, results[0].Failures)
+}
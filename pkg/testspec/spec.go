@@ -0,0 +1,141 @@
+// Package testspec runs the tests that are declared in spec files next to scripts, for the test command.
+package testspec
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gptscript-ai/gptscript/pkg/mock"
+	"sigs.k8s.io/yaml"
+)
+
+// Suffixes are the endings of the names of spec files.
+var Suffixes = []string{".test.yaml", ".test.yml"}
+
+// Spec is the tests of a script.
+type Spec struct {
+	// File is the spec file that the spec was loaded from.
+	File string `json:"-"`
+	// Script is the script that is tested, relative to the spec file. It is the script with the name of the spec file
+	// without the suffix if it isn't set, such as search.gpt for search.test.yaml.
+	Script string `json:"script,omitempty"`
+	Tests  []Test `json:"tests,omitempty"`
+}
+
+type Test struct {
+	Name    string `json:"name"`
+	Input   string `json:"input,omitempty"`
+	SubTool string `json:"subTool,omitempty"`
+	// Responses are the responses of the model to the requests of the run, in order, which are turns of the mock
+	// model. The run uses the real model if there aren't any.
+	Responses []mock.Turn `json:"responses,omitempty"`
+	// Mocks are the results of the calls of tools with these names, which are returned instead of calling the tools.
+	Mocks  map[string]string `json:"mocks,omitempty"`
+	Expect Expect            `json:"expect,omitempty"`
+}
+
+// Expect is what the run of a test is expected to do. All the expectations that are set must be met.
+type Expect struct {
+	// Output is the exact output, ignoring whitespace at the start and end.
+	Output *string `json:"output,omitempty"`
+	// Contains are strings that the output contains.
+	Contains []string `json:"contains,omitempty"`
+	// Regex is a regular expression that matches the output.
+	Regex string `json:"regex,omitempty"`
+	// JSONSchema is a JSON schema that the output is valid JSON for.
+	JSONSchema map[string]any `json:"jsonSchema,omitempty"`
+	// Judge asks an LLM whether the output is equivalent to what is expected.
+	Judge *JudgeExpectation `json:"judge,omitempty"`
+	// Error is a string that the error of the run contains. The run is expected to succeed if it isn't set.
+	Error string `json:"error,omitempty"`
+}
+
+type JudgeExpectation struct {
+	Expected string `json:"expected"`
+	// Criteria are the rules that the judge uses to decide whether the output is equivalent.
+	Criteria string `json:"criteria,omitempty"`
+}
+
+// Load reads a spec from a YAML or JSON file.
+func Load(file string) (*Spec, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test spec: %w", err)
+	}
+
+	var spec Spec
+	if err := yaml.UnmarshalStrict(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse test spec %s: %w", file, err)
+	}
+	spec.File = file
+
+	if spec.Script == "" {
+		spec.Script = trimSuffix(filepath.Base(file)) + ".gpt"
+	}
+	if len(spec.Tests) == 0 {
+		return nil, fmt.Errorf("test spec %s has no tests", file)
+	}
+	for i, test := range spec.Tests {
+		if test.Name == "" {
+			return nil, fmt.Errorf("test %d of test spec %s has no name", i+1, file)
+		}
+	}
+
+	return &spec, nil
+}
+
+// ScriptPath returns the path of the script of the spec.
+func (s *Spec) ScriptPath() string {
+	if filepath.IsAbs(s.Script) {
+		return s.Script
+	}
+	return filepath.Join(filepath.Dir(s.File), s.Script)
+}
+
+// Find returns the spec files at the paths. Directories are searched for spec files, and files are used as they are.
+func Find(paths ...string) ([]string, error) {
+	var result []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			result = append(result, path)
+			continue
+		}
+
+		var found []string
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && p != path && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && trimSuffix(d.Name()) != d.Name() {
+				found = append(found, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(found)
+		result = append(result, found...)
+	}
+	return result, nil
+}
+
+func trimSuffix(name string) string {
+	for _, suffix := range Suffixes {
+		if trimmed, ok := strings.CutSuffix(name, suffix); ok {
+			return trimmed
+		}
+	}
+	return name
+}
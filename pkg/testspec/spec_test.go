@@ -0,0 +1,84 @@
+package testspec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func write(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	file := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+	require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	return file
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	file := write(t, dir, "search.test.yaml", `
+tests:
+  - name: mocked
+    input: cats
+    responses:
+      - toolCalls:
+          - name: search
+            arguments: {query: cats}
+      - toolCalls:
+          - name: search
+            arguments: '{"query": "dogs"}'
+      - text: done
+    mocks:
+      search: two cats
+    expect:
+      output: "yes"
+      contains: [cat]
+`)
+
+	spec, err := Load(file)
+	require.NoError(t, err)
+	require.Equal(t, "search.gpt", spec.Script)
+	require.Equal(t, filepath.Join(dir, "search.gpt"), spec.ScriptPath())
+	require.Len(t, spec.Tests, 1)
+
+	test := spec.Tests[0]
+	require.Equal(t, "cats", test.Input)
+	require.Equal(t, map[string]string{"search": "two cats"}, test.Mocks)
+	require.Equal(t, "yes", *test.Expect.Output)
+	require.Len(t, test.Responses, 3)
+
+	require.Equal(t, map[string]any{"query": "cats"}, test.Responses[0].ToolCalls[0].Arguments)
+	require.Equal(t, `{"query": "dogs"}`, test.Responses[1].ToolCalls[0].Arguments)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := Load(write(t, dir, "empty.test.yaml", "script: other.gpt\n"))
+	require.ErrorContains(t, err, "has no tests")
+
+	_, err = Load(write(t, dir, "noname.test.yaml", "tests:\n  - input: hi\n"))
+	require.ErrorContains(t, err, "test 1 of test spec")
+
+	_, err = Load(write(t, dir, "unknown.test.yaml", "tests:\n  - name: a\n    expected: hi\n"))
+	require.ErrorContains(t, err, "failed to parse test spec")
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	b := write(t, dir, "b.test.yml", "")
+	a := write(t, dir, "sub/a.test.yaml", "")
+	write(t, dir, "a.gpt", "")
+	write(t, dir, ".hidden/c.test.yaml", "")
+	write(t, dir, "node_modules/d.test.yaml", "")
+	other := write(t, t.TempDir(), "other.yaml", "")
+
+	files, err := Find(dir, other)
+	require.NoError(t, err)
+	require.Equal(t, []string{b, a, other}, files)
+
+	_, err = Find(filepath.Join(dir, "missing"))
+	require.Error(t, err)
+}
@@ -18,6 +18,14 @@ type CompletionRequest struct {
 	Temperature          *float32             `json:"temperature,omitempty"`
 	JSONResponse         bool                 `json:"jsonResponse,omitempty"`
 	Cache                *bool                `json:"cache,omitempty"`
+	TopP                 *float32             `json:"topP,omitempty"`
+	Stop                 []string             `json:"stop,omitempty"`
+	// Seed is the seed to use for sampling. When it isn't set, the OpenAI client derives one from the request.
+	Seed              *int     `json:"seed,omitempty"`
+	PresencePenalty   *float32 `json:"presencePenalty,omitempty"`
+	FrequencyPenalty  *float32 `json:"frequencyPenalty,omitempty"`
+	ReasoningEffort   string   `json:"reasoningEffort,omitempty"`
+	ParallelToolCalls *bool    `json:"parallelToolCalls,omitempty"`
 }
 
 func (r *CompletionRequest) GetCache() bool {
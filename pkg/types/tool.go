@@ -7,7 +7,9 @@ import (
 	"path/filepath"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/google/shlex"
@@ -20,6 +22,9 @@ const (
 	OpenAPIPrefix = "#!sys.openapi"
 	EchoPrefix    = "#!sys.echo"
 	CommandPrefix = "#!"
+
+	MCPPrefix       = "#!sys.mcp"
+	MCPInvokePrefix = "#!sys.mcp.invoke"
 )
 
 var (
@@ -72,6 +77,7 @@ type Program struct {
 	EntryToolID  string         `json:"entryToolId,omitempty"`
 	ToolSet      ToolSet        `json:"toolSet,omitempty"`
 	OpenAPICache map[string]any `json:"-"`
+	MCPCache     map[string]any `json:"-"`
 }
 
 func (p Program) IsChat() bool {
@@ -124,7 +130,16 @@ type Parameters struct {
 	JSONResponse        bool             `json:"jsonResponse,omitempty"`
 	Chat                bool             `json:"chat,omitempty"`
 	Temperature         *float32         `json:"temperature,omitempty"`
+	TopP                *float32         `json:"topP,omitempty"`
+	Stop                []string         `json:"stop,omitempty"`
+	Seed                *int             `json:"seed,omitempty"`
+	PresencePenalty     *float32         `json:"presencePenalty,omitempty"`
+	FrequencyPenalty    *float32         `json:"frequencyPenalty,omitempty"`
+	ReasoningEffort     string           `json:"reasoningEffort,omitempty"`
+	ParallelToolCalls   *bool            `json:"parallelToolCalls,omitempty"`
 	Cache               *bool            `json:"cache,omitempty"`
+	CacheTTL            time.Duration    `json:"cacheTTL,omitempty"`
+	CacheEnv            []string         `json:"cacheEnv,omitempty"`
 	InternalPrompt      *bool            `json:"internalPrompt"`
 	Arguments           *openapi3.Schema `json:"arguments,omitempty"`
 	Tools               []string         `json:"tools,omitempty"`
@@ -442,12 +457,39 @@ func (t ToolDef) String() string {
 	if t.Parameters.JSONResponse {
 		_, _ = fmt.Fprintln(buf, "JSON Response: true")
 	}
-	if t.Parameters.Cache != nil && !*t.Parameters.Cache {
-		_, _ = fmt.Fprintln(buf, "Cache: false")
+	if t.Parameters.Cache != nil && (!*t.Parameters.Cache || strings.HasPrefix(t.Instructions, CommandPrefix)) {
+		_, _ = fmt.Fprintf(buf, "Cache: %v\n", *t.Parameters.Cache)
+	}
+	if t.Parameters.CacheTTL != 0 {
+		_, _ = fmt.Fprintf(buf, "Cache TTL: %s\n", t.Parameters.CacheTTL)
+	}
+	if len(t.Parameters.CacheEnv) != 0 {
+		_, _ = fmt.Fprintf(buf, "Cache Env: %s\n", strings.Join(t.Parameters.CacheEnv, ", "))
 	}
 	if t.Parameters.Temperature != nil {
 		_, _ = fmt.Fprintf(buf, "Temperature: %f\n", *t.Parameters.Temperature)
 	}
+	if t.Parameters.TopP != nil {
+		_, _ = fmt.Fprintf(buf, "Top P: %f\n", *t.Parameters.TopP)
+	}
+	for _, stop := range t.Parameters.Stop {
+		_, _ = fmt.Fprintf(buf, "Stop: %s\n", strconv.Quote(stop))
+	}
+	if t.Parameters.Seed != nil {
+		_, _ = fmt.Fprintf(buf, "Seed: %d\n", *t.Parameters.Seed)
+	}
+	if t.Parameters.PresencePenalty != nil {
+		_, _ = fmt.Fprintf(buf, "Presence Penalty: %f\n", *t.Parameters.PresencePenalty)
+	}
+	if t.Parameters.FrequencyPenalty != nil {
+		_, _ = fmt.Fprintf(buf, "Frequency Penalty: %f\n", *t.Parameters.FrequencyPenalty)
+	}
+	if t.Parameters.ReasoningEffort != "" {
+		_, _ = fmt.Fprintf(buf, "Reasoning Effort: %s\n", t.Parameters.ReasoningEffort)
+	}
+	if t.Parameters.ParallelToolCalls != nil {
+		_, _ = fmt.Fprintf(buf, "Parallel Tool Calls: %v\n", *t.Parameters.ParallelToolCalls)
+	}
 	if t.Parameters.Arguments != nil {
 		var keys []string
 		for k := range t.Parameters.Arguments.Properties {
@@ -787,6 +829,18 @@ func (t Tool) IsOpenAPI() bool {
 	return strings.HasPrefix(t.Instructions, OpenAPIPrefix)
 }
 
+// IsMCP returns true if the tool defines an MCP server. The loader replaces the instructions of these tools and adds
+// a tool for each tool of the server.
+func (t Tool) IsMCP() bool {
+	rest, ok := strings.CutPrefix(t.Instructions, MCPPrefix)
+	return ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n')
+}
+
+// IsMCPInvoke returns true if the tool calls a tool of an MCP server.
+func (t Tool) IsMCPInvoke() bool {
+	return strings.HasPrefix(t.Instructions, MCPInvokePrefix+" ")
+}
+
 func (t Tool) IsAgentsOnly() bool {
 	return t.IsNoop() && len(t.Context) == 0
 }
@@ -800,6 +854,12 @@ func (t Tool) IsHTTP() bool {
 		strings.HasPrefix(t.Instructions, "#!https://")
 }
 
+// CachesResults returns whether the results of the tool are cached. Unlike completions, the results of tools are only
+// cached when the tool asks for it.
+func (t Tool) CachesResults() bool {
+	return t.Parameters.CacheTTL > 0 || (t.Parameters.Cache != nil && *t.Parameters.Cache)
+}
+
 func FirstSet[T comparable](in ...T) (result T) {
 	for _, i := range in {
 		if i != result {
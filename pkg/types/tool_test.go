@@ -2,6 +2,7 @@ package types
 
 import (
 	"testing"
+	"time"
 
 	"github.com/hexops/autogold/v2"
 )
@@ -17,7 +18,16 @@ func TestToolDef_String(t *testing.T) {
 			JSONResponse:        true,
 			Chat:                true,
 			Temperature:         float32Ptr(0.8),
+			TopP:                float32Ptr(0.9),
+			Stop:                []string{"Stop1", "Stop2"},
+			Seed:                intPtr(42),
+			PresencePenalty:     float32Ptr(0.5),
+			FrequencyPenalty:    float32Ptr(-0.5),
+			ReasoningEffort:     "low",
+			ParallelToolCalls:   boolPtr(false),
 			Cache:               boolPtr(true),
+			CacheTTL:            time.Hour,
+			CacheEnv:            []string{"Env1", "Env2"},
 			InternalPrompt:      boolPtr(true),
 			Arguments:           ObjectSchema("arg1", "desc1", "arg2", "desc2"),
 			Tools:               []string{"Tool1", "Tool2"},
@@ -64,7 +74,17 @@ Max Tokens: 1024
 Model: ModelSample
 Model Provider: true
 JSON Response: true
+Cache TTL: 1h0m0s
+Cache Env: Env1, Env2
 Temperature: 0.800000
+Top P: 0.900000
+Stop: "Stop1"
+Stop: "Stop2"
+Seed: 42
+Presence Penalty: 0.500000
+Frequency Penalty: -0.500000
+Reasoning Effort: low
+Parallel Tool Calls: false
 Parameter: arg1: desc1
 Parameter: arg2: desc2
 Internal Prompt: true
@@ -96,3 +116,8 @@ func float32Ptr(f float32) *float32 {
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+// intPtr is used to return a pointer to a given int value
+func intPtr(i int) *int {
+	return &i
+}
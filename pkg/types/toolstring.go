@@ -75,6 +75,8 @@ func ToSysDisplayString(id string, args map[string]string) (string, error) {
 		return fmt.Sprintf("Removing `%s`", args["location"]), nil
 	case "sys.write":
 		return fmt.Sprintf("Writing `%s`", args["filename"]), nil
+	case "sys.oauth2":
+		return fmt.Sprintf("Getting an OAuth2 token from `%s`", args["token_url"]), nil
 	case "sys.context", "sys.stat", "sys.getenv", "sys.abort", "sys.chat.current", "sys.chat.finish", "sys.chat.history", "sys.echo", "sys.prompt", "sys.time.now", "sys.model.provider.credential":
 		return "", nil
 	case "sys.openapi":